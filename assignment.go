@@ -0,0 +1,81 @@
+package tagger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+	"github.com/chuckha/tagger/id3v23/tags"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// ParseAssignment splits a KEY=VALUE argument into the frame key and the JSON form of the frame.
+// A plain value sets the frames.ValueField of the frame, e.g. TIT2=Title.
+// Much like cURL, a value of a data field that starts with an @ reads that file, e.g. APIC=@cover.jpg.
+// A value that is a JSON object or list is used as is, like a value of Frames in a config.
+func ParseAssignment(arg string) (string, json.RawMessage, error) {
+	key, value, ok := strings.Cut(arg, "=")
+	if !ok {
+		return "", nil, errors.Errorf("expected KEY=VALUE, got %q", arg)
+	}
+	if err := frames.ValidateKey(key); err != nil {
+		return "", nil, err
+	}
+	if trimmed := bytes.TrimSpace([]byte(value)); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return key, trimmed, nil
+	}
+	id, _ := frames.ParseKey(key)
+	var v any = value
+	if path, ok := strings.CutPrefix(value, "@"); ok && isDataField(id, frames.ValueField(id)) {
+		v = map[string]string{"File": path}
+	}
+	b, err := json.Marshal(map[string]any{frames.ValueField(id): v})
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	return key, b, nil
+}
+
+// isDataField reports if the field of the frame id can read its value from a file.
+func isDataField(id, name string) bool {
+	for _, field := range frames.Fields(id) {
+		if field.Name == name {
+			return field.Data
+		}
+	}
+	return false
+}
+
+// NewSetConfig builds a config that sets a frame for each KEY=VALUE assignment, in order.
+// The frames are validated just like the Frames of a config file.
+func NewSetConfig(assignments ...string) (*Config, error) {
+	c := NewConfig()
+	for _, assignment := range assignments {
+		key, value, err := ParseAssignment(assignment)
+		if err != nil {
+			return nil, err
+		}
+		fs, err := unmarshalFrames(key, value, false)
+		if err != nil {
+			return nil, errors.Errorf("frame %q: %w", key, err)
+		}
+		for _, frame := range fs {
+			c.Operations = append(c.Operations, tags.Operation{Kind: tags.Set, Key: key, Frame: frame})
+		}
+	}
+	return c, c.Validate()
+}
+
+// NewRemoveConfig builds a config that removes every frame named by the keys.
+func NewRemoveConfig(keys ...string) (*Config, error) {
+	c := NewConfig()
+	for _, key := range keys {
+		if err := frames.ValidateKey(key); err != nil {
+			return nil, err
+		}
+		c.Operations = append(c.Operations, tags.Operation{Kind: tags.Remove, Key: key})
+	}
+	return c, nil
+}
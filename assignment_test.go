@@ -0,0 +1,87 @@
+package tagger
+
+import (
+	"testing"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+	"github.com/chuckha/tagger/id3v23/tags"
+)
+
+func TestParseAssignment(t *testing.T) {
+	testcases := []struct {
+		arg      string
+		key      string
+		expected string
+	}{
+		{arg: "TIT2=New title", key: "TIT2", expected: `{"Information":"New title"}`},
+		{arg: "TIT2=[Live] a=b", key: "TIT2", expected: `{"Information":"[Live] a=b"}`},
+		{arg: `TPE1=["A", "B"]`, key: "TPE1", expected: `["A", "B"]`},
+		{arg: "TXXX:Narrator=Stephen Fry", key: "TXXX:Narrator", expected: `{"Value":"Stephen Fry"}`},
+		{arg: "APIC=@cover.jpg", key: "APIC", expected: `{"Data":{"File":"cover.jpg"}}`},
+		{arg: "PRIV=@handle", key: "PRIV", expected: `{"Data":{"File":"handle"}}`},
+		{arg: "TIT2=@handle", key: "TIT2", expected: `{"Information":"@handle"}`},
+		{arg: `COMM:eng={"ActualText": "x"}`, key: "COMM:eng", expected: `{"ActualText": "x"}`},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.arg, func(t *testing.T) {
+			key, value, err := ParseAssignment(tt.arg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key != tt.key || string(value) != tt.expected {
+				t.Fatalf("expected %s=%s, got %s=%s", tt.key, tt.expected, key, value)
+			}
+		})
+	}
+	for _, arg := range []string{"TIT2", "XXXX=x", "TIT2:x=y", "COMM:english=x"} {
+		if _, _, err := ParseAssignment(arg); err == nil {
+			t.Errorf("expected %q to be rejected", arg)
+		}
+	}
+}
+
+func TestNewSetConfig(t *testing.T) {
+	cfg, err := NewSetConfig("TIT2=New title", "TPE1=Stephen Fry/Jim Dale", "TCON=Audiobook", "TXXX:Narrator=Stephen Fry")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	tag := tags.NewID3v2()
+	if err := cfg.Apply(tag); err != nil {
+		t.Fatal(err)
+	}
+	expectText(t, tag, "TIT2", "New title")
+	expectText(t, tag, "TPE1", "Stephen Fry/Jim Dale")
+	expectText(t, tag, "TCON", "(183)Audiobook")
+	if fs := tag.FramesWithKey("TXXX:Narrator"); len(fs) != 1 || frames.Text(fs[0].Body) != "Stephen Fry" {
+		t.Fatalf("expected a narrator, got %v", fs)
+	}
+
+	if _, err := NewSetConfig("TRCK=one"); err == nil {
+		t.Fatal("expected the TRCK format to be validated")
+	}
+	if _, err := NewSetConfig("TIT2=a", "TIT2=b"); err == nil {
+		t.Fatal("expected two TIT2 frames to break the multiplicity rule")
+	}
+}
+
+func TestNewRemoveConfig(t *testing.T) {
+	tag := tags.NewID3v2()
+	tag.SetFrames(
+		frames.NewFrame("TIT2", frames.NewTextInformation("title")),
+		frames.NewFrame("COMM", &frames.Comment{Language: "eng", ActualText: []rune("x")}),
+		frames.NewFrame("COMM", &frames.Comment{Language: "deu", ActualText: []rune("y")}),
+	)
+	cfg, err := NewRemoveConfig("TIT2", "COMM:eng")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Apply(tag); err != nil {
+		t.Fatal(err)
+	}
+	if len(*tag.Frames) != 1 || (*tag.Frames)[0].Key() != "COMM:deu" {
+		t.Fatalf("expected only the german comment to be left, got %v", tag)
+	}
+	if _, err := NewRemoveConfig("COMM:english"); err == nil {
+		t.Fatal("expected an invalid key to be rejected")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/chuckha/tagger"
+	"github.com/chuckha/tagger/id3v23/frames"
+	"github.com/chuckha/tagger/id3v23/tags"
+)
+
+// isKey reports if the argument is a frame key such as TIT2 or COMM:eng rather than a file.
+func isKey(arg string) bool {
+	id, _ := frames.ParseKey(arg)
+	_, ok := frames.Descriptions[id]
+	return ok
+}
+
+// isAssignment reports if the argument is a KEY=VALUE assignment rather than a file.
+func isAssignment(arg string) bool {
+	key, _, ok := strings.Cut(arg, "=")
+	return ok && isKey(key)
+}
+
+// splitArgs separates the files from the frame keys or assignments; they may be given in any order.
+func splitArgs(args []string, isFrame func(string) bool) (files, rest []string) {
+	for _, arg := range args {
+		if isFrame(arg) {
+			rest = append(rest, arg)
+			continue
+		}
+		files = append(files, arg)
+	}
+	return expandGlobs(files), rest
+}
+
+// getFrames prints the text of every frame named by the keys and reports if every key was found in every file.
+func getFrames(files, keys []string) bool {
+	ok := true
+	for _, file := range files {
+		prefix := ""
+		if len(files) > 1 {
+			prefix = file + ": "
+		}
+		tag, err := tags.NewID3v2FromFile(file)
+		if err != nil {
+			ok = false
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			continue
+		}
+		for _, key := range keys {
+			fs := tag.FramesWithKey(key)
+			if len(fs) == 0 {
+				ok = false
+				fmt.Fprintf(os.Stderr, "%s: no %s frame\n", file, key)
+			}
+			for _, frame := range fs {
+				fmt.Printf("%s%s=%s\n", prefix, frame.Key(), frames.Text(frame.Body))
+			}
+		}
+	}
+	return ok
+}
+
+// editFiles applies the config to every file in place.
+func editFiles(cfg *tagger.Config, files []string, dryRun bool) bool {
+	ok := true
+	for _, file := range files {
+		if err := tagFile(cfg, file, file, dryRun); err != nil {
+			ok = false
+			fmt.Printf("%s: %v\n", file, err)
+		}
+	}
+	return ok
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/chuckha/tagger/id3v23/tags"
+
+	"gitlab.com/tozd/go/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Exit codes of the info command. A read or parse error wins over a missing tag.
+const (
+	exitNoTag      = 2
+	exitParseError = 3
+)
+
+type fileInfo struct {
+	File  string     `json:"file" yaml:"file"`
+	Tag   *tags.Info `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Error string     `json:"error,omitempty" yaml:"error,omitempty"`
+	// Warning is a problem reading the tag that did not stop it from being read.
+	Warning string `json:"warning,omitempty" yaml:"warning,omitempty"`
+
+	tag *tags.ID3v2
+}
+
+// expandGlobs replaces every argument that is a glob with the files it matches.
+// Arguments that match nothing are kept so the missing file is reported.
+func expandGlobs(args []string) []string {
+	files := []string{}
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil || len(matches) == 0 {
+			files = append(files, arg)
+			continue
+		}
+		files = append(files, matches...)
+	}
+	return files
+}
+
+// readInfos reads the tag of every file and returns the exit code for the worst failure.
+func readInfos(files []string) ([]*fileInfo, int) {
+	infos := []*fileInfo{}
+	code := 0
+	for _, file := range files {
+		info := &fileInfo{File: file}
+		infos = append(infos, info)
+		tag, err := tags.NewID3v2FromFile(file)
+		if err != nil {
+			info.Error = err.Error()
+			var noTag *tags.NoID3v2IdentifierError
+			if errors.As(err, &noTag) {
+				code = max(code, exitNoTag)
+			} else {
+				code = exitParseError
+			}
+			continue
+		}
+		info.tag = tag
+		info.Tag = tag.Info()
+		if tag.Warning != nil {
+			info.Warning = tag.Warning.Error()
+		}
+	}
+	return infos, code
+}
+
+func writeInfos(w io.Writer, format string, infos []*fileInfo) error {
+	switch format {
+	case "text":
+		for _, info := range infos {
+			if info.Error != "" {
+				fmt.Fprintf(w, "%s: %s\n", info.File, info.Error)
+				continue
+			}
+			if len(infos) > 1 {
+				fmt.Fprintf(w, "%s:\n", info.File)
+			}
+			if info.Warning != "" {
+				fmt.Fprintf(w, "warning: %s\n", info.Warning)
+			}
+			fmt.Fprintln(w, info.tag)
+		}
+		return nil
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return errors.WithStack(enc.Encode(infos))
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(infos); err != nil {
+			return errors.WithStack(err)
+		}
+		return errors.WithStack(enc.Close())
+	case "csv":
+		return writeCSV(w, infos)
+	}
+	return errors.Errorf("unknown format %q; expected one of json, yaml, csv or text", format)
+}
+
+// writeCSV writes a row per frame. A file without a tag gets a single row holding the error.
+func writeCSV(w io.Writer, infos []*fileInfo) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"file", "id", "key", "description", "size", "flags", "text", "fields", "error"})
+	for _, info := range infos {
+		if info.Error != "" {
+			cw.Write([]string{info.File, "", "", "", "", "", "", "", info.Error})
+			continue
+		}
+		for _, frame := range info.Tag.Frames {
+			fields, err := json.Marshal(frame.Fields)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			cw.Write([]string{
+				info.File,
+				frame.ID,
+				frame.Key,
+				frame.Description,
+				strconv.Itoa(frame.Size),
+				strings.Join(frame.Flags, "|"),
+				frame.Text,
+				string(fields),
+				"",
+			})
+		}
+	}
+	cw.Flush()
+	return errors.WithStack(cw.Error())
+}
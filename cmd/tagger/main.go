@@ -1,33 +1,146 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/chuckha/tagger"
 	"github.com/chuckha/tagger/id3v23/tags"
 )
 
+// writeOptions are set by the flags of every command that writes files.
+var (
+	padding      = tags.DefaultPaddingPolicy
+	writeOptions = tags.WriteOptions{Padding: &padding}
+)
+
+// addWriteFlags adds the flags that change how files are written.
+func addWriteFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&writeOptions.Backup, "backup", false, "keep a copy of every replaced file with a .bak suffix")
+	fs.BoolVar(&writeOptions.Durable, "durable", false, "always replace files through a temporary file instead of overwriting a tag in place")
+	fs.IntVar(&padding.Min, "padding-min", padding.Min, "padding of a tag that has to grow")
+	fs.IntVar(&padding.Max, "padding-max", padding.Max, "most padding a tag keeps before it is shrunk; 0 never shrinks a tag")
+	fs.IntVar(&padding.Block, "padding-block", padding.Block, "round the size of a grown or shrunk tag up to a multiple of this")
+}
+
 func main() {
 	infofs := flag.NewFlagSet("info", flag.ExitOnError)
+	infoFormat := infofs.String("format", "text", "output format: json, yaml, csv or text")
 	infofs.Usage = func() {
-		fmt.Println("tagger info <file>")
+		fmt.Println("tagger info [-format json|yaml|csv|text] <file|glob>...")
+		fmt.Println("exits 2 if a file has no ID3v2 tag and 3 if a file cannot be read or parsed")
 	}
 
 	tagfs := flag.NewFlagSet("tag", flag.ExitOnError)
 	cfg := tagfs.String("config", "", "path to config file")
+	tagOut := tagfs.String("o", "", "write the tagged file here instead of in place; only for a single file")
+	tagDryRun := tagfs.Bool("dry-run", false, "print the changes without writing anything")
+	addWriteFlags(tagfs)
 	tagfs.Usage = func() {
-		fmt.Println("tagger tag -config <cfg.json> <file>")
+		fmt.Println("tagger tag -config <cfg.json> [-o <dst>] [-dry-run] [-backup] <file>...")
+	}
+
+	getfs := flag.NewFlagSet("get", flag.ExitOnError)
+	getfs.Usage = func() {
+		fmt.Println("tagger get <file|glob>... <key>...")
+	}
+
+	setfs := flag.NewFlagSet("set", flag.ExitOnError)
+	setDryRun := setfs.Bool("dry-run", false, "print the changes without writing anything")
+	addWriteFlags(setfs)
+	setfs.Usage = func() {
+		fmt.Println("tagger set [-dry-run] [-backup] <file|glob>... <key>=<value>...")
+	}
+
+	rmfs := flag.NewFlagSet("rm", flag.ExitOnError)
+	rmDryRun := rmfs.Bool("dry-run", false, "print the changes without writing anything")
+	addWriteFlags(rmfs)
+	rmfs.Usage = func() {
+		fmt.Println("tagger rm [-dry-run] [-backup] <file|glob>... <key>...")
+	}
+
+	difffs := flag.NewFlagSet("diff", flag.ExitOnError)
+	difffs.Usage = func() {
+		fmt.Println("tagger diff <a.mp3> <b.mp3>")
+		fmt.Println("exits 1 if the tags differ and 2 if a file cannot be read")
+	}
+
+	copyTagsfs := flag.NewFlagSet("copy-tags", flag.ExitOnError)
+	only := copyTagsfs.String("only", "", "comma separated frame keys to copy, e.g. TIT2,APIC")
+	except := copyTagsfs.String("except", "", "comma separated frame keys not to copy, e.g. PRIV")
+	copyDryRun := copyTagsfs.Bool("dry-run", false, "print the changes without writing anything")
+	addWriteFlags(copyTagsfs)
+	copyTagsfs.Usage = func() {
+		fmt.Println("tagger copy-tags [-only <keys>] [-except <keys>] [-dry-run] [-backup] <src> <dst>")
+	}
+
+	exportfs := flag.NewFlagSet("export", flag.ExitOnError)
+	exportOut := exportfs.String("o", "tags.json", "sidecar file to write; binary data goes in a .data directory next to it")
+	exportfs.Usage = func() {
+		fmt.Println("tagger export <dir> [-o <tags.json>]")
+	}
+
+	importfs := flag.NewFlagSet("import", flag.ExitOnError)
+	importDryRun := importfs.Bool("dry-run", false, "print the changes without writing anything")
+	addWriteFlags(importfs)
+	importfs.Usage = func() {
+		fmt.Println("tagger import [-dry-run] [-backup] <tags.json>")
+	}
+
+	extractfs := flag.NewFlagSet("extract", flag.ExitOnError)
+	extractOut := extractfs.String("o", ".", "directory to write the pictures and objects to")
+	extractfs.Usage = func() {
+		fmt.Println("tagger extract <file> [-o <dir>]")
 	}
 
 	templateTagfs := flag.NewFlagSet("template-tag", flag.ExitOnError)
 	templateCfg := templateTagfs.String("template-config", "", "path to template config file")
 	dryRun := templateTagfs.Bool("dry-run", true, "dry run")
 	noisy := templateTagfs.Bool("noisy", false, "noisy")
+	jobs := templateTagfs.Int("jobs", 1, "number of files read and written at the same time")
+	journalFile := templateTagfs.String("journal", "", "file to record the original tags in so the run can be undone; defaults to tagger-journal-<time>.jsonl")
+	addWriteFlags(templateTagfs)
 	templateTagfs.Usage = func() {
-		fmt.Println("tagger template-tag -template-config <cfg.json> [-dry-run=false] [-noisy] <dir>")
+		fmt.Println("tagger template-tag -template-config <cfg.json> [-dry-run=false] [-noisy] [-backup] [-journal <file>] [-jobs <n>] <dir>")
+	}
+
+	undofs := flag.NewFlagSet("undo", flag.ExitOnError)
+	undoDryRun := undofs.Bool("dry-run", false, "check every file can be restored without changing anything")
+	undofs.Usage = func() {
+		fmt.Println("tagger undo [-dry-run] <journal>")
+	}
+
+	patternTestfs := flag.NewFlagSet("pattern-test", flag.ExitOnError)
+	pattern := patternTestfs.String("pattern", "", "file pattern to test")
+	patternCfg := patternTestfs.String("template-config", "", "path to a template config whose rules are tested")
+	patternTestfs.Usage = func() {
+		fmt.Println("tagger pattern-test [-pattern <pattern>] [-template-config <cfg.json>] <path>...")
+	}
+
+	configExplainfs := flag.NewFlagSet("config-explain", flag.ExitOnError)
+	root := configExplainfs.String("root", ".", "directory the directory configs are inherited from")
+	configExplainfs.Usage = func() {
+		fmt.Println("tagger config-explain [-root <dir>] <file>")
+	}
+
+	configValidatefs := flag.NewFlagSet("config-validate", flag.ExitOnError)
+	validateTemplate := configValidatefs.Bool("template", false, "validate template configs instead of configs")
+	configValidatefs.Usage = func() {
+		fmt.Println("tagger config-validate [-template] <cfg.json|cfg.yaml|cfg.toml>...")
+	}
+
+	configSchemafs := flag.NewFlagSet("config-schema", flag.ExitOnError)
+	schemaTemplate := configSchemafs.Bool("template", false, "print the schema of template configs instead of configs")
+	schemaOut := configSchemafs.String("o", "", "file to write the schema to instead of stdout")
+	configSchemafs.Usage = func() {
+		fmt.Println("tagger config-schema [-template] [-o <file>]")
 	}
 
 	stripTagfs := flag.NewFlagSet("strip-tag", flag.ExitOnError)
@@ -36,9 +149,22 @@ func main() {
 		flag.Usage = func() {
 			fmt.Println("tagger <command> [args]")
 			fmt.Println("commands:")
-			fmt.Println("  info <file>")
-			fmt.Println("  tag --config <cfg.json> <file>")
-			fmt.Println("  template-tag --template-config <cfg.json> <dir>")
+			fmt.Println("  info [--format json|yaml|csv|text] <file|glob>...")
+			fmt.Println("  tag --config <cfg.json> [-o <dst>] [--dry-run] [--backup] <file>...")
+			fmt.Println("  get <file>... <key>...")
+			fmt.Println("  set [--dry-run] [--backup] <file>... <key>=<value>...")
+			fmt.Println("  rm [--dry-run] [--backup] <file>... <key>...")
+			fmt.Println("  diff <a.mp3> <b.mp3>")
+			fmt.Println("  copy-tags [--only <keys>] [--except <keys>] [--dry-run] [--backup] <src> <dst>")
+			fmt.Println("  export <dir> [-o <tags.json>]")
+			fmt.Println("  import [--dry-run] [--backup] <tags.json>")
+			fmt.Println("  extract <file> [-o <dir>]")
+			fmt.Println("  template-tag --template-config <cfg.json> [--dry-run=false] [--backup] [--journal <file>] [--jobs <n>] <dir>")
+			fmt.Println("  undo [--dry-run] <journal>")
+			fmt.Println("  pattern-test [--pattern <pattern>] [--template-config <cfg.json>] <path>...")
+			fmt.Println("  config-explain [--root <dir>] <file>")
+			fmt.Println("  config-validate [--template] <cfg>...")
+			fmt.Println("  config-schema [--template] [-o <file>]")
 			fmt.Println("  strip-tag-v1 <file>")
 		}
 		flag.Usage()
@@ -48,51 +174,298 @@ func main() {
 	switch os.Args[1] {
 	case "info":
 		infofs.Parse(os.Args[2:])
-		file := infofs.Arg(0)
-		tag, err := tags.NewID3v2FromFile(file)
-		if err != nil {
-			panic(fmt.Sprintf("%+v", err))
+		if infofs.NArg() == 0 {
+			infofs.Usage()
+			os.Exit(1)
+		}
+		infos, code := readInfos(expandGlobs(infofs.Args()))
+		if err := writeInfos(os.Stdout, *infoFormat, infos); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
-		fmt.Println(tag)
+		os.Exit(code)
 	case "tag":
 		tagfs.Parse(os.Args[2:])
-		file := tagfs.Arg(0)
-		b, err := os.ReadFile(*cfg)
+		if tagfs.NArg() == 0 || (*tagOut != "" && tagfs.NArg() > 1) {
+			tagfs.Usage()
+			os.Exit(1)
+		}
+		cfg, err := tagger.LoadConfig(*cfg)
 		if err != nil {
 			panic(fmt.Sprintf("%+v", err))
 		}
-		cfg := tagger.NewConfig()
-		if err := cfg.UnmarshalJSON(b); err != nil {
-			panic(fmt.Sprintf("%+v", err))
+		failed := false
+		for _, file := range tagfs.Args() {
+			dst := file
+			if *tagOut != "" {
+				dst = *tagOut
+			}
+			if err := tagFile(cfg, file, dst, *tagDryRun); err != nil {
+				failed = true
+				fmt.Printf("%s: %v\n", file, err)
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	case "get":
+		getfs.Parse(os.Args[2:])
+		files, keys := splitArgs(getfs.Args(), isKey)
+		if len(files) == 0 || len(keys) == 0 {
+			getfs.Usage()
+			os.Exit(1)
+		}
+		if !getFrames(files, keys) {
+			os.Exit(1)
+		}
+	case "set":
+		setfs.Parse(os.Args[2:])
+		files, assignments := splitArgs(setfs.Args(), isAssignment)
+		if len(files) == 0 || len(assignments) == 0 {
+			setfs.Usage()
+			os.Exit(1)
 		}
-		fmt.Println(cfg)
-		tag, err := tags.NewID3v2FromFile(file)
+		cfg, err := tagger.NewSetConfig(assignments...)
 		if err != nil {
-			panic(fmt.Sprintf("%+v", err))
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if !editFiles(cfg, files, *setDryRun) {
+			os.Exit(1)
+		}
+	case "rm":
+		rmfs.Parse(os.Args[2:])
+		files, keys := splitArgs(rmfs.Args(), isKey)
+		if len(files) == 0 || len(keys) == 0 {
+			rmfs.Usage()
+			os.Exit(1)
+		}
+		cfg, err := tagger.NewRemoveConfig(keys...)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if !editFiles(cfg, files, *rmDryRun) {
+			os.Exit(1)
+		}
+	case "diff":
+		difffs.Parse(os.Args[2:])
+		if difffs.NArg() != 2 {
+			difffs.Usage()
+			os.Exit(2)
+		}
+		same, err := diffTags(difffs.Arg(0), difffs.Arg(1))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		if !same {
+			os.Exit(1)
+		}
+	case "copy-tags":
+		copyTagsfs.Parse(os.Args[2:])
+		if copyTagsfs.NArg() != 2 {
+			copyTagsfs.Usage()
+			os.Exit(1)
+		}
+		onlyKeys, err := splitKeys(*only)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
-		fmt.Println("APPLYING...")
-		if err := tag.ApplyFrames(cfg.Frames); err != nil {
+		exceptKeys, err := splitKeys(*except)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := copyTags(copyTagsfs.Arg(0), copyTagsfs.Arg(1), onlyKeys, exceptKeys, *copyDryRun); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case "export":
+		args := parseInterspersed(exportfs, os.Args[2:])
+		if len(args) != 1 {
+			exportfs.Usage()
+			os.Exit(1)
+		}
+		warnings, err := tagger.ExportDir(args[0], *exportOut)
+		if err != nil {
 			panic(fmt.Sprintf("%+v", err))
 		}
-		fmt.Println(tag)
+		for _, warning := range warnings {
+			fmt.Println(warning)
+		}
+		fmt.Printf("wrote %q\n", *exportOut)
+	case "import":
+		args := parseInterspersed(importfs, os.Args[2:])
+		if len(args) != 1 {
+			importfs.Usage()
+			os.Exit(1)
+		}
+		if !importSidecar(args[0], *importDryRun) {
+			os.Exit(1)
+		}
+	case "extract":
+		args := parseInterspersed(extractfs, os.Args[2:])
+		if len(args) != 1 {
+			extractfs.Usage()
+			os.Exit(1)
+		}
+		if err := extractAttachments(args[0], *extractOut); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	case "template-tag":
 		templateTagfs.Parse(os.Args[2:])
 		dir := templateTagfs.Arg(0)
-		tmplfile, err := os.ReadFile(*templateCfg)
+		tmplcfg, err := tagger.LoadTemplateConfig(*templateCfg)
 		if err != nil {
 			panic(fmt.Sprintf("%+v", err))
 		}
-		tmplcfg := tagger.NewTemplateConfig()
-		if err := tmplcfg.UnmarshalJSON(tmplfile); err != nil {
-			panic(fmt.Sprintf("%+v", err))
-		}
 		if *dryRun {
 			tmplcfg.UpdateBehavior(tagger.WriteFile, tagger.Skip)
 		}
 		if *noisy {
 			tmplcfg.UpdateBehavior(tagger.Logging, tagger.Noisy)
 		}
-		if err := tmplcfg.ProcessDir(dir); err != nil {
+		if writeOptions.Backup {
+			tmplcfg.UpdateBehavior(tagger.Backup, tagger.Add)
+		}
+		if writeOptions.Durable {
+			tmplcfg.UpdateBehavior(tagger.Durable, tagger.Add)
+		}
+		tmplcfg.SetJobs(*jobs)
+		// padding flags override the padding of the template config
+		templateTagfs.Visit(func(f *flag.Flag) {
+			if strings.HasPrefix(f.Name, "padding-") {
+				tmplcfg.Padding = &padding
+			}
+		})
+		var journal *tagger.Journal
+		if !*dryRun {
+			if *journalFile == "" {
+				*journalFile = time.Now().Format("tagger-journal-20060102-150405.jsonl")
+			}
+			journal, err = tagger.CreateJournal(*journalFile)
+			if err != nil {
+				panic(fmt.Sprintf("%+v", err))
+			}
+			tmplcfg.SetJournal(journal)
+			fmt.Printf("recording the original tags in %q; undo with: tagger undo %q\n", journal.Name(), journal.Name())
+		}
+		report, err := tmplcfg.ProcessDirReport(dir)
+		if journal != nil {
+			if cerr := closeJournal(journal); cerr != nil {
+				fmt.Println(cerr)
+			}
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		for _, path := range report.Unmatched {
+			fmt.Printf("no rule matched %q\n", path)
+		}
+	case "undo":
+		args := parseInterspersed(undofs, os.Args[2:])
+		if len(args) != 1 {
+			undofs.Usage()
+			os.Exit(1)
+		}
+		if !undoJournal(args[0], *undoDryRun) {
+			os.Exit(1)
+		}
+	case "pattern-test":
+		patternTestfs.Parse(os.Args[2:])
+		rules := []*tagger.Rule{}
+		if *pattern != "" {
+			re, err := tagger.CompilePattern(*pattern)
+			if err != nil {
+				panic(fmt.Sprintf("%+v", err))
+			}
+			rules = append(rules, &tagger.Rule{Pattern: *pattern, FilePattern: re})
+		}
+		if *patternCfg != "" {
+			tmplcfg, err := tagger.LoadTemplateConfig(*patternCfg)
+			if err != nil {
+				panic(fmt.Sprintf("%+v", err))
+			}
+			rules = append(rules, tmplcfg.Rules...)
+		}
+		if len(rules) == 0 {
+			patternTestfs.Usage()
+			os.Exit(1)
+		}
+		for _, rule := range rules {
+			fmt.Printf("%s compiles to %s\n", rule, rule.FilePattern)
+		}
+		for _, path := range patternTestfs.Args() {
+			printMatches(path, rules)
+		}
+	case "config-explain":
+		configExplainfs.Parse(os.Args[2:])
+		if configExplainfs.NArg() != 1 {
+			configExplainfs.Usage()
+			os.Exit(1)
+		}
+		file := configExplainfs.Arg(0)
+		dc, err := tagger.LoadDirConfig(*root, filepath.Dir(file))
+		if err != nil {
+			panic(fmt.Sprintf("%+v", err))
+		}
+		paths := make([]string, 0, len(dc.Sources))
+		for path := range dc.Sources {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fmt.Printf("%s = %s\t(%s)\n", path, dc.Value(path), dc.Sources[path])
+		}
+	case "config-validate":
+		configValidatefs.Parse(os.Args[2:])
+		schema, load := tagger.ConfigSchema(), func(file string) error {
+			_, err := tagger.LoadConfig(file)
+			return err
+		}
+		if *validateTemplate {
+			schema, load = tagger.TemplateConfigSchema(), func(file string) error {
+				_, err := tagger.LoadTemplateConfig(file)
+				return err
+			}
+		}
+		valid := true
+		for _, file := range configValidatefs.Args() {
+			err := tagger.ValidateFile(file, schema)
+			if err == nil {
+				// the schema cannot check everything, e.g. the format of TRCK, so load it too
+				err = load(file)
+			}
+			if err != nil {
+				valid = false
+				fmt.Println(err)
+				continue
+			}
+			fmt.Printf("%s: ok\n", file)
+		}
+		if !valid {
+			os.Exit(1)
+		}
+	case "config-schema":
+		configSchemafs.Parse(os.Args[2:])
+		schema := tagger.ConfigSchema()
+		if *schemaTemplate {
+			schema = tagger.TemplateConfigSchema()
+		}
+		b, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			panic(fmt.Sprintf("%+v", err))
+		}
+		b = append(b, '\n')
+		if *schemaOut == "" {
+			os.Stdout.Write(b)
+			return
+		}
+		if err := os.WriteFile(*schemaOut, b, 0644); err != nil {
 			panic(fmt.Sprintf("%+v", err))
 		}
 	case "strip-tag":
@@ -129,3 +502,38 @@ func main() {
 		panic(fmt.Sprintf("unknown command: %q	", os.Args[1]))
 	}
 }
+
+// parseInterspersed parses the flags wherever they are among the arguments and returns the arguments that are not flags.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	rest := []string{}
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return rest
+		}
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// printMatches prints the variables every rule captures from the path.
+func printMatches(path string, rules []*tagger.Rule) {
+	fmt.Printf("%q\n", path)
+	matched := false
+	for _, rule := range rules {
+		captured, ok := rule.Match(path)
+		if !ok {
+			continue
+		}
+		matched = true
+		fmt.Printf("  %s\n", rule)
+		for _, name := range rule.FilePattern.SubexpNames() {
+			if value, ok := captured[name]; ok {
+				fmt.Printf("    %s = %q\n", name, value)
+			}
+		}
+	}
+	if !matched {
+		fmt.Println("  no rule matched")
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/chuckha/tagger"
+	"github.com/chuckha/tagger/id3v23/frames"
+	"github.com/chuckha/tagger/id3v23/tags"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// readTag reads the tag of the file or returns an empty tag if the file does not have one.
+func readTag(file string) (*tags.ID3v2, error) {
+	tag, err := tags.NewID3v2FromFile(file)
+	var noTag *tags.NoID3v2IdentifierError
+	if errors.As(err, &noTag) {
+		return tags.NewID3v2(), nil
+	}
+	return tag, err
+}
+
+// readTagToChange reads the tag of the file once and returns it along with a copy to change.
+func readTagToChange(file string) (before, tag *tags.ID3v2, err error) {
+	before, err = readTag(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	tag, err = before.Clone()
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "%s", file)
+	}
+	return before, tag, nil
+}
+
+// tagFile applies the config to the tag of src and writes the result to dst.
+func tagFile(cfg *tagger.Config, src, dst string, dryRun bool) error {
+	before, tag, err := readTagToChange(src)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Apply(tag); err != nil {
+		return err
+	}
+	return writeChanges(before, tag, src, dst, dryRun)
+}
+
+// copyTags copies the frames of the src tag to the tag of dst and writes it.
+func copyTags(src, dst string, only, except []string, dryRun bool) error {
+	from, err := readTag(src)
+	if err != nil {
+		return err
+	}
+	before, tag, err := readTagToChange(dst)
+	if err != nil {
+		return err
+	}
+	if err := tag.CopyFrames(from, only, except); err != nil {
+		return err
+	}
+	return writeChanges(before, tag, dst, dst, dryRun)
+}
+
+// writeChanges prints how the tag of src changed and, unless it is a dry run, writes the result to dst.
+func writeChanges(before, tag *tags.ID3v2, src, dst string, dryRun bool) error {
+	changes := tags.Diff(before, tag)
+	fmt.Printf("%s:\n", src)
+	if len(changes) == 0 {
+		fmt.Println("  no changes")
+	}
+	for _, change := range changes {
+		fmt.Printf("  %s\n", change)
+	}
+	if dryRun {
+		return nil
+	}
+	// the tag is written even without changes when it is copied to another file
+	if len(changes) == 0 && src == dst {
+		return nil
+	}
+	if err := tag.WriteWithOptions(src, dst, writeOptions); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %q\n", dst)
+	return nil
+}
+
+// diffTags prints how the tag of b differs from the tag of a and reports if they are the same.
+// A file without a tag is compared as an empty tag.
+func diffTags(a, b string) (bool, error) {
+	before, err := readTag(a)
+	if err != nil {
+		return false, err
+	}
+	after, err := readTag(b)
+	if err != nil {
+		return false, err
+	}
+	changes := tags.Diff(before, after)
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	return len(changes) == 0, nil
+}
+
+// splitKeys splits a comma separated list of frame keys and checks each one.
+func splitKeys(list string) ([]string, error) {
+	if list == "" {
+		return nil, nil
+	}
+	keys := strings.Split(list, ",")
+	for _, key := range keys {
+		if err := frames.ValidateKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// importSidecar replaces the tag of every file in the sidecar file and reports if all of them succeeded.
+func importSidecar(sidecar string, dryRun bool) bool {
+	entries, err := tagger.LoadSidecar(sidecar)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	ok := true
+	for _, entry := range entries {
+		if err := importEntry(entry, dryRun); err != nil {
+			ok = false
+			fmt.Printf("%s: %v\n", entry.File, err)
+		}
+	}
+	return ok
+}
+
+func importEntry(entry *tagger.SidecarEntry, dryRun bool) error {
+	before, tag, err := readTagToChange(entry.File)
+	if err != nil {
+		return err
+	}
+	if err := entry.Apply(tag); err != nil {
+		return err
+	}
+	return writeChanges(before, tag, entry.File, entry.File, dryRun)
+}
+
+// extractAttachments writes every picture and object in the tag of the file to dir.
+func extractAttachments(file, dir string) error {
+	tag, err := tags.NewID3v2FromFile(file)
+	if err != nil {
+		return err
+	}
+	attachments := tag.Attachments()
+	if len(attachments) == 0 {
+		fmt.Println("no pictures or objects")
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, attachment := range attachments {
+		dst, err := frames.WriteNewFile(dir, attachment.Name, attachment.Reader)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("wrote %q\n", dst)
+	}
+	return nil
+}
+
+// closeJournal closes the journal of a template-tag run and removes it if nothing was recorded, since there is nothing to undo.
+func closeJournal(journal *tagger.Journal) error {
+	if err := journal.Close(); err != nil {
+		return err
+	}
+	if journal.Entries() > 0 {
+		return nil
+	}
+	return errors.WithStack(os.Remove(journal.Name()))
+}
+
+// undoJournal restores every file in the journal and reports if all of them were restored.
+func undoJournal(file string, dryRun bool) bool {
+	entries, err := tagger.LoadJournal(file)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	if dryRun {
+		ok := true
+		for _, entry := range entries {
+			if err := entry.Verify(); err != nil {
+				ok = false
+				fmt.Printf("%s: %v\n", entry.Path, err)
+				continue
+			}
+			fmt.Printf("would restore %q\n", entry.Path)
+		}
+		return ok
+	}
+	restored, err := tagger.UndoJournal(entries)
+	for _, path := range restored {
+		fmt.Printf("restored %q\n", path)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
@@ -1,10 +1,13 @@
 package tagger
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
+	"regexp"
+	"sort"
 
 	"github.com/chuckha/tagger/id3v23/frames"
+	"github.com/chuckha/tagger/id3v23/tags"
 
 	"gitlab.com/tozd/go/errors"
 )
@@ -13,43 +16,268 @@ import (
 // There is an extraction language available to extract values from the path and put them into tags.
 // This also supports file-based additions like lyrics or pictures as well as things like compression.
 type Config struct {
+	// Frames are the frames of the Frames object keyed by frame key, e.g. "TIT2" or "TXXX:Narrator".
+	// They are set before the Operations, in the order they were written.
 	Frames map[string]frames.FrameBody
+	// Operations are executed in order after the Frames.
+	// A key of the Frames object that holds a list of frames is set by operations at the start of the list.
+	Operations []tags.Operation
+
+	// keys is the order the Frames were written in.
+	keys []string
+	// verbatim keeps text frames exactly as they are written instead of normalizing separators and genres,
+	// so frames that were exported from a tag can be restored byte for byte. Their formats are not validated either.
+	verbatim bool
 }
 
 func NewConfig() *Config {
 	return &Config{
-		Frames: make(map[string]frames.FrameBody),
+		Frames:     make(map[string]frames.FrameBody),
+		Operations: []tags.Operation{},
 	}
 }
 
+// UnmarshalJSON reads the Frames object and the Operations list.
+// Each key of Frames is a frame key such as "TIT2" or "TXXX:Narrator" and each value is a frame or a list of frames.
 func (c *Config) UnmarshalJSON(data []byte) error {
 	var cfg struct {
-		Frames map[string]json.RawMessage
+		Frames     json.RawMessage
+		Operations []struct {
+			Op      tags.OperationKind
+			Frame   string
+			Value   json.RawMessage
+			Pattern string
+		}
 	}
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return errors.WithStack(err)
 	}
-	for k, data := range cfg.Frames {
-		switch frames.IDToFrameKind[k] {
-		case frames.TextInformationKind:
-			ti := &frames.TextInformation{}
-			if err := ti.UnmarshalJSON(data); err != nil {
-				return errors.WithStack(err)
+	keys, values, err := orderedObject(cfg.Frames)
+	if err != nil {
+		return err
+	}
+	if c.Frames == nil {
+		c.Frames = make(map[string]frames.FrameBody)
+	}
+	for i, key := range keys {
+		fs, err := unmarshalFrames(key, values[i], c.verbatim)
+		if err != nil {
+			return errors.Errorf("frame %q: %w", key, err)
+		}
+		// exported tags keep the exact order of their frames so everything is an operation
+		if len(fs) == 1 && !c.verbatim {
+			c.Frames[key] = fs[0].Body
+			c.keys = append(c.keys, key)
+			continue
+		}
+		for _, frame := range fs {
+			c.Operations = append(c.Operations, tags.Operation{Kind: tags.Set, Key: key, Frame: frame})
+		}
+	}
+	for i, in := range cfg.Operations {
+		switch in.Op {
+		case tags.Remove:
+			c.Operations = append(c.Operations, tags.Operation{Kind: in.Op, Key: in.Frame})
+		case tags.RemoveMatching:
+			pattern, err := regexp.Compile(in.Pattern)
+			if err != nil {
+				return errors.Errorf("operation %d on frame %q: %w", i, in.Frame, err)
 			}
-			c.Frames[k] = ti
-		case frames.AttachedPictureKind:
-			ap := &frames.AttachedPicture{}
-			if err := ap.UnmarshalJSON(data); err != nil {
-				return errors.WithStack(err)
+			c.Operations = append(c.Operations, tags.Operation{Kind: in.Op, Key: in.Frame, Pattern: pattern})
+		case tags.Set, tags.SetIfMissing, tags.AppendValue:
+			fs, err := unmarshalFrames(in.Frame, in.Value, c.verbatim)
+			if err != nil {
+				return errors.Errorf("operation %d on frame %q: %w", i, in.Frame, err)
+			}
+			for _, frame := range fs {
+				c.Operations = append(c.Operations, tags.Operation{Kind: in.Op, Key: in.Frame, Frame: frame})
 			}
-			c.Frames[k] = ap
 		default:
-			panic(fmt.Sprintf("config does not support frame %q", k))
+			return errors.Errorf("operation %d on frame %q: unknown operation %q", i, in.Frame, in.Op)
+		}
+	}
+	return c.Validate()
+}
+
+// Apply runs the config on the tag: the Frames are set and then the Operations are executed.
+func (c *Config) Apply(tag *tags.ID3v2) error {
+	return tag.ApplyOperations(c.operations()...)
+}
+
+// operations turns the Frames into set operations, in the order they were written, followed by the Operations.
+// Frames added to the map directly come after the written ones, sorted by key.
+func (c *Config) operations() []tags.Operation {
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, key := range c.keys {
+		if _, ok := c.Frames[key]; ok && !seen[key] {
+			keys = append(keys, key)
+			seen[key] = true
+		}
+	}
+	added := []string{}
+	for key := range c.Frames {
+		if !seen[key] {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	ops := make([]tags.Operation, 0, len(c.Frames)+len(c.Operations))
+	for _, key := range append(keys, added...) {
+		id, _ := frames.ParseKey(key)
+		ops = append(ops, tags.Operation{Kind: tags.Set, Key: key, Frame: frames.NewFrame(id, c.Frames[key])})
+	}
+	return append(ops, c.Operations...)
+}
+
+// setFrames returns every frame the config sets.
+func (c *Config) setFrames() []*frames.Frame {
+	fs := []*frames.Frame{}
+	for _, op := range c.operations() {
+		if op.Kind == tags.Set {
+			fs = append(fs, op.Frame)
+		}
+	}
+	return fs
+}
+
+// orderedObject splits a JSON object into its keys and values keeping the order they were written in.
+func orderedObject(data []byte) ([]string, []json.RawMessage, error) {
+	keys := []string{}
+	values := []json.RawMessage{}
+	if len(bytes.TrimSpace(data)) == 0 || string(data) == "null" {
+		return keys, values, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, errors.Errorf("expected an object, got %s", data)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		keys = append(keys, tok.(string))
+		values = append(values, value)
+	}
+	return keys, values, nil
+}
+
+// unmarshalFrames reads a single frame or a list of frames for the frame key.
+func unmarshalFrames(key string, data json.RawMessage, verbatim bool) ([]*frames.Frame, error) {
+	raws := []json.RawMessage{data}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(data, &raws); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	id, qualifier := frames.ParseKey(key)
+	out := make([]*frames.Frame, 0, len(raws))
+	for _, raw := range raws {
+		body, err := frames.NewFrameBody(id)
+		if err != nil {
+			return nil, err
+		}
+		if err := body.UnmarshalJSON(raw); err != nil {
+			return nil, err
+		}
+		if err := frames.Qualify(id, body, qualifier); err != nil {
+			return nil, err
+		}
+		if ti, ok := body.(*frames.TextInformation); ok && !verbatim {
+			// use the separator this frame expects for multiple values
+			ti.SetValues(id, ti.Values(id))
+			if id == "TCON" {
+				// genres can be set by name or number; always write them as id3v2.3 references
+				body = frames.NewTextInformation(frames.NewContentType(ti.Values(id)...).String())
+			}
 		}
+		if err := overrideEncoding(body, raw); err != nil {
+			return nil, err
+		}
+		out = append(out, frames.NewFrame(id, body))
+	}
+	return out, nil
+}
+
+// overrideEncoding applies the TextEncoding of a frame, if it has one, in place of the encoding picked for its text.
+func overrideEncoding(body frames.FrameBody, raw json.RawMessage) error {
+	var in struct {
+		TextEncoding string
+	}
+	if err := json.Unmarshal(raw, &in); err != nil || in.TextEncoding == "" {
+		// the body has already been read so any other error has been reported
+		return nil
+	}
+	enc, err := frames.ParseEncoding(in.TextEncoding)
+	if err != nil {
+		return err
+	}
+	return frames.SetEncoding(body, enc)
+}
+
+// Validate checks every frame that has a format defined by the spec, such as TRCK or TYER,
+// that every key removed names a supported frame,
+// and that the frames being set do not break the rules for how many of each frame may exist.
+// The operations are replayed in order like ApplyOperations, so a frame that was removed may be set again.
+// All problems are reported at once so a config can be fixed in one go.
+func (c *Config) Validate() error {
+	errs := []error{}
+	tag := tags.NewID3v2()
+	for i, op := range c.operations() {
+		switch op.Kind {
+		case tags.Remove, tags.RemoveMatching:
+			if err := frames.ValidateKey(op.Key); err != nil {
+				errs = append(errs, errors.WithMessagef(err, "operation %d: %s %q", i, op.Kind, op.Key))
+				continue
+			}
+		case tags.Set, tags.SetIfMissing, tags.AppendValue:
+			if err := c.validateFrame(tag, op); err != nil {
+				errs = append(errs, errors.WithMessagef(err, "operation %d", i))
+				continue
+			}
+		}
+		if err := tag.ApplyOperations(op); err != nil {
+			errs = append(errs, errors.WithMessagef(err, "operation %d", i))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
 	return nil
 }
 
+// validateFrame checks the formats of the frame of the operation and that a set does not conflict with a frame the config already set on the tag.
+func (c *Config) validateFrame(tag *tags.ID3v2, op tags.Operation) error {
+	frame := op.Frame
+	id := frame.Header.ID
+	errs := []error{}
+	if ti, ok := frame.Body.(*frames.TextInformation); ok && !c.verbatim {
+		for _, val := range ti.Values(id) {
+			if err := frames.ValidateText(id, val); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	rule, err := frames.RuleFor(id)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	if op.Kind == tags.Set {
+		for _, existing := range *tag.Frames {
+			if rule.Conflicts(existing, frame) {
+				errs = append(errs, errors.Errorf("%s is set twice (%s); %s", op.Key, frames.Descriptions[id], rule.Description))
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 /*
 
 // read in a directory and walk it
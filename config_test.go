@@ -0,0 +1,156 @@
+package tagger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+	"github.com/chuckha/tagger/id3v23/tags"
+)
+
+func TestConfigFrames(t *testing.T) {
+	cfg := NewConfig()
+	err := cfg.UnmarshalJSON([]byte(`{
+    "Frames": {
+        "TIT2": {"Information": "Spinner's End"},
+        "TXXX:Narrator": {"Value": "Stephen Fry"},
+        "COMM": [{"Language": "eng", "ActualText": "a"}, {"Language": "jpn", "ActualText": "b"}]
+    },
+    "Operations": [{"Op": "remove", "Frame": "TALB"}]
+}`))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(cfg.Frames) != 2 {
+		t.Fatalf("expected the single frames to be in Frames, got %v", cfg.Frames)
+	}
+	if ti, ok := cfg.Frames["TIT2"].(*frames.TextInformation); !ok || string(ti.Information) != "Spinner's End" {
+		t.Fatalf("unexpected TIT2 %v", cfg.Frames["TIT2"])
+	}
+	if _, ok := cfg.Frames["TXXX:Narrator"].(*frames.UserDefinedTextInformation); !ok {
+		t.Fatalf("unexpected TXXX:Narrator %v", cfg.Frames["TXXX:Narrator"])
+	}
+	if len(cfg.Operations) != 3 {
+		t.Fatalf("expected the list of comments and the remove to be operations, got %d", len(cfg.Operations))
+	}
+	// frames can be added to the map directly
+	cfg.Frames["TPE1"] = frames.NewTextInformation("Jim Dale")
+	tag := tags.NewID3v2()
+	if err := tag.SetFrames(frames.NewFrame("TALB", frames.NewTextInformation("album"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Apply(tag); err != nil {
+		t.Fatal(err)
+	}
+	expectText(t, tag, "TIT2", "Spinner's End")
+	expectText(t, tag, "TPE1", "Jim Dale")
+	if tag.TextFrame("TALB") != nil {
+		t.Fatal("expected TALB to be removed")
+	}
+	got := []string{}
+	for _, frame := range *tag.Frames {
+		got = append(got, frame.Header.ID)
+	}
+	if expected := "TIT2 TXXX TPE1 COMM COMM"; strings.Join(got, " ") != expected {
+		t.Fatalf("expected frames %s, got %s", expected, strings.Join(got, " "))
+	}
+}
+
+func TestConfigFramesCanBeAppliedAsAMap(t *testing.T) {
+	cfg := NewConfig()
+	if err := cfg.UnmarshalJSON([]byte(`{"Frames": {"TIT2": {"Information": "title"}, "TXXX:Narrator": {"Value": "Stephen Fry"}}}`)); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	tag := tags.NewID3v2()
+	if err := tag.ApplyFrames(cfg.Frames); err != nil {
+		t.Fatal(err)
+	}
+	expectText(t, tag, "TIT2", "title")
+	if fs := tag.FramesWithKey("TXXX:Narrator"); len(fs) != 1 || fs[0].Header.ID != "TXXX" {
+		t.Fatalf("expected TXXX:Narrator to be set, got %v", fs)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	testcases := []struct {
+		name  string
+		cfg   string
+		valid bool
+	}{
+		{
+			name:  "set, remove and set again",
+			cfg:   `{"Operations": [{"Op": "set", "Frame": "TPE1", "Value": {"Information": "a"}}, {"Op": "remove", "Frame": "TPE1"}, {"Op": "set", "Frame": "TPE1", "Value": {"Information": "b"}}]}`,
+			valid: true,
+		},
+		{
+			name:  "set after a frame is removed by its text",
+			cfg:   `{"Frames": {"COMM:eng": {"ActualText": "old"}}, "Operations": [{"Op": "remove-matching", "Frame": "COMM", "Pattern": "old"}, {"Op": "set", "Frame": "COMM:eng", "Value": {"ActualText": "new"}}]}`,
+			valid: true,
+		},
+		{
+			name:  "set if missing after a set",
+			cfg:   `{"Frames": {"TCON": {"Information": "Rock"}}, "Operations": [{"Op": "set-if-missing", "Frame": "TCON", "Value": {"Information": "Folk"}}]}`,
+			valid: true,
+		},
+		{
+			name:  "append after a set",
+			cfg:   `{"Frames": {"TPE1": {"Information": "a"}}, "Operations": [{"Op": "append-value", "Frame": "TPE1", "Value": {"Information": "b"}}]}`,
+			valid: true,
+		},
+		{
+			name: "set twice",
+			cfg:  `{"Frames": {"TPE1": {"Information": "a"}}, "Operations": [{"Op": "set", "Frame": "TPE1", "Value": {"Information": "b"}}]}`,
+		},
+		{
+			name: "set again after a remove that did not match",
+			cfg:  `{"Frames": {"COMM:eng": {"ActualText": "old"}}, "Operations": [{"Op": "remove-matching", "Frame": "COMM", "Pattern": "new"}, {"Op": "set", "Frame": "COMM:eng", "Value": {"ActualText": "new"}}]}`,
+		},
+		{
+			name: "remove without a key",
+			cfg:  `{"Operations": [{"Op": "remove", "Frame": ""}]}`,
+		},
+		{
+			name: "remove an unknown frame",
+			cfg:  `{"Operations": [{"Op": "remove", "Frame": "ZZZZ"}]}`,
+		},
+		{
+			name: "remove matching a bad key",
+			cfg:  `{"Operations": [{"Op": "remove-matching", "Frame": "COMM:english", "Pattern": "x"}]}`,
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewConfig().UnmarshalJSON([]byte(tt.cfg))
+			if tt.valid && err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expected the config to be invalid")
+			}
+		})
+	}
+}
+
+func TestConfigValuesSurviveAWrite(t *testing.T) {
+	cfg := NewConfig()
+	if err := cfg.UnmarshalJSON([]byte(`{"Frames": {"TPE1": {"Information": ["A", "B"]}, "TLAN": {"Information": ["eng", "jpn"]}}}`)); err != nil {
+		t.Fatal(err)
+	}
+	tag := tags.NewID3v2()
+	if err := cfg.Apply(tag); err != nil {
+		t.Fatal(err)
+	}
+	out, err := tag.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := tags.NewID3v2()
+	if err := read.UnmarshalBinary(out); err != nil {
+		t.Fatal(err)
+	}
+	for id, expected := range map[string]string{"TPE1": "A B", "TLAN": "eng jpn"} {
+		if got := strings.Join(read.TextFrame(id).Values(id), " "); got != expected {
+			t.Errorf("expected %s values %q, got %q", id, expected, got)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package tagger
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// DataSource is a table of rows loaded from a file that is joined to every matched file.
+// The row whose Key column equals the On value of a file is available to the templates.
+type DataSource struct {
+	// Name is the name of the rows in templates, {{.rows.Name}}. It defaults to the file name without the extension.
+	Name string
+	Path string
+	// Format is csv, tsv, json or cue. It defaults to the extension of the Path.
+	Format string
+	// Key is the column of a row that is joined on. Cue sheets default to Track.
+	Key string
+	// On is the value of the matched file that is joined to Key, e.g. "$part$" or "filename".
+	On FileKey
+	// Optional allows files without a row; they get an empty row.
+	Optional bool
+
+	rows map[string]map[string]any
+}
+
+// Load reads the rows of the data source and indexes them by Key.
+func (d *DataSource) Load() error {
+	if d.Path == "" {
+		return errors.New("a data source needs a Path")
+	}
+	if d.Name == "" {
+		d.Name = strings.TrimSuffix(filepath.Base(d.Path), filepath.Ext(d.Path))
+	}
+	if d.Format == "" {
+		d.Format = strings.ToLower(strings.TrimPrefix(filepath.Ext(d.Path), "."))
+	}
+	if d.Key == "" && d.Format == "cue" {
+		d.Key = "Track"
+	}
+	if d.Key == "" {
+		return errors.Errorf("data source %q needs a Key", d.Name)
+	}
+	if d.On == "" {
+		return errors.Errorf("data source %q needs a file key to join On", d.Name)
+	}
+	f, err := os.Open(d.Path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	var rows []map[string]any
+	switch d.Format {
+	case "csv":
+		rows, err = readDelimited(f, ',')
+	case "tsv":
+		rows, err = readDelimited(f, '\t')
+	case "json":
+		err = errors.WithStack(json.NewDecoder(f).Decode(&rows))
+	case "cue":
+		rows, err = readCue(f)
+	default:
+		return errors.Errorf("data source %q has an unknown format %q; use csv, tsv, json or cue", d.Name, d.Format)
+	}
+	if err != nil {
+		return errors.WithMessagef(err, "data source %q", d.Name)
+	}
+	d.rows = map[string]map[string]any{}
+	for i, row := range rows {
+		v, ok := row[d.Key]
+		if !ok {
+			return errors.Errorf("data source %q: row %d has no %q column", d.Name, i+1, d.Key)
+		}
+		key := joinKey(fmt.Sprint(v))
+		if _, ok := d.rows[key]; ok {
+			return errors.Errorf("data source %q: more than one row has %s %q", d.Name, d.Key, v)
+		}
+		d.rows[key] = row
+	}
+	return nil
+}
+
+// row returns the row joined to the matched file.
+func (d *DataSource) row(m *match) (map[string]any, error) {
+	on := d.On.value(m)
+	if row, ok := d.rows[joinKey(on)]; ok {
+		return row, nil
+	}
+	if d.Optional {
+		return map[string]any{}, nil
+	}
+	return nil, errors.Errorf("data source %q has no row where %s is %q", d.Name, d.Key, on)
+}
+
+// joinKey normalizes a value so captured digits like "07" join to a row numbered 7.
+func joinKey(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.IndexFunc(s, func(r rune) bool { return !isDigit(r) }) >= 0 {
+		return s
+	}
+	return string(trimZeros([]rune(s)))
+}
+
+// readDelimited reads rows keyed by the columns of the header row.
+func readDelimited(r io.Reader, comma rune) ([]map[string]any, error) {
+	cr := csv.NewReader(skipBOM(r))
+	cr.Comma = comma
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	rows := make([]map[string]any, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := map[string]any{}
+		for i, column := range header {
+			row[column] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// skipBOM drops the UTF-8 byte order mark spreadsheet programs put at the start of exported files.
+// Otherwise it ends up in the name of the first column.
+func skipBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		_, _ = br.Discard(3)
+	}
+	return br
+}
+
+// readCue reads a row for every TRACK of a cue sheet.
+// Rows have the Track number, Title, Performer, Songwriter, ISRC, the Index 01 time and the File the track is in,
+// as well as the Album, AlbumPerformer, AlbumSongwriter and REM values, e.g. Genre and Date, of the whole sheet.
+func readCue(r io.Reader) ([]map[string]any, error) {
+	album := map[string]any{}
+	rows := []map[string]any{}
+	var file string
+	var current map[string]any
+	scanner := bufio.NewScanner(skipBOM(r))
+	for line := 1; scanner.Scan(); line++ {
+		fields := cueFields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		arg := func(i int) string {
+			if i < len(fields) {
+				return fields[i]
+			}
+			return ""
+		}
+		// values before the first track describe the album
+		set := func(key string) {
+			if current == nil {
+				album["Album"+key] = arg(1)
+				return
+			}
+			current[key] = arg(1)
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "REM":
+			album[cueName(arg(1))] = arg(2)
+		case "FILE":
+			file = arg(1)
+		case "TRACK":
+			if arg(1) == "" {
+				return nil, errors.Errorf("line %d: TRACK needs a number", line)
+			}
+			current = map[string]any{"Track": arg(1), "File": file}
+			rows = append(rows, current)
+		case "TITLE":
+			if current == nil {
+				album["Album"] = arg(1)
+				continue
+			}
+			current["Title"] = arg(1)
+		case "PERFORMER":
+			set("Performer")
+		case "SONGWRITER":
+			set("Songwriter")
+		case "ISRC":
+			if current != nil {
+				current["ISRC"] = arg(1)
+			}
+		case "INDEX":
+			if current != nil && arg(1) == "01" {
+				current["Index"] = arg(2)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, row := range rows {
+		for k, v := range album {
+			if _, ok := row[k]; !ok {
+				row[k] = v
+			}
+		}
+	}
+	return rows, nil
+}
+
+// cueFields splits a cue sheet line into fields; quoted fields may contain spaces.
+func cueFields(line string) []string {
+	fields := []string{}
+	line = strings.TrimSpace(line)
+	for line != "" {
+		if line[0] == '"' {
+			end := strings.IndexByte(line[1:], '"')
+			if end < 0 {
+				fields = append(fields, line[1:])
+				break
+			}
+			fields = append(fields, line[1:end+1])
+			line = strings.TrimSpace(line[end+2:])
+			continue
+		}
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			fields = append(fields, line)
+			break
+		}
+		fields = append(fields, line[:end])
+		line = strings.TrimSpace(line[end:])
+	}
+	return fields
+}
+
+// cueName turns a REM name like GENRE or DISCID into Genre or Discid.
+func cueName(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+}
@@ -0,0 +1,171 @@
+package tagger
+
+import (
+	"strings"
+	"testing"
+)
+
+const cueSheet = `REM GENRE Audiobook
+REM DATE 2005
+PERFORMER "Stephen Fry"
+TITLE "Harry Potter and the Half-Blood Prince"
+FILE "hbp.mp3" MP3
+  TRACK 01 AUDIO
+    TITLE "The Other Minister"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Spinner's End"
+    PERFORMER "Jim Dale"
+    INDEX 00 31:10:00
+    INDEX 01 31:12:40
+`
+
+func TestDataSourceLoad(t *testing.T) {
+	dir := t.TempDir()
+	testcases := []struct {
+		name     string
+		file     string
+		contents string
+		source   DataSource
+		key      string
+		column   string
+		expected any
+	}{
+		{
+			name:     "csv",
+			file:     "chapters.csv",
+			contents: "Track,Title\n1,The Other Minister\n2,\"Spinner's End, Part 1\"\n",
+			source:   DataSource{Key: "Track", On: "$part$"},
+			key:      "2", column: "Title", expected: "Spinner's End, Part 1",
+		},
+		{
+			name:     "csv with a byte order mark",
+			file:     "chapters.csv",
+			contents: "\xef\xbb\xbfTrack,Title\n1,The Other Minister\n",
+			source:   DataSource{Key: "Track", On: "$part$"},
+			key:      "1", column: "Title", expected: "The Other Minister",
+		},
+		{
+			name:     "tsv",
+			file:     "chapters.tsv",
+			contents: "File\tTitle\nTrack 01.mp3\tThe Other Minister\n",
+			source:   DataSource{Key: "File", On: "filename"},
+			key:      "Track 01.mp3", column: "Title", expected: "The Other Minister",
+		},
+		{
+			name:     "json",
+			file:     "chapters.json",
+			contents: `[{"Track": 1, "Title": "The Other Minister", "Readers": ["Stephen Fry"]}]`,
+			source:   DataSource{Key: "Track", On: "$part$"},
+			key:      "1", column: "Title", expected: "The Other Minister",
+		},
+		{
+			name:     "format",
+			file:     "chapters.txt",
+			contents: "Track,Title\n1,The Other Minister\n",
+			source:   DataSource{Format: "csv", Key: "Track", On: "$part$"},
+			key:      "1", column: "Title", expected: "The Other Minister",
+		},
+		{
+			name:     "cue",
+			file:     "hbp.cue",
+			contents: cueSheet,
+			source:   DataSource{On: "$part$"},
+			key:      "2", column: "Performer", expected: "Jim Dale",
+		},
+		{
+			name:     "cue with a byte order mark",
+			file:     "hbp.cue",
+			contents: "\xef\xbb\xbf" + cueSheet,
+			source:   DataSource{On: "$part$"},
+			key:      "1", column: "Genre", expected: "Audiobook",
+		},
+		{
+			name:     "cue album",
+			file:     "hbp.cue",
+			contents: cueSheet,
+			source:   DataSource{On: "$part$"},
+			key:      "1", column: "Album", expected: "Harry Potter and the Half-Blood Prince",
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := tt.source
+			ds.Path = writeFile(t, dir, tt.file, tt.contents)
+			if err := ds.Load(); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if ds.Name != strings.Split(tt.file, ".")[0] {
+				t.Fatalf("unexpected name %q", ds.Name)
+			}
+			row, ok := ds.rows[tt.key]
+			if !ok {
+				t.Fatalf("expected a row for %q in %v", tt.key, ds.rows)
+			}
+			if row[tt.column] != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, row[tt.column])
+			}
+		})
+	}
+}
+
+func TestDataSourceLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	testcases := []struct {
+		name     string
+		file     string
+		contents string
+		source   DataSource
+		expected string
+	}{
+		{name: "duplicate", file: "a.csv", contents: "Track\n1\n01\n", source: DataSource{Key: "Track", On: "$part$"}, expected: `more than one row has Track "01"`},
+		{name: "missing column", file: "b.csv", contents: "Title\nA\n", source: DataSource{Key: "Track", On: "$part$"}, expected: `row 1 has no "Track" column`},
+		{name: "ragged", file: "c.csv", contents: "Track,Title\n1\n", source: DataSource{Key: "Track", On: "$part$"}, expected: "wrong number of fields"},
+		{name: "format", file: "d.xls", contents: "", source: DataSource{Key: "Track", On: "$part$"}, expected: `unknown format "xls"`},
+		{name: "no key", file: "e.csv", contents: "", source: DataSource{On: "$part$"}, expected: "needs a Key"},
+		{name: "no on", file: "f.csv", contents: "", source: DataSource{Key: "Track"}, expected: "needs a file key to join On"},
+		{name: "json", file: "g.json", contents: `{"Track": 1}`, source: DataSource{Key: "Track", On: "$part$"}, expected: "cannot unmarshal"},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := tt.source
+			ds.Path = writeFile(t, dir, tt.file, tt.contents)
+			err := ds.Load()
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q in %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestReadCue(t *testing.T) {
+	rows, err := readCue(strings.NewReader(cueSheet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 tracks, got %d", len(rows))
+	}
+	expected := map[string]any{
+		"Track":          "02",
+		"Title":          "Spinner's End",
+		"Performer":      "Jim Dale",
+		"Index":          "31:12:40",
+		"File":           "hbp.mp3",
+		"Album":          "Harry Potter and the Half-Blood Prince",
+		"AlbumPerformer": "Stephen Fry",
+		"Genre":          "Audiobook",
+		"Date":           "2005",
+	}
+	for k, v := range expected {
+		if rows[1][k] != v {
+			t.Errorf("expected %s to be %q, got %q", k, v, rows[1][k])
+		}
+	}
+	if rows[0]["Performer"] != nil {
+		t.Errorf("expected the first track to have no performer of its own, got %q", rows[0]["Performer"])
+	}
+}
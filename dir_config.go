@@ -0,0 +1,209 @@
+package tagger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// DirConfigFile is the name of the file that configures every file in its directory and the directories below it.
+const DirConfigFile = ".tagger.json"
+
+// DirConfig is the merge of every DirConfigFile from a root directory down to a directory.
+// Objects are merged key by key, a deeper file replaces any other value and a null removes the key.
+type DirConfig struct {
+	Values map[string]any
+	// Sources is the file each value came from keyed by its dotted path, e.g. "Frames.TALB.Information".
+	Sources map[string]string
+}
+
+func NewDirConfig() *DirConfig {
+	return &DirConfig{
+		Values:  map[string]any{},
+		Sources: map[string]string{},
+	}
+}
+
+// LoadDirConfig merges the DirConfigFile of root and of every directory between root and dir.
+func LoadDirConfig(root, dir string) (*DirConfig, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, errors.Errorf("%q is not inside %q", dir, root)
+	}
+	dc, err := NewDirConfig().Merge(filepath.Join(root, DirConfigFile))
+	if err != nil {
+		return nil, err
+	}
+	if rel == "." {
+		return dc, nil
+	}
+	current := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		dc, err = dc.Merge(filepath.Join(current, DirConfigFile))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dc, nil
+}
+
+// Merge returns a copy of the config with the file merged over it.
+// A file that does not exist changes nothing.
+// Relative paths of files read by its Frames are relative to the directory of the file.
+func (d *DirConfig) Merge(file string) (*DirConfig, error) {
+	out := &DirConfig{
+		Values:  copyObject(d.Values),
+		Sources: make(map[string]string, len(d.Sources)),
+	}
+	for k, v := range d.Sources {
+		out.Sources[k] = v
+	}
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var values map[string]any
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, errors.WithMessagef(errors.WithStack(err), "%s", file)
+	}
+	resolveFramePaths(values, filepath.Dir(file))
+	out.merge(out.Values, values, "", file)
+	return out, nil
+}
+
+// resolveFramePaths joins dir to the relative paths of the pictures and {"File": "path"} values of the Frames.
+func resolveFramePaths(values map[string]any, dir string) {
+	fs, _ := values["Frames"].(map[string]any)
+	for key, v := range fs {
+		id, _ := frames.ParseKey(key)
+		bodies, ok := v.([]any)
+		if !ok {
+			bodies = []any{v}
+		}
+		for _, body := range bodies {
+			fields, _ := body.(map[string]any)
+			for name, field := range fields {
+				switch field := field.(type) {
+				case string:
+					// the Data of a picture is always a path, optionally prefixed with @
+					if id == "APIC" && name == "Data" {
+						fields[name] = resolvePath(strings.TrimPrefix(field, "@"), dir)
+					}
+				case map[string]any:
+					if path, ok := field["File"].(string); ok {
+						field["File"] = resolvePath(path, dir)
+					}
+				}
+			}
+		}
+	}
+}
+
+func resolvePath(path, dir string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func (d *DirConfig) merge(dst, src map[string]any, prefix, file string) {
+	for k, v := range src {
+		path := prefix + k
+		if v == nil {
+			delete(dst, k)
+			d.forget(path)
+			continue
+		}
+		srcObj, srcIsObj := v.(map[string]any)
+		dstObj, dstIsObj := dst[k].(map[string]any)
+		if srcIsObj && dstIsObj {
+			d.merge(dstObj, srcObj, path+".", file)
+			continue
+		}
+		d.forget(path)
+		if srcIsObj {
+			obj := map[string]any{}
+			dst[k] = obj
+			d.merge(obj, srcObj, path+".", file)
+			continue
+		}
+		dst[k] = v
+		d.Sources[path] = file
+	}
+}
+
+// forget removes the source of the path and of everything below it.
+func (d *DirConfig) forget(path string) {
+	for k := range d.Sources {
+		if k == path || strings.HasPrefix(k, path+".") {
+			delete(d.Sources, k)
+		}
+	}
+}
+
+func copyObject(in map[string]any) map[string]any {
+	out := make(map[string]any, len(in))
+	for k, v := range in {
+		if obj, ok := v.(map[string]any); ok {
+			v = copyObject(obj)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// Frames returns the set operations of the merged Frames object.
+func (d *DirConfig) Frames() (*Config, error) {
+	cfg := NewConfig()
+	frames, ok := d.Values["Frames"]
+	if !ok {
+		return cfg, nil
+	}
+	b, err := json.Marshal(map[string]any{"Frames": frames})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := cfg.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Value returns the value at the dotted path as JSON.
+func (d *DirConfig) Value(path string) string {
+	var v any = d.Values
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return ""
+		}
+		v = obj[part]
+	}
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(b.String())
+}
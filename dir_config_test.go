@@ -0,0 +1,119 @@
+package tagger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+)
+
+func writeDirConfigs(t *testing.T, configs map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for dir, contents := range configs {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(root, dir), DirConfigFile, contents)
+	}
+	return root
+}
+
+func TestLoadDirConfig(t *testing.T) {
+	root := writeDirConfigs(t, map[string]string{
+		".":           `{"series": "Harry Potter", "Frames": {"TIT1": {"Information": "Harry Potter"}, "COMM": {"ActualText": "old"}}, "narrators": ["Stephen Fry"]}`,
+		"book6":       `{"book": 6, "Frames": {"TALB": {"Information": "Half-Blood Prince"}, "COMM": null}, "narrators": ["Jim Dale"]}`,
+		"book6/disc1": `{"Frames": {"TPOS": {"Information": "1/2"}}, "series": {"name": "HP"}}`,
+	})
+	dc, err := LoadDirConfig(root, filepath.Join(root, "book6", "disc1"))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	expected := map[string]struct{ value, source string }{
+		"Frames.TIT1.Information": {`"Harry Potter"`, "."},
+		"Frames.TALB.Information": {`"Half-Blood Prince"`, "book6"},
+		"Frames.TPOS.Information": {`"1/2"`, "book6/disc1"},
+		"book":                    {`6`, "book6"},
+		"narrators":               {`["Jim Dale"]`, "book6"},
+		"series.name":             {`"HP"`, "book6/disc1"},
+	}
+	if len(dc.Sources) != len(expected) {
+		t.Fatalf("expected %d values, got %v", len(expected), dc.Sources)
+	}
+	for path, e := range expected {
+		if got := dc.Value(path); got != e.value {
+			t.Errorf("expected %s to be %s, got %s", path, e.value, got)
+		}
+		if got := dc.Sources[path]; got != filepath.Join(root, e.source, DirConfigFile) {
+			t.Errorf("expected %s to come from %s, got %s", path, e.source, got)
+		}
+	}
+
+	parent, err := LoadDirConfig(root, filepath.Join(root, "book6"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.Value("series") != `"Harry Potter"` {
+		t.Fatalf("merging a child changed its parent: %v", parent.Values)
+	}
+}
+
+func TestLoadDirConfigErrors(t *testing.T) {
+	root := writeDirConfigs(t, map[string]string{"bad": `{"Frames": `})
+	if _, err := LoadDirConfig(root, filepath.Join(root, "bad")); err == nil || !strings.Contains(err.Error(), DirConfigFile) {
+		t.Fatalf("expected an error naming the file, got %v", err)
+	}
+	if _, err := LoadDirConfig(filepath.Join(root, "bad"), root); err == nil || !strings.Contains(err.Error(), "is not inside") {
+		t.Fatalf("expected a directory outside of the root to fail, got %v", err)
+	}
+}
+
+func TestLoadDirConfigRelativeRoot(t *testing.T) {
+	root := writeDirConfigs(t, map[string]string{"book6": `{"book": 6}`})
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(wd, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc, err := LoadDirConfig(rel, filepath.Join(root, "book6"))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if dc.Value("book") != "6" {
+		t.Fatalf("expected the config of book6, got %v", dc.Values)
+	}
+}
+
+func TestLoadDirConfigFramePaths(t *testing.T) {
+	root := writeDirConfigs(t, map[string]string{
+		".":     `{"Frames": {"APIC": {"Data": "@cover.png"}}}`,
+		"book6": `{"Frames": {"PRIV": [{"OwnerIdentifier": "shop", "Data": {"File": "data/shop.bin"}}]}}`,
+	})
+	writeFile(t, root, "cover.png", "\x89PNG\x0D\x0A\x1A\x0A")
+	if err := os.Mkdir(filepath.Join(root, "book6", "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(root, "book6", "data"), "shop.bin", "\x01\x02")
+	dc, err := LoadDirConfig(root, filepath.Join(root, "book6"))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	// the paths are relative to the config files, not to the working directory
+	cfg, err := dc.Frames()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	apic, ok := cfg.Frames["APIC"].(*frames.AttachedPicture)
+	if !ok || apic.MIMEType != "image/png" {
+		t.Fatalf("expected the picture next to the root config, got %v", cfg.Frames["APIC"])
+	}
+	priv, ok := cfg.Frames["PRIV"].(*frames.PrivateData)
+	if !ok || string(priv.Data) != "\x01\x02" {
+		t.Fatalf("expected the data next to the book6 config, got %v", cfg.Frames)
+	}
+}
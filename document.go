@@ -0,0 +1,479 @@
+package tagger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gitlab.com/tozd/go/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a file format configs can be written in.
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+	TOML Format = "toml"
+)
+
+// FormatOf picks the format of a file by its extension; a trailing .tmpl is ignored so config.yaml.tmpl is YAML.
+// Anything that is not YAML or TOML is JSON.
+func FormatOf(path string) Format {
+	path = strings.TrimSuffix(path, ".tmpl")
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return YAML
+	case ".toml":
+		return TOML
+	}
+	return JSON
+}
+
+// PositionError is a problem at a line and column of a config file.
+type PositionError struct {
+	File   string
+	Line   int
+	Column int
+	// Path is where in the document the problem is, e.g. Frames.TRCK.Information or Rules[1].FilePattern.
+	Path string
+	Err  error
+}
+
+func (e *PositionError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.File)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ":%d:%d", e.Line, e.Column)
+	}
+	b.WriteString(": ")
+	if e.Path != "" {
+		fmt.Fprintf(&b, "%s: ", e.Path)
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// node is a value of a config document in any format along with where it was written.
+type node struct {
+	// value is nil for objects and lists, which use keys and fields or items instead.
+	// Numbers are json.Numbers, except for YAML floats.
+	value  any
+	object bool
+	keys   []string
+	fields map[string]*node
+	list   bool
+	items  []*node
+	line   int
+	column int
+	// keyLine and keyColumn are where the key of a field of an object was written.
+	keyLine   int
+	keyColumn int
+}
+
+// kind is the JSON Schema type of the node.
+func (n *node) kind() string {
+	switch v := n.value; {
+	case n.object:
+		return "object"
+	case n.list:
+		return "array"
+	case v == nil:
+		return "null"
+	default:
+		switch v.(type) {
+		case string:
+			return "string"
+		case bool:
+			return "boolean"
+		}
+		return "number"
+	}
+}
+
+// MarshalJSON writes the node as JSON keeping the keys of objects in the order they were written in.
+func (n *node) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	switch {
+	case n.object:
+		b.WriteByte('{')
+		for i, key := range n.keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			k, err := json.Marshal(key)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			v, err := n.fields[key].MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+			b.Write(k)
+			b.WriteByte(':')
+			b.Write(v)
+		}
+		b.WriteByte('}')
+	case n.list:
+		b.WriteByte('[')
+		for i, item := range n.items {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			v, err := item.MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+			b.Write(v)
+		}
+		b.WriteByte(']')
+	default:
+		v, err := json.Marshal(n.value)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		b.Write(v)
+	}
+	return b.Bytes(), nil
+}
+
+// parseDocument reads a config document in the format.
+// Syntax errors are PositionErrors.
+func parseDocument(file string, format Format, b []byte) (*node, error) {
+	switch format {
+	case YAML:
+		return parseYAML(file, b)
+	case TOML:
+		return parseTOML(file, b)
+	}
+	return parseJSON(file, b)
+}
+
+// ToJSON converts a config document in the format to JSON.
+func ToJSON(file string, format Format, b []byte) ([]byte, error) {
+	if format == JSON {
+		return b, nil
+	}
+	n, err := parseDocument(file, format, b)
+	if err != nil {
+		return nil, err
+	}
+	return n.MarshalJSON()
+}
+
+// position converts a byte offset to a 1-indexed line and column.
+func position(src []byte, offset int) (int, int) {
+	if offset > len(src) {
+		offset = len(src)
+	}
+	before := src[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+type jsonParser struct {
+	file string
+	src  []byte
+	dec  *json.Decoder
+}
+
+func parseJSON(file string, b []byte) (*node, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	p := &jsonParser{file: file, src: b, dec: dec}
+	n, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err == nil {
+		line, column := p.position()
+		return nil, &PositionError{File: file, Line: line, Column: column, Err: errors.New("unexpected data after the document")}
+	}
+	return n, nil
+}
+
+// position is where the next token starts.
+func (p *jsonParser) position() (int, int) {
+	offset := int(p.dec.InputOffset())
+	for offset < len(p.src) && strings.IndexByte(" \t\r\n,:", p.src[offset]) >= 0 {
+		offset++
+	}
+	return position(p.src, offset)
+}
+
+func (p *jsonParser) error(err error) error {
+	offset := int(p.dec.InputOffset())
+	var se *json.SyntaxError
+	if errors.As(err, &se) {
+		offset = int(se.Offset)
+	}
+	line, column := position(p.src, offset)
+	return &PositionError{File: p.file, Line: line, Column: column, Err: err}
+}
+
+func (p *jsonParser) value() (*node, error) {
+	line, column := p.position()
+	tok, err := p.dec.Token()
+	if err != nil {
+		return nil, p.error(err)
+	}
+	n := &node{line: line, column: column}
+	switch tok {
+	case json.Delim('{'):
+		n.object = true
+		n.fields = map[string]*node{}
+		for p.dec.More() {
+			keyLine, keyColumn := p.position()
+			tok, err := p.dec.Token()
+			if err != nil {
+				return nil, p.error(err)
+			}
+			key := tok.(string)
+			child, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			child.keyLine, child.keyColumn = keyLine, keyColumn
+			if _, ok := n.fields[key]; !ok {
+				n.keys = append(n.keys, key)
+			}
+			n.fields[key] = child
+		}
+	case json.Delim('['):
+		n.list = true
+		for p.dec.More() {
+			child, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, child)
+		}
+	default:
+		n.value = tok
+		return n, nil
+	}
+	// the closing delimiter
+	if _, err := p.dec.Token(); err != nil {
+		return nil, p.error(err)
+	}
+	return n, nil
+}
+
+func parseYAML(file string, b []byte) (*node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, &PositionError{File: file, Err: errors.WithStack(err)}
+	}
+	if len(doc.Content) == 0 {
+		return &node{object: true, fields: map[string]*node{}}, nil
+	}
+	return fromYAML(file, doc.Content[0])
+}
+
+func fromYAML(file string, y *yaml.Node) (*node, error) {
+	n := &node{line: y.Line, column: y.Column}
+	switch y.Kind {
+	case yaml.AliasNode:
+		return fromYAML(file, y.Alias)
+	case yaml.MappingNode:
+		n.object = true
+		n.fields = map[string]*node{}
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			key := y.Content[i].Value
+			child, err := fromYAML(file, y.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			child.keyLine, child.keyColumn = y.Content[i].Line, y.Content[i].Column
+			if _, ok := n.fields[key]; !ok {
+				n.keys = append(n.keys, key)
+			}
+			n.fields[key] = child
+		}
+	case yaml.SequenceNode:
+		n.list = true
+		for _, item := range y.Content {
+			child, err := fromYAML(file, item)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, child)
+		}
+	default:
+		var v any
+		if err := y.Decode(&v); err != nil {
+			return nil, &PositionError{File: file, Line: y.Line, Column: y.Column, Err: errors.WithStack(err)}
+		}
+		// integers are written as they are, like numbers of JSON documents, so large ones keep every digit
+		switch x := v.(type) {
+		case int:
+			v = json.Number(strconv.Itoa(x))
+		case uint64:
+			v = json.Number(strconv.FormatUint(x, 10))
+		case int64:
+			v = json.Number(strconv.FormatInt(x, 10))
+		case string, bool, float64, nil:
+		default:
+			// timestamps and other YAML types are kept as they were written
+			v = y.Value
+		}
+		n.value = v
+	}
+	return n, nil
+}
+
+func parseTOML(file string, b []byte) (*node, error) {
+	var v map[string]any
+	md, err := toml.Decode(string(b), &v)
+	if err != nil {
+		var pe toml.ParseError
+		if errors.As(err, &pe) {
+			line, column := position(b, pe.Position.Start)
+			return nil, &PositionError{File: file, Line: line, Column: column, Err: errors.New(pe.Message)}
+		}
+		return nil, &PositionError{File: file, Err: errors.WithStack(err)}
+	}
+	// TOML tables are maps so the order keys were written in comes from the metadata
+	order := map[string]int{}
+	for i, key := range md.Keys() {
+		if _, ok := order[key.String()]; !ok {
+			order[key.String()] = i
+		}
+	}
+	positions := tomlPositions(b)
+	return fromTOML(v, nil, "", order, positions, 0, 0), nil
+}
+
+// fromTOML converts a decoded TOML value; key is the TOML key used for ordering and path the document path used for positions.
+func fromTOML(v any, key toml.Key, path string, order map[string]int, positions map[string][2]int, line, column int) *node {
+	if pos, ok := positions[path]; ok {
+		line, column = pos[0], pos[1]
+	}
+	n := &node{line: line, column: column, keyLine: line, keyColumn: column}
+	switch x := v.(type) {
+	case map[string]any:
+		n.object = true
+		n.fields = map[string]*node{}
+		for k := range x {
+			n.keys = append(n.keys, k)
+		}
+		childKey := func(k string) toml.Key {
+			return append(append(toml.Key{}, key...), k)
+		}
+		sort.SliceStable(n.keys, func(i, j int) bool {
+			return order[childKey(n.keys[i]).String()] < order[childKey(n.keys[j]).String()]
+		})
+		for _, k := range n.keys {
+			n.fields[k] = fromTOML(x[k], childKey(k), joinPath(path, k), order, positions, line, column)
+		}
+	case []map[string]any:
+		n.list = true
+		for i, item := range x {
+			n.items = append(n.items, fromTOML(item, key, fmt.Sprintf("%s[%d]", path, i), order, positions, line, column))
+		}
+	case []any:
+		n.list = true
+		for i, item := range x {
+			n.items = append(n.items, fromTOML(item, key, fmt.Sprintf("%s[%d]", path, i), order, positions, line, column))
+		}
+	case int64:
+		n.value = json.Number(strconv.FormatInt(x, 10))
+	default:
+		n.value = x
+	}
+	return n
+}
+
+// joinPath adds a key to a document path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// tomlPositions finds the line and column of the keys and tables of a TOML document by their document path.
+// The TOML library only reports positions of syntax errors, not of keys, so they are found by scanning the lines.
+// It understands the common forms, key = value, [table] and [[array of tables]], and leaves inline tables and
+// multi-line values to the position of the key they belong to.
+func tomlPositions(src []byte) map[string][2]int {
+	positions := map[string][2]int{}
+	arrays := map[string]int{}
+	table := ""
+	inString := ""
+	for i, line := range strings.Split(string(src), "\n") {
+		trimmed := strings.TrimSpace(line)
+		column := len(line) - len(strings.TrimLeft(line, " \t")) + 1
+		if inString != "" {
+			if strings.Count(trimmed, inString)%2 == 1 {
+				inString = ""
+			}
+			continue
+		}
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case strings.HasPrefix(trimmed, "[["):
+			name := tomlKeyPath(strings.TrimSuffix(strings.TrimPrefix(trimmed, "[["), "]]"))
+			table = fmt.Sprintf("%s[%d]", name, arrays[name])
+			arrays[name]++
+			positions[table] = [2]int{i + 1, column}
+		case strings.HasPrefix(trimmed, "["):
+			end := strings.LastIndexByte(trimmed, ']')
+			if end < 0 {
+				continue
+			}
+			table = tomlKeyPath(trimmed[1:end])
+			positions[table] = [2]int{i + 1, column}
+		default:
+			eq := strings.IndexByte(trimmed, '=')
+			if eq < 0 {
+				continue
+			}
+			positions[joinPath(table, tomlKeyPath(trimmed[:eq]))] = [2]int{i + 1, column}
+			value := strings.TrimSpace(trimmed[eq+1:])
+			for _, quote := range []string{`"""`, `'''`} {
+				if strings.HasPrefix(value, quote) && strings.Count(value, quote)%2 == 1 {
+					inString = quote
+				}
+			}
+		}
+	}
+	return positions
+}
+
+// tomlKeyPath turns a TOML key such as a."b.c" into a document path.
+func tomlKeyPath(key string) string {
+	parts := []string{}
+	var b strings.Builder
+	quote := byte(0)
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			b.WriteByte(c)
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '.':
+			parts = append(parts, strings.TrimSpace(b.String()))
+			b.Reset()
+		case c == ' ' || c == '\t':
+		default:
+			b.WriteByte(c)
+		}
+	}
+	parts = append(parts, strings.TrimSpace(b.String()))
+	return strings.Join(parts, ".")
+}
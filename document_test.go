@@ -0,0 +1,159 @@
+package tagger
+
+import (
+	"testing"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func TestFormatOf(t *testing.T) {
+	testcases := map[string]Format{
+		"config.json":      JSON,
+		"config.yaml":      YAML,
+		"config.YML":       YAML,
+		"config.toml":      TOML,
+		"config.yaml.tmpl": YAML,
+		"config.json.tmpl": JSON,
+		"config":           JSON,
+	}
+	for file, expected := range testcases {
+		if got := FormatOf(file); got != expected {
+			t.Errorf("expected %s to be %s, got %s", file, expected, got)
+		}
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	testcases := []struct {
+		name     string
+		format   Format
+		doc      string
+		expected string
+	}{
+		{
+			name:   "yaml",
+			format: YAML,
+			doc: `Frames:
+  TRCK: {Information: "1/17"}
+  TPE1:
+    Information: [Stephen Fry, Jim Dale]
+  TBPM: {Information: 120}
+  COMM: {ActualText: "say \"hi\"\u0000bye"}
+`,
+			expected: `{"Frames":{"TRCK":{"Information":"1/17"},"TPE1":{"Information":["Stephen Fry","Jim Dale"]},"TBPM":{"Information":120},"COMM":{"ActualText":"say \"hi\"\u0000bye"}}}`,
+		},
+		{
+			name:   "yaml anchors",
+			format: YAML,
+			doc: `Frames:
+  TPE1: &reader {Information: Stephen Fry}
+  TCOM: *reader
+`,
+			expected: `{"Frames":{"TPE1":{"Information":"Stephen Fry"},"TCOM":{"Information":"Stephen Fry"}}}`,
+		},
+		{
+			name:   "toml",
+			format: TOML,
+			doc: `[Frames.TRCK]
+Information = "1/17"
+
+[Frames."TXXX:Narrator"]
+Value = "say \"hi\"\u0000bye"
+
+[Frames.TALB]
+Information = "Harry Potter"
+
+[[Operations]]
+Op = "remove"
+Frame = "COMM"
+`,
+			expected: `{"Frames":{"TRCK":{"Information":"1/17"},"TXXX:Narrator":{"Value":"say \"hi\"\u0000bye"},"TALB":{"Information":"Harry Potter"}},"Operations":[{"Op":"remove","Frame":"COMM"}]}`,
+		},
+		{
+			name:     "yaml integers",
+			format:   YAML,
+			doc:      "Frames:\n  TLEN: {Information: 9007199254740993}\n  TBPM: {Information: 0x10}\n  TDLY: {Information: 1.5}\n",
+			expected: `{"Frames":{"TLEN":{"Information":9007199254740993},"TBPM":{"Information":16},"TDLY":{"Information":1.5}}}`,
+		},
+		{
+			name:     "toml integers",
+			format:   TOML,
+			doc:      "[Frames.TLEN]\nInformation = 9007199254740993\n",
+			expected: `{"Frames":{"TLEN":{"Information":9007199254740993}}}`,
+		},
+		{
+			name:     "json is unchanged",
+			format:   JSON,
+			doc:      `{"Frames": {}}`,
+			expected: `{"Frames": {}}`,
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := ToJSON("config", tt.format, []byte(tt.doc))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if string(b) != tt.expected {
+				t.Fatalf("expected\n%s\ngot\n%s", tt.expected, b)
+			}
+		})
+	}
+}
+
+func TestToJSONSyntaxErrors(t *testing.T) {
+	testcases := []struct {
+		name   string
+		format Format
+		doc    string
+		line   int
+	}{
+		{name: "json", format: JSON, doc: "{\n  \"Frames\": {\n    \"TIT2\": {\"Information\": \"a\",}\n  }\n}", line: 3},
+		{name: "yaml", format: YAML, doc: "Frames:\n  TIT2: {Information: a\n", line: 3},
+		{name: "toml", format: TOML, doc: "[Frames.TIT2]\nInformation = \"a\"\nInformation = \"b\"\n", line: 3},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseDocument("config", tt.format, []byte(tt.doc))
+			var pe *PositionError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expected a position error, got %v", err)
+			}
+			if tt.format != YAML && pe.Line != tt.line {
+				t.Fatalf("expected the error on line %d: %v", tt.line, err)
+			}
+		})
+	}
+}
+
+func TestTOMLPositions(t *testing.T) {
+	positions := tomlPositions([]byte(`FilePattern = "$track$.mp3"
+description = """
+  Information = "not a key"
+"""
+
+[[Rules]]
+  FilePattern = "a"
+
+[[Rules]]
+  FilePattern = "b"
+
+[Frames."TXXX:Narrator"]
+Value = "Stephen Fry"
+`))
+	expected := map[string][2]int{
+		"FilePattern":                {1, 1},
+		"Rules[0]":                   {6, 1},
+		"Rules[0].FilePattern":       {7, 3},
+		"Rules[1].FilePattern":       {10, 3},
+		"Frames.TXXX:Narrator.Value": {13, 1},
+	}
+	for path, pos := range expected {
+		if positions[path] != pos {
+			t.Errorf("expected %s at %v, got %v", path, pos, positions[path])
+		}
+	}
+	if _, ok := positions["Information"]; ok {
+		t.Errorf("expected the contents of a multi-line string to be skipped")
+	}
+}
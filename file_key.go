@@ -0,0 +1,73 @@
+package tagger
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// FileKey is a value of a matched file that files are sorted by and data sources are joined on.
+// It is "path", "filename", a captured variable written the way it is in the FilePattern, e.g. "$part$" or "%author%",
+// or a value of the existing tag, e.g. "tag.TPOS" or "tag.TXXX.Narrator".
+type FileKey string
+
+const (
+	PathKey     FileKey = "path"
+	FilenameKey FileKey = "filename"
+)
+
+// Validate makes sure the key can be used with the file patterns that capture the names.
+func (k FileKey) Validate(captures []string) error {
+	if k == PathKey || k == FilenameKey || strings.HasPrefix(string(k), "tag.") {
+		return nil
+	}
+	name, ok := k.capture()
+	if !ok {
+		return errors.Errorf(`key %q must be "path", "filename", a captured variable like "$part$" or a tag value like "tag.TPOS"`, k)
+	}
+	for _, c := range captures {
+		if c == name {
+			return nil
+		}
+	}
+	return errors.Errorf("key %q is not captured by any file pattern", k)
+}
+
+// capture returns the name of the captured variable the key refers to.
+func (k FileKey) capture() (string, bool) {
+	s := string(k)
+	if len(s) < 3 {
+		return "", false
+	}
+	if (s[0] == '$' && s[len(s)-1] == '$') || (s[0] == '%' && s[len(s)-1] == '%') {
+		return s[1 : len(s)-1], true
+	}
+	return "", false
+}
+
+// value returns the value of the key for a matched file.
+func (k FileKey) value(m *match) string {
+	switch k {
+	case PathKey:
+		return filepath.ToSlash(m.path)
+	case FilenameKey:
+		return filepath.Base(m.path)
+	}
+	if name, ok := k.capture(); ok {
+		return fmt.Sprint(m.captures[name])
+	}
+	var v any = m.tagData()
+	for _, part := range strings.Split(strings.TrimPrefix(string(k), "tag."), ".") {
+		data, ok := v.(map[string]any)
+		if !ok {
+			return ""
+		}
+		v = data[part]
+	}
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,19 @@
+package tagger
+
+import (
+	"testing"
+)
+
+func TestFileKeyValidate(t *testing.T) {
+	captures := []string{"", "disk", "part"}
+	for _, key := range []FileKey{"path", "filename", "$disk$", "%part%", "tag.TPOS", "tag.TXXX.Narrator"} {
+		if err := key.Validate(captures); err != nil {
+			t.Errorf("expected %q to be valid: %v", key, err)
+		}
+	}
+	for _, key := range []FileKey{"disk", "$track$", "$", ""} {
+		if err := key.Validate(captures); err == nil {
+			t.Errorf("expected %q to be invalid", key)
+		}
+	}
+}
@@ -2,6 +2,10 @@ module github.com/chuckha/tagger
 
 go 1.21.3
 
-require gitlab.com/tozd/go/errors v0.8.0
+require (
+	github.com/BurntSushi/toml v1.3.2
+	gitlab.com/tozd/go/errors v0.8.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require github.com/pkg/errors v0.9.1 // indirect
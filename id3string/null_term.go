@@ -7,26 +7,42 @@ import (
 	"unicode/utf16"
 )
 
-func ExtractValueWithEncoding(enc byte, data []byte) ([]rune, int) {
+// UnknownEncodingError is a text encoding byte that is neither ISO-8859-1 (0) nor Unicode (1), the only encodings of id3v2.3.
+type UnknownEncodingError struct {
+	Encoding byte
+}
+
+func (e *UnknownEncodingError) Error() string {
+	return fmt.Sprintf("unknown text encoding %d", e.Encoding)
+}
+
+// ExtractValueWithEncoding decodes all of the data. It also returns the number of bytes consumed.
+func ExtractValueWithEncoding(enc byte, data []byte) ([]rune, int, error) {
 	switch enc {
 	case 0:
-		return []rune(string(data)), 0
+		return []rune(string(data)), len(data), nil
 	case 1:
-		return ExtractUnicode(data), 2 // consume 2 BOM bytes
+		return ExtractUnicode(data), len(data), nil
 	default:
-		panic(fmt.Sprintf("unhandled text encoding: %08b", enc))
+		return nil, 0, &UnknownEncodingError{Encoding: enc}
 	}
 }
 
-func ExtractNullTerminatedValueWithEncoding(enc byte, data []byte) ([]rune, int) {
+// ExtractNullTerminatedValueWithEncoding decodes a null terminated value.
+// It also returns the number of bytes consumed which includes the BOM and the null terminator.
+func ExtractNullTerminatedValueWithEncoding(enc byte, data []byte) ([]rune, int, error) {
 	switch enc {
 	case 0:
-		return ExtractNullTerminated(data), 1
+		n := bytes.IndexByte(data, 0)
+		if n == -1 {
+			return ExtractNullTerminated(data), len(data), nil
+		}
+		return ExtractNullTerminated(data), n + 1, nil
 	case 1:
-		// 4 are the BOM and the unicode null terminator
-		return ExtractUnicodeNullTerminated(data), 4
+		runes, n := ExtractUnicodeNullTerminated(data)
+		return runes, n, nil
 	default:
-		panic(fmt.Sprintf("unhandled text encoding: %08b", enc))
+		return nil, 0, &UnknownEncodingError{Encoding: enc}
 	}
 }
 
@@ -47,35 +63,49 @@ func ExtractNullTerminated(b []byte) []rune {
 	return []rune(string(b[:n]))
 }
 
-// ExtractNullTerminatedUnicode gets the two BOM bytes, looks for a unicode null, then extracts the middle.
-func ExtractUnicodeNullTerminated(b []byte) []rune {
-	// TODO: use the bom to determine if it's big or little endian; for now assume big endian
-	_ = b[0:2]
-	n := bytes.Index(b[2:], []byte{0, 0})
-	if n == -1 {
-		return []rune(string(b))
+// ExtractUnicodeNullTerminated reads the BOM, looks for a unicode null, then extracts the middle.
+// It also returns the number of bytes consumed including the BOM and the unicode null.
+func ExtractUnicodeNullTerminated(b []byte) ([]rune, int) {
+	order, body := byteOrder(b)
+	bom := len(b) - len(body)
+	// the unicode null must start on a character boundary
+	for i := 0; i+1 < len(body); i += 2 {
+		if body[i] == 0 && body[i+1] == 0 {
+			return bytesToRunes(order, body[:i]), bom + i + 2
+		}
 	}
-	return bytesToRunes(b[2:n])
+	return bytesToRunes(order, body), len(b)
 }
 
 func ExtractUnicode(b []byte) []rune {
-	// TODO: use the bom to determine if it's big or little endian; for now assume big endian
-	_ = b[0:2]
-	return bytesToRunes(b[2:])
+	order, body := byteOrder(b)
+	return bytesToRunes(order, body)
+}
+
+// byteOrder reads the BOM and returns the rest of the bytes.
+// Without a BOM the bytes are assumed to be big endian.
+func byteOrder(b []byte) (binary.ByteOrder, []byte) {
+	if len(b) >= 2 {
+		switch {
+		case b[0] == 0xFF && b[1] == 0xFE:
+			return binary.LittleEndian, b[2:]
+		case b[0] == 0xFE && b[1] == 0xFF:
+			return binary.BigEndian, b[2:]
+		}
+	}
+	return binary.BigEndian, b
 }
 
-func bytesToRunes(b []byte) []rune {
-	// Check if byte slice length is even
+func bytesToRunes(order binary.ByteOrder, b []byte) []rune {
+	// a trailing odd byte cannot be part of a UTF-16 character so it is dropped
 	if len(b)%2 != 0 {
-		panic("byte slice has odd length, cannot be valid UTF-16")
+		b = b[:len(b)-1]
 	}
 
 	// Convert []byte to []uint16
 	uints := make([]uint16, 0, len(b)/2)
 	for i := 0; i < len(b); i += 2 {
-		// For big endian, use binary.BigEndian.Uint16
-		// For little endian, use binary.LittleEndian.Uint16
-		uints = append(uints, binary.BigEndian.Uint16(b[i:i+2]))
+		uints = append(uints, order.Uint16(b[i:i+2]))
 	}
 
 	// Decode []uint16 to []rune
@@ -0,0 +1,42 @@
+package id3string
+
+import "testing"
+
+func TestExtractNullTerminatedValueWithEncoding(t *testing.T) {
+	testcases := []struct {
+		name     string
+		enc      byte
+		input    []byte
+		expected string
+		consumed int
+	}{
+		{name: "ascii", enc: 0, input: []byte("abc\x00rest"), expected: "abc", consumed: 4},
+		{name: "ascii without terminator", enc: 0, input: []byte("abc"), expected: "abc", consumed: 3},
+		{name: "big endian", enc: 1, input: []byte{0xFE, 0xFF, 0x00, 'a', 0x01, 0x00, 0x00, 0x00, 'x'}, expected: "aĀ", consumed: 8},
+		{name: "little endian", enc: 1, input: []byte{0xFF, 0xFE, 'a', 0x00, 0x00, 0x01, 0x00, 0x00, 'x'}, expected: "aĀ", consumed: 8},
+		{name: "empty without a BOM", enc: 1, input: []byte{0x00, 0x00, 'x'}, expected: "", consumed: 2},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n, err := ExtractNullTerminatedValueWithEncoding(tt.enc, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, string(got))
+			}
+			if n != tt.consumed {
+				t.Fatalf("expected %d bytes consumed, got %d", tt.consumed, n)
+			}
+		})
+	}
+}
+
+func TestExtractUnknownEncoding(t *testing.T) {
+	if _, _, err := ExtractValueWithEncoding(3, []byte("abc")); err == nil {
+		t.Fatal("expected an error for encoding 3")
+	}
+	if _, _, err := ExtractNullTerminatedValueWithEncoding(3, []byte("abc\x00")); err == nil {
+		t.Fatal("expected an error for encoding 3")
+	}
+}
@@ -1,8 +1,11 @@
 package frames
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"strings"
 
@@ -25,7 +28,11 @@ type AttachedPicture struct {
 // This means that the mp3 is missing a 0x00 after the MIME type and the description is omitted entirely.
 
 func (a *AttachedPicture) UnmarshalBinary(data []byte) error {
-	a.TextEncoding = data[0]
+	enc, err := readEncoding(data, 1)
+	if err != nil {
+		return err
+	}
+	a.TextEncoding = enc
 	ptr := 1
 
 	a.MIMEType = id3string.ExtractNullTerminatedASCII(data[ptr:])
@@ -40,17 +47,26 @@ func (a *AttachedPicture) UnmarshalBinary(data []byte) error {
 				break
 			}
 		}
+		if ptr >= len(data) {
+			return errors.New("picture type is cut off by the end of the frame")
+		}
 		a.PictureType = data[ptr]
 		ptr++
 		a.Description = []rune{}
 	} else {
 		// otherwise we have a normal layout
 		ptr += len(a.MIMEType) + 1
+		if ptr >= len(data) {
+			return errors.New("picture type is cut off by the end of the frame")
+		}
 		a.PictureType = data[ptr]
 		ptr++
-		desc, n := id3string.ExtractNullTerminatedValueWithEncoding(a.TextEncoding, data[ptr:])
+		desc, n, err := id3string.ExtractNullTerminatedValueWithEncoding(a.TextEncoding, data[ptr:])
+		if err != nil {
+			return errors.WithStack(err)
+		}
 		a.Description = desc
-		ptr += len(a.Description) + n
+		ptr += n
 	}
 	a.PictureData = data[ptr:]
 	return nil
@@ -62,43 +78,64 @@ func (a *AttachedPicture) UnmarshalJSON(data []byte) error {
 		MIMEType    string
 		PictureType string
 		Description string
-		Data        string
+		Data        dataValue
 	}
-	// but actually here read in a file reference and use that as the picture data
 	if err := json.Unmarshal(data, &in); err != nil {
 		return errors.WithStack(err)
 	}
-	a.MIMEType = in.MIMEType
-	a.PictureType = invertedPictureTypes()[in.PictureType]
+	pictureType, ok := invertedPictureTypes()[in.PictureType]
+	if !ok && in.PictureType != "" {
+		return errors.Errorf("unknown picture type %q", in.PictureType)
+	}
+	a.PictureType = pictureType
 
-	b, err := os.ReadFile(strings.TrimLeft(in.Data, "@"))
-	if err != nil {
-		return errors.WithStack(err)
+	// unlike other data a plain string is the path to the picture, optionally prefixed with @
+	b := in.Data.Bytes
+	if in.Data.File == "" {
+		var err error
+		if b, err = os.ReadFile(strings.TrimPrefix(string(b), "@")); err != nil {
+			return errors.WithStack(err)
+		}
 	}
 	a.PictureData = b
-	if id3string.IsASCIIBytes([]byte(in.Description)) {
-		a.Description = []rune(in.Description)
-		return nil
+	a.MIMEType = in.MIMEType
+	if a.MIMEType == "" {
+		a.MIMEType = http.DetectContentType(b)
 	}
 	a.Description = id3string.DecodeUTF8(in.Description)
+	a.TextEncoding = textEncoding(a.Description)
 	return nil
 }
 
+// Reader reads the picture.
+func (a *AttachedPicture) Reader() io.Reader {
+	return bytes.NewReader(a.PictureData)
+}
+
+// ExtractPicture writes the picture to test.jpg in the working directory.
+// An existing file is never overwritten; the name is numbered instead, e.g. "test (2).jpg".
+//
+// Deprecated: name the picture with FileName and write it with WriteNewFile, or use the extract command.
 func (a *AttachedPicture) ExtractPicture() {
-	f, err := os.Create("test.jpg")
-	if err != nil {
+	if _, err := WriteNewFile(".", "test.jpg", a.Reader()); err != nil {
 		panic(err)
 	}
-	if _, err := f.Write(a.PictureData); err != nil {
-		panic(err)
+}
+
+// FileName names the picture by its type and description, with the extension of its MIME type or its contents.
+func (a *AttachedPicture) FileName() string {
+	name := PictureTypes[a.PictureType]
+	if name == "" {
+		name = "Picture"
 	}
-	if err := f.Close(); err != nil {
-		panic(err)
+	if len(a.Description) > 0 {
+		name += " - " + string(a.Description)
 	}
+	return SafeFileName(name) + Extension(a.MIMEType, a.PictureData)
 }
 
 func (a *AttachedPicture) String() string {
-	return fmt.Sprintf("enc: %x; mime: %q; type: %q; desc: %q", a.TextEncoding, a.MIMEType, PictureTypes[a.PictureType], a.Description)
+	return fmt.Sprintf("enc: %x; mime: %q; type: %q; desc: %q", a.TextEncoding, a.MIMEType, PictureTypes[a.PictureType], string(a.Description))
 }
 
 func (a *AttachedPicture) MarshalBinary() ([]byte, error) {
@@ -1,6 +1,10 @@
 package frames
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestAttachedPictureEncoding(t *testing.T) {
 	t.Run("marshal is inverse of unmarshal", func(t *testing.T) {
@@ -91,3 +95,91 @@ func TestAttachedPictureUnmarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestAttachedPicture_UnmarshalJSON(t *testing.T) {
+	picture := filepath.Join(t.TempDir(), "cover")
+	png := []byte("\x89PNG\x0D\x0A\x1A\x0A rest of the picture")
+	if err := os.WriteFile(picture, png, 0644); err != nil {
+		t.Fatal(err)
+	}
+	testcases := []struct {
+		name  string
+		data  string
+		fails bool
+	}{
+		{name: "path", data: `"` + picture + `"`},
+		{name: "path with an @", data: `"@` + picture + `"`},
+		{name: "file", data: `{"File": "` + picture + `"}`},
+		// only the first @ is dropped, so this is the path "@/tmp/.../cover"
+		{name: "path with two @", data: `"@@` + picture + `"`, fails: true},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			ap := &AttachedPicture{}
+			err := ap.UnmarshalJSON([]byte(`{"Data": ` + tt.data + `}`))
+			if tt.fails {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ap.MIMEType != "image/png" {
+				t.Fatalf("expected the MIME type to be detected from the picture, got %q", ap.MIMEType)
+			}
+		})
+	}
+}
+
+func TestAttachedPicture_ExtractPicture(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if err := os.WriteFile("test.jpg", []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ap := &AttachedPicture{MIMEType: "image/jpeg", PictureData: []byte("\xff\xd8\xff\xe0")}
+	ap.ExtractPicture()
+	b, err := os.ReadFile(filepath.Join(dir, "test (2).jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(ap.PictureData) {
+		t.Fatalf("expected the picture, got %q", b)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "test.jpg")); err != nil || string(b) != "existing" {
+		t.Fatalf("expected the existing file to be kept, got %q: %v", b, err)
+	}
+}
+
+func TestAttachedPicture_FileName(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    *AttachedPicture
+		expected string
+	}{
+		{name: "type and MIME type", input: &AttachedPicture{MIMEType: "image/jpeg", PictureType: 3}, expected: "Cover (front).jpg"},
+		{name: "description", input: &AttachedPicture{MIMEType: "image/png", PictureType: 4, Description: []rune("scan")}, expected: "Cover (back) - scan.png"},
+		{name: "unsafe type", input: &AttachedPicture{MIMEType: "image/png", PictureType: 0x0A}, expected: "Band_Orchestra.png"},
+		{name: "sniffed", input: &AttachedPicture{MIMEType: "-->", PictureData: []byte("\xff\xd8\xff\xe0")}, expected: "Other.jpg"},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.input.FileName(); got != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -19,28 +19,46 @@ type Comment struct {
 }
 
 func (c *Comment) UnmarshalBinary(data []byte) error {
+	enc, err := readEncoding(data, 4)
+	if err != nil {
+		return err
+	}
 	ptr := 0
-	c.TextEncoding = data[0]
+	c.TextEncoding = enc
 	ptr++
 	c.Language = string(data[1:4])
 	ptr += 3
-	desc, n := id3string.ExtractNullTerminatedValueWithEncoding(c.TextEncoding, data[ptr:])
+	desc, n, err := id3string.ExtractNullTerminatedValueWithEncoding(c.TextEncoding, data[ptr:])
+	if err != nil {
+		return errors.WithStack(err)
+	}
 	c.ShortContentDescription = desc
-	ptr += len(desc) + n
-	at, n := id3string.ExtractNullTerminatedValueWithEncoding(c.TextEncoding, data[ptr:])
-	c.ActualText = at
 	ptr += n
-	// TODO: check length maybe?
+	at, _, err := id3string.ExtractNullTerminatedValueWithEncoding(c.TextEncoding, data[ptr:])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c.ActualText = at
 	return nil
 }
 
 func (c *Comment) UnmarshalJSON(data []byte) error {
-	if err := json.Unmarshal(data, c); err != nil {
+	var in struct {
+		Language                string
+		ShortContentDescription string
+		ActualText              string
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
 		return errors.WithStack(err)
 	}
-	if !id3string.IsASCII(c.ShortContentDescription) || !id3string.IsASCII(c.ActualText) {
-		c.TextEncoding = 1
+	lang, err := language(in.Language)
+	if err != nil {
+		return err
 	}
+	c.Language = lang
+	c.ShortContentDescription = []rune(in.ShortContentDescription)
+	c.ActualText = []rune(in.ActualText)
+	c.TextEncoding = textEncoding(c.ShortContentDescription, c.ActualText)
 	return nil
 }
 
@@ -53,7 +71,7 @@ func (c *Comment) MarshalBinary() ([]byte, error) {
 }
 
 func (c *Comment) String() string {
-	return fmt.Sprintf("enc: %x; lang: %q; short: %q; text: %q", c.TextEncoding, c.Language, c.ShortContentDescription, c.ActualText)
+	return fmt.Sprintf("enc: %x; lang: %q; short: %q; text: %q", c.TextEncoding, c.Language, string(c.ShortContentDescription), string(c.ActualText))
 }
 
 func (c *Comment) Equal(c2 *Comment) bool {
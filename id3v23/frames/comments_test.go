@@ -17,6 +17,15 @@ func TestCommentsEncoding(t *testing.T) {
 					ActualText:              []rune("actual"),
 				},
 			},
+			{
+				name: "unicode comment",
+				input: &Comment{
+					TextEncoding:            1,
+					Language:                "jpn",
+					ShortContentDescription: []rune("説明"),
+					ActualText:              []rune("本文"),
+				},
+			},
 		}
 
 		for _, tt := range testcases {
@@ -35,4 +44,18 @@ func TestCommentsEncoding(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("UnmarshalJSON", func(t *testing.T) {
+		c := &Comment{}
+		if err := c.UnmarshalJSON([]byte(`{"ShortContentDescription":"note","ActualText":"しろくま"}`)); err != nil {
+			t.Fatal(err)
+		}
+		expected := &Comment{TextEncoding: 1, Language: "eng", ShortContentDescription: []rune("note"), ActualText: []rune("しろくま")}
+		if !c.Equal(expected) {
+			t.Fatalf("\nexpected: %v\n     got: %v", expected, c)
+		}
+		if err := c.UnmarshalJSON([]byte(`{"Language":"english"}`)); err == nil {
+			t.Fatal("expected an error for a language that is not three letters")
+		}
+	})
 }
@@ -0,0 +1,80 @@
+package frames
+
+import (
+	"strings"
+)
+
+// Decode returns the fields of a frame body as plain values, keyed like the JSON form of the body.
+// Binary data is reported by its size rather than its bytes.
+func Decode(id string, body FrameBody) map[string]any {
+	switch b := body.(type) {
+	case *TextInformation:
+		out := map[string]any{
+			"TextEncoding": EncodingName(b.TextEncoding),
+			"Information":  strings.TrimRight(string(b.Information), NullSeparator),
+			"Values":       b.Values(id),
+		}
+		if id == "TCON" {
+			out["Genres"] = ParseContentType(string(b.Information)).Names()
+		}
+		return out
+	case *Comment:
+		return map[string]any{
+			"TextEncoding":            EncodingName(b.TextEncoding),
+			"Language":                b.Language,
+			"ShortContentDescription": string(b.ShortContentDescription),
+			"ActualText":              string(b.ActualText),
+		}
+	case *AttachedPicture:
+		return map[string]any{
+			"TextEncoding": EncodingName(b.TextEncoding),
+			"MIMEType":     b.MIMEType,
+			"PictureType":  PictureTypes[b.PictureType],
+			"Description":  string(b.Description),
+			"Size":         len(b.PictureData),
+		}
+	case *UserDefinedURL:
+		return map[string]any{
+			"TextEncoding": EncodingName(b.TextEncoding),
+			"Description":  string(b.Description),
+			"URL":          b.URL,
+		}
+	case *PrivateData:
+		return map[string]any{
+			"OwnerIdentifier": b.OwnerIdentifier,
+			"Size":            len(b.Data),
+		}
+	case *UnsynchronizedLyrics:
+		return map[string]any{
+			"TextEncoding":      EncodingName(b.TextEncoding),
+			"Language":          b.Language,
+			"ContentDescriptor": string(b.ContentDescriptor),
+			"Lyrics":            b.Lyrics,
+		}
+	case *UserDefinedTextInformation:
+		return map[string]any{
+			"TextEncoding": EncodingName(b.TextEncoding),
+			"Description":  string(b.Description),
+			"Value":        string(b.Value),
+		}
+	case *MusicCDIdentifier:
+		return map[string]any{"Size": len(b.TableOfContents)}
+	case *GeneralEncapsulationObject:
+		return map[string]any{
+			"TextEncoding":       EncodingName(b.TextEncoding),
+			"MIMEType":           b.MIMEType,
+			"Filename":           string(b.Filename),
+			"ContentDescription": string(b.ContentDescription),
+			"Size":               len(b.EncapsulatedObject),
+		}
+	case *TermsOfUse:
+		return map[string]any{
+			"TextEncoding": EncodingName(b.TextEncoding),
+			"Language":     b.Language,
+			"Text":         b.Text,
+		}
+	case *Unknown:
+		return map[string]any{"Size": len(b.Data)}
+	}
+	return map[string]any{}
+}
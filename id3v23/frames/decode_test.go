@@ -0,0 +1,71 @@
+package frames
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	testcases := []struct {
+		name     string
+		id       string
+		body     FrameBody
+		expected map[string]any
+	}{
+		{
+			name: "text values",
+			id:   "TLAN",
+			body: NewTextInformationValues("TLAN", "eng", "jpn"),
+			expected: map[string]any{
+				"TextEncoding": "ISO-8859-1",
+				"Information":  "eng\x00jpn",
+				"Values":       []string{"eng", "jpn"},
+			},
+		},
+		{
+			name: "genres",
+			id:   "TCON",
+			body: NewTextInformation("(183)Audiobook"),
+			expected: map[string]any{
+				"TextEncoding": "ISO-8859-1",
+				"Information":  "(183)Audiobook",
+				"Values":       []string{"(183)Audiobook"},
+				"Genres":       []string{"Audiobook"},
+			},
+		},
+		{
+			name: "picture without its bytes",
+			id:   "APIC",
+			body: &AttachedPicture{TextEncoding: 1, MIMEType: "image/png", PictureType: 3, Description: []rune("cover"), PictureData: []byte{1, 2, 3}},
+			expected: map[string]any{
+				"TextEncoding": "UTF-16",
+				"MIMEType":     "image/png",
+				"PictureType":  "Cover (front)",
+				"Description":  "cover",
+				"Size":         3,
+			},
+		},
+		{
+			name:     "unknown",
+			id:       "RVAD",
+			body:     &Unknown{Data: []byte{1, 2}},
+			expected: map[string]any{"Size": 2},
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Decode(tt.id, tt.body)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Fatalf("\nexpected: %v\n     got: %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFlagNames(t *testing.T) {
+	header := &FrameHeader{ID: "TIT2", ReadOnly: true, Encrypted: true}
+	expected := []string{"ReadOnly", "Encrypted"}
+	if got := header.FlagNames(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
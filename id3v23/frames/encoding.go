@@ -0,0 +1,102 @@
+package frames
+
+import (
+	"fmt"
+
+	"github.com/chuckha/tagger/id3string"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// Encodings are the names of the id3v2.3 text encodings, indexed by their byte.
+var Encodings = []string{"ISO-8859-1", "UTF-16"}
+
+// EncodingName names an ID3v2.3 text encoding byte.
+func EncodingName(enc byte) string {
+	if int(enc) < len(Encodings) {
+		return Encodings[enc]
+	}
+	return fmt.Sprintf("unknown (%d)", enc)
+}
+
+// ParseEncoding is the inverse of EncodingName.
+func ParseEncoding(name string) (byte, error) {
+	for i, encoding := range Encodings {
+		if encoding == name {
+			return byte(i), nil
+		}
+	}
+	return 0, errors.Errorf("unknown text encoding %q; expected one of %q", name, Encodings)
+}
+
+// readEncoding reads the text encoding at the start of a frame body that must be at least size bytes long.
+func readEncoding(data []byte, size int) (byte, error) {
+	if len(data) < size {
+		return 0, errors.Errorf("frame body of %d bytes is shorter than the %d bytes it needs", len(data), size)
+	}
+	if int(data[0]) >= len(Encodings) {
+		return 0, errors.WithStack(&id3string.UnknownEncodingError{Encoding: data[0]})
+	}
+	return data[0], nil
+}
+
+// encodedText returns the text encoding of the body and every text it encodes.
+// ok is false for bodies without a text encoding.
+func encodedText(body FrameBody) (enc byte, texts [][]rune, ok bool) {
+	switch b := body.(type) {
+	case *TextInformation:
+		return b.TextEncoding, [][]rune{b.Information}, true
+	case *Comment:
+		return b.TextEncoding, [][]rune{b.ShortContentDescription, b.ActualText}, true
+	case *AttachedPicture:
+		return b.TextEncoding, [][]rune{b.Description}, true
+	case *UserDefinedURL:
+		return b.TextEncoding, [][]rune{b.Description}, true
+	case *UnsynchronizedLyrics:
+		return b.TextEncoding, [][]rune{b.ContentDescriptor, []rune(b.Lyrics)}, true
+	case *UserDefinedTextInformation:
+		return b.TextEncoding, [][]rune{b.Description, b.Value}, true
+	case *GeneralEncapsulationObject:
+		return b.TextEncoding, [][]rune{b.Filename, b.ContentDescription}, true
+	case *TermsOfUse:
+		return b.TextEncoding, [][]rune{[]rune(b.Text)}, true
+	}
+	return 0, nil, false
+}
+
+// SetEncoding overrides the text encoding that was picked for the body.
+// ISO-8859-1 is only allowed for ascii text.
+func SetEncoding(body FrameBody, enc byte) error {
+	_, texts, ok := encodedText(body)
+	if !ok {
+		return errors.Errorf("%T has no text encoding", body)
+	}
+	if enc == 0 && textEncoding(texts...) != 0 {
+		return errors.Errorf("text that is not ascii cannot be encoded as %s", Encodings[0])
+	}
+	switch b := body.(type) {
+	case *TextInformation:
+		b.TextEncoding = enc
+	case *Comment:
+		b.TextEncoding = enc
+	case *AttachedPicture:
+		b.TextEncoding = enc
+	case *UserDefinedURL:
+		b.TextEncoding = enc
+	case *UnsynchronizedLyrics:
+		b.TextEncoding = enc
+	case *UserDefinedTextInformation:
+		b.TextEncoding = enc
+	case *GeneralEncapsulationObject:
+		b.TextEncoding = enc
+	case *TermsOfUse:
+		b.TextEncoding = enc
+	}
+	return nil
+}
+
+// isDefaultEncoding reports if the body uses the encoding its JSON form picks on its own.
+func isDefaultEncoding(body FrameBody) bool {
+	enc, texts, ok := encodedText(body)
+	return !ok || enc == textEncoding(texts...)
+}
@@ -0,0 +1,28 @@
+package frames
+
+import "testing"
+
+func TestEncodings(t *testing.T) {
+	for i, name := range Encodings {
+		enc, err := ParseEncoding(name)
+		if err != nil || enc != byte(i) || EncodingName(enc) != name {
+			t.Fatalf("expected %q to be encoding %d, got %d: %v", name, i, enc, err)
+		}
+	}
+	if _, err := ParseEncoding("UTF-8"); err == nil {
+		t.Fatal("expected UTF-8 to be rejected; id3v2.3 does not support it")
+	}
+
+	t.Run("set encoding", func(t *testing.T) {
+		body := NewTextInformation("ascii")
+		if err := SetEncoding(body, 1); err != nil || body.TextEncoding != 1 {
+			t.Fatalf("expected ascii text to be encoded as UTF-16, got %d: %v", body.TextEncoding, err)
+		}
+		if err := SetEncoding(NewTextInformation("日本語"), 0); err == nil {
+			t.Fatal("expected text that is not ascii to be rejected as ISO-8859-1")
+		}
+		if err := SetEncoding(&PrivateData{}, 1); err == nil {
+			t.Fatal("expected a frame without text to be rejected")
+		}
+	})
+}
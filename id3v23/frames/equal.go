@@ -0,0 +1,47 @@
+package frames
+
+import "bytes"
+
+// BodiesEqual reports if two frame bodies hold the same values, using the Equal method of the body type.
+// Bodies of different types are never equal.
+func BodiesEqual(a, b FrameBody) bool {
+	switch a := a.(type) {
+	case *TextInformation:
+		b, ok := b.(*TextInformation)
+		return ok && a.Equal(b)
+	case *Comment:
+		b, ok := b.(*Comment)
+		return ok && a.Equal(b)
+	case *AttachedPicture:
+		b, ok := b.(*AttachedPicture)
+		return ok && a.Equal(b)
+	case *UserDefinedURL:
+		b, ok := b.(*UserDefinedURL)
+		return ok && a.Equal(b)
+	case *PrivateData:
+		b, ok := b.(*PrivateData)
+		return ok && a.Equal(b)
+	case *UnsynchronizedLyrics:
+		b, ok := b.(*UnsynchronizedLyrics)
+		return ok && a.Equal(b)
+	case *UserDefinedTextInformation:
+		b, ok := b.(*UserDefinedTextInformation)
+		return ok && a.Equal(b)
+	case *MusicCDIdentifier:
+		b, ok := b.(*MusicCDIdentifier)
+		return ok && a.Equal(b)
+	case *GeneralEncapsulationObject:
+		b, ok := b.(*GeneralEncapsulationObject)
+		return ok && a.Equal(b)
+	case *TermsOfUse:
+		b, ok := b.(*TermsOfUse)
+		return ok && a.Equal(b)
+	case *Unknown:
+		b, ok := b.(*Unknown)
+		return ok && a.Equal(b)
+	}
+	// a body type without an Equal method
+	ab, aerr := a.MarshalBinary()
+	bb, berr := b.MarshalBinary()
+	return aerr == nil && berr == nil && bytes.Equal(ab, bb)
+}
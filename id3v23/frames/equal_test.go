@@ -0,0 +1,25 @@
+package frames
+
+import "testing"
+
+func TestBodiesEqual(t *testing.T) {
+	testcases := []struct {
+		name     string
+		a, b     FrameBody
+		expected bool
+	}{
+		{name: "same text", a: NewTextInformation("a"), b: NewTextInformation("a"), expected: true},
+		{name: "different text", a: NewTextInformation("a"), b: NewTextInformation("b")},
+		{name: "different encoding", a: &TextInformation{TextEncoding: 0, Information: []rune("a")}, b: &TextInformation{TextEncoding: 1, Information: []rune("a")}},
+		{name: "different types", a: NewTextInformation("a"), b: &UserDefinedTextInformation{Value: []rune("a")}},
+		{name: "same private data", a: &PrivateData{OwnerIdentifier: "x", Data: []byte{1}}, b: &PrivateData{OwnerIdentifier: "x", Data: []byte{1}}, expected: true},
+		{name: "same unknown", a: &Unknown{Data: []byte{1}}, b: &Unknown{Data: []byte{1}}, expected: true},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BodiesEqual(tt.a, tt.b); got != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
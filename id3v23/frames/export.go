@@ -0,0 +1,69 @@
+package frames
+
+import (
+	"gitlab.com/tozd/go/errors"
+)
+
+// DataWriter stores binary data of a field somewhere and returns the path it can be read back from.
+type DataWriter func(field string, data []byte) (string, error)
+
+// Export returns the JSON form of a body, the inverse of its UnmarshalJSON.
+// Binary data is handed to write and referenced by {"File": path}.
+// The TextEncoding is only included when it is not the one UnmarshalJSON would pick.
+func Export(body FrameBody, write DataWriter) (map[string]any, error) {
+	out := map[string]any{}
+	data := func(field string, b []byte) error {
+		path, err := write(field, b)
+		if err != nil {
+			return err
+		}
+		out[field] = map[string]string{"File": path}
+		return nil
+	}
+	var err error
+	switch b := body.(type) {
+	case *TextInformation:
+		out["Information"] = string(b.Information)
+	case *Comment:
+		out["Language"] = b.Language
+		out["ShortContentDescription"] = string(b.ShortContentDescription)
+		out["ActualText"] = string(b.ActualText)
+	case *AttachedPicture:
+		out["MIMEType"] = b.MIMEType
+		out["PictureType"] = PictureTypes[b.PictureType]
+		out["Description"] = string(b.Description)
+		err = data("Data", b.PictureData)
+	case *UserDefinedURL:
+		out["Description"] = string(b.Description)
+		out["URL"] = b.URL
+	case *PrivateData:
+		out["OwnerIdentifier"] = b.OwnerIdentifier
+		err = data("Data", b.Data)
+	case *UnsynchronizedLyrics:
+		out["Language"] = b.Language
+		out["ContentDescriptor"] = string(b.ContentDescriptor)
+		out["Lyrics"] = b.Lyrics
+	case *UserDefinedTextInformation:
+		out["Description"] = string(b.Description)
+		out["Value"] = string(b.Value)
+	case *MusicCDIdentifier:
+		err = data("TableOfContents", b.TableOfContents)
+	case *GeneralEncapsulationObject:
+		out["MIMEType"] = b.MIMEType
+		out["Filename"] = string(b.Filename)
+		out["ContentDescription"] = string(b.ContentDescription)
+		err = data("EncapsulatedObject", b.EncapsulatedObject)
+	case *TermsOfUse:
+		out["Language"] = b.Language
+		out["Text"] = b.Text
+	default:
+		return nil, errors.Errorf("%T cannot be exported", body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enc, _, _ := encodedText(body); !isDefaultEncoding(body) && SetEncoding(body, enc) == nil {
+		out["TextEncoding"] = EncodingName(enc)
+	}
+	return out, nil
+}
@@ -0,0 +1,67 @@
+package frames
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	write := func(field string, data []byte) (string, error) {
+		path := filepath.Join(dir, field)
+		return path, os.WriteFile(path, data, 0644)
+	}
+	testcases := []struct {
+		id   string
+		body FrameBody
+	}{
+		{id: "TIT2", body: NewTextInformation("title")},
+		{id: "TIT2", body: &TextInformation{TextEncoding: 1, Information: []rune("ascii in UTF-16")}},
+		{id: "TPE1", body: NewTextInformation("日本語")},
+		{id: "COMM", body: &Comment{Language: "deu", ShortContentDescription: []rune("desc"), ActualText: []rune("text")}},
+		{id: "APIC", body: &AttachedPicture{MIMEType: "image/png", PictureType: 3, Description: []rune{}, PictureData: []byte{0, 1, 2}}},
+		{id: "WXXX", body: &UserDefinedURL{TextEncoding: 1, Description: []rune("home"), URL: "https://example.com"}},
+		{id: "PRIV", body: &PrivateData{OwnerIdentifier: "owner", Data: []byte{0, 1}}},
+		{id: "USLT", body: &UnsynchronizedLyrics{Language: "eng", ContentDescriptor: []rune{}, Lyrics: "@not a file"}},
+		{id: "TXXX", body: &UserDefinedTextInformation{Description: []rune("Narrator"), Value: []rune("Stephen Fry")}},
+		{id: "MCDI", body: &MusicCDIdentifier{TableOfContents: []byte{9, 9}}},
+		{id: "GEOB", body: &GeneralEncapsulationObject{MIMEType: "text/plain", Filename: []rune("a.txt"), ContentDescription: []rune("object"), EncapsulatedObject: []byte("data")}},
+		{id: "USER", body: &TermsOfUse{Language: "eng", Text: "terms"}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.id, func(t *testing.T) {
+			out, err := Export(tt.body, write)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := json.Marshal(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewFrameBody(tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := got.UnmarshalJSON(b); err != nil {
+				t.Fatal(err)
+			}
+			if name, ok := out["TextEncoding"].(string); ok {
+				enc, err := ParseEncoding(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := SetEncoding(got, enc); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !BodiesEqual(tt.body, got) {
+				t.Fatalf("\nexpected: %v\n     got: %v\n    json: %s", tt.body, got, b)
+			}
+		})
+	}
+	if _, err := Export(&Unknown{}, write); err == nil {
+		t.Fatal("expected unsupported frames not to be exported")
+	}
+}
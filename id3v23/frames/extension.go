@@ -0,0 +1,67 @@
+package frames
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// MIMETypeExtensions are the file extensions of the MIME types found in tags.
+var MIMETypeExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/jpg":  ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/bmp":  ".bmp",
+	"image/webp": ".webp",
+	"text/plain": ".txt",
+}
+
+// Extension returns the file extension for data of the MIME type.
+// When the MIME type is not known the data is sniffed; data that is still not recognized gets .bin.
+func Extension(mimeType string, data []byte) string {
+	if ext, ok := MIMETypeExtensions[strings.ToLower(mimeType)]; ok {
+		return ext
+	}
+	sniffed, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	if ext, ok := MIMETypeExtensions[sniffed]; ok {
+		return ext
+	}
+	return ".bin"
+}
+
+// SafeFileName replaces the characters that are not allowed in file names on common file systems.
+func SafeFileName(name string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name))
+}
+
+// WriteNewFile writes r to a new file named name in dir and returns its path.
+// Files that already exist are never overwritten; the name is numbered instead, e.g. "cover (2).jpg".
+func WriteNewFile(dir, name string, r io.Reader) (string, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	dst := filepath.Join(dir, name)
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	for n := 2; errors.Is(err, fs.ErrExist); n++ {
+		dst = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", base, n, ext))
+		f, err = os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	}
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		return "", errors.Join(errors.WithStack(err), f.Close())
+	}
+	return dst, errors.WithStack(f.Close())
+}
@@ -0,0 +1,68 @@
+package frames
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtension(t *testing.T) {
+	png := []byte("\x89PNG\x0D\x0A\x1A\x0A")
+	testcases := []struct {
+		name     string
+		mimeType string
+		data     []byte
+		expected string
+	}{
+		{name: "known MIME type", mimeType: "image/jpeg", expected: ".jpg"},
+		{name: "MIME type case", mimeType: "IMAGE/PNG", expected: ".png"},
+		{name: "sniffed", mimeType: "-->", data: png, expected: ".png"},
+		{name: "unknown", mimeType: "application/x-whatever", data: []byte{0, 1, 2}, expected: ".bin"},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Extension(tt.mimeType, tt.data); got != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSafeFileName(t *testing.T) {
+	testcases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "Cover (front)", expected: "Cover (front)"},
+		{input: "AC/DC: Live?", expected: "AC_DC_ Live_"},
+		{input: " tab\there ", expected: "tab_here"},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := SafeFileName(tt.input); got != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWriteNewFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cover.jpg"), []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"cover (2).jpg", "cover (3).jpg"} {
+		dst, err := WriteNewFile(dir, "cover.jpg", strings.NewReader("picture"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dst != filepath.Join(dir, expected) {
+			t.Fatalf("expected %q, got %q", expected, dst)
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "cover.jpg"))
+	if err != nil || string(b) != "existing" {
+		t.Fatalf("expected the existing file to be kept, got %q: %v", b, err)
+	}
+}
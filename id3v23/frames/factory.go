@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/chuckha/tagger/id3string"
+	"gitlab.com/tozd/go/errors"
 )
 
 const HeaderMinSize = 10
@@ -25,7 +25,7 @@ func (f *FrameBodyUnpacker) UnmarshalJSON(data []byte) error {
 	fbs := []FrameBody{
 		&Comment{}, &TextInformation{}, &AttachedPicture{}, &UserDefinedURL{},
 		&PrivateData{}, &UserDefinedTextInformation{}, &MusicCDIdentifier{},
-		//		&GeneralEncapsulationObject{}, &TermsOfUse{},
+		&UnsynchronizedLyrics{}, &GeneralEncapsulationObject{}, &TermsOfUse{},
 	}
 	for _, fb := range fbs {
 		if err := fb.UnmarshalJSON(data); err == nil {
@@ -61,76 +61,70 @@ func (f *Frames) RemoveFramesWithID(id string) {
 	}
 }
 
+// ApplyFrame adds the frame to the tag following the id3v2.3 rules for how many of which frame can exist.
+// Any frame the new frame conflicts with is replaced; the new frame takes the place of the first one replaced.
 func (f *Frames) ApplyFrame(frame *Frame) error {
-	// TODO: add id3v2.3 rules here for how many of which frame can exist
-	switch IDToFrameKind[string(frame.Header.ID)] {
-	case TextInformationKind, NonStandardTextInformationKind:
-		// remove all of the frames with the same id
-		for i := 0; i < len(*f); i++ {
-			if (*f)[i].Header.ID != frame.Header.ID {
-				continue
-			}
-			*f = append((*f)[:i], (*f)[i+1:]...)
-			i--
+	rule, err := RuleFor(frame.Header.ID)
+	if err != nil {
+		return err
+	}
+	position := -1
+	for i := 0; i < len(*f); i++ {
+		if !rule.Conflicts((*f)[i], frame) {
+			continue
 		}
-	case AttachedPictureKind:
-		// if the content descriptor is the same; remove it
-		// if the incoming picture type is 01 or 02, remove the other 01 or 02 type
-		for i := 0; i < len(*f); i++ {
-			if (*f)[i].Header.ID != frame.Header.ID {
-				continue
-			}
-			if id3string.Equal((*f)[i].Body.(*AttachedPicture).Description, frame.Body.(*AttachedPicture).Description) {
-				*f = append((*f)[:i], (*f)[i+1:]...)
-				i--
-				continue
-			}
-			if frame.Body.(*AttachedPicture).PictureType == 0x01 {
-				if (*f)[i].Body.(*AttachedPicture).PictureType == 0x01 {
-					*f = append((*f)[:i], (*f)[i+1:]...)
-					i--
-					continue
-				}
-			}
-			if frame.Body.(*AttachedPicture).PictureType == 0x02 {
-				if (*f)[i].Body.(*AttachedPicture).PictureType == 0x02 {
-					*f = append((*f)[:i], (*f)[i+1:]...)
-					i--
-					continue
-				}
-			}
+		if position == -1 {
+			position = i
 		}
-	default:
-		return fmt.Errorf("apply frame cannot handle: %q", frame.Header.ID)
+		*f = append((*f)[:i], (*f)[i+1:]...)
+		i--
 	}
-
-	// append it if it's not handled by any above case
-	*f = append(*f, frame)
+	// append it if it does not replace anything
+	if position == -1 {
+		*f = append(*f, frame)
+		return nil
+	}
+	*f = append((*f)[:position], append(Frames{frame}, (*f)[position:]...)...)
 	return nil
 }
 
+// IgnoredFramesError is returned when a frame id has a null byte in it, which is usually padding that does not start with one.
+// The frames before it are read; the rest of the tag is ignored.
+type IgnoredFramesError struct {
+	Offset int
+	Size   int
+}
+
+func (e *IgnoredFramesError) Error() string {
+	return fmt.Sprintf("frame id at byte %d contains a null byte; ignored the last %d bytes of the tag", e.Offset, e.Size)
+}
+
+// UnmarshalBinary appends the frames of the tag data to f. It stops at padding.
+// An IgnoredFramesError leaves f with the frames read before it.
 func (f *Frames) UnmarshalBinary(data []byte) error {
 	ptr := 0
 	for ptr < len(data) {
 		if data[ptr] == '\x00' {
 			return nil
 		}
+		if ptr+HeaderMinSize > len(data) {
+			return errors.Errorf("frame header at byte %d is cut off by the end of the tag", ptr)
+		}
 		header := &FrameHeader{}
 		if err := header.UnmarshalBinary(data[ptr : ptr+HeaderMinSize]); err != nil {
 			return err
 		}
 		ptr += HeaderMinSize
 		// TODO: read in extended header
-		if ptr+header.Size > len(data) {
-			fmt.Println("header", header)
-		}
-		frame := &Frame{Header: header}
 		if strings.Contains(header.ID, "\x00") {
-			fmt.Println("warning: frame id contains null byte; ignoring the rest of the tag")
-			return nil
+			return errors.WithStack(&IgnoredFramesError{Offset: ptr - HeaderMinSize, Size: len(data) - ptr + HeaderMinSize})
+		}
+		if header.Size < 0 || ptr+header.Size > len(data) {
+			return errors.Errorf("frame %q of %d bytes at byte %d does not fit in the tag of %d bytes", header.ID, header.Size, ptr-HeaderMinSize, len(data))
 		}
+		frame := &Frame{Header: header}
 		if err := frame.UnmarshalBinary(data[ptr : ptr+header.Size]); err != nil {
-			return err
+			return errors.WithMessagef(err, "frame %q", header.ID)
 		}
 		ptr += header.Size
 		*f = append(*f, frame)
@@ -217,31 +211,40 @@ var IDToFrameKind = map[string]string{
 	"USER": TermsOfUseKind,
 }
 
-func (f *Frame) UnmarshalBinary(data []byte) error {
-	switch IDToFrameKind[string(f.Header.ID)] {
+// NewFrameBody returns an empty body of the right type for the frame ID.
+func NewFrameBody(id string) (FrameBody, error) {
+	switch IDToFrameKind[id] {
 	case TextInformationKind, NonStandardTextInformationKind:
-		f.Body = &TextInformation{}
+		return &TextInformation{}, nil
 	case CommentKind:
-		f.Body = &Comment{}
+		return &Comment{}, nil
 	case AttachedPictureKind:
-		f.Body = &AttachedPicture{}
+		return &AttachedPicture{}, nil
 	case UserDefinedURLKind:
-		f.Body = &UserDefinedURL{}
+		return &UserDefinedURL{}, nil
 	case PrivateKind:
-		f.Body = &PrivateData{}
-	// case UnsynchronizedLyricsKind:
-	// 	f.Body = &UnsynchronizedLyrics{}
+		return &PrivateData{}, nil
+	case UnsynchronizedLyricsKind:
+		return &UnsynchronizedLyrics{}, nil
 	case UserDefinedTextInformationKind:
-		f.Body = &UserDefinedTextInformation{}
+		return &UserDefinedTextInformation{}, nil
 	case MusicCDIdentifierKind:
-		f.Body = &MusicCDIdentifier{}
-	// case GeneralEncapsulationObjectKind:
-	// 	f.Body = &GeneralEncapsulationObject{}
-	// case TermsOfUseKind:
-	// 	f.Body = &TermsOfUse{}
+		return &MusicCDIdentifier{}, nil
+	case GeneralEncapsulationObjectKind:
+		return &GeneralEncapsulationObject{}, nil
+	case TermsOfUseKind:
+		return &TermsOfUse{}, nil
 	default:
-		panic(fmt.Sprintf("unknown frame type: %q", f.Header.ID))
+		return nil, errors.Errorf("unsupported frame %q (%s)", id, Descriptions[id])
+	}
+}
+
+func (f *Frame) UnmarshalBinary(data []byte) error {
+	body, err := NewFrameBody(f.Header.ID)
+	if err != nil {
+		body = &Unknown{}
 	}
+	f.Body = body
 	if err := f.Body.UnmarshalBinary(data); err != nil {
 		return err
 	}
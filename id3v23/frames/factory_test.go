@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sort"
 	"testing"
+
+	"gitlab.com/tozd/go/errors"
 )
 
 func TestFrames_FramesSorting(t *testing.T) {
@@ -24,3 +26,23 @@ func TestFrames_FramesSorting(t *testing.T) {
 		t.Fatal("did not sort correctly")
 	}
 }
+
+func TestFrames_UnmarshalBinaryIgnoresFramesAfterANullByte(t *testing.T) {
+	frame, err := NewFrame("TIT2", NewTextInformation("Spinner's End")).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(frame, []byte("TI\x00\x00\x00\x00\x00\x01\x00\x00a")...)
+	var fs Frames
+	err = fs.UnmarshalBinary(data)
+	var ignored *IgnoredFramesError
+	if !errors.As(err, &ignored) {
+		t.Fatalf("expected an IgnoredFramesError, got %v", err)
+	}
+	if ignored.Offset != len(frame) || ignored.Size != 11 {
+		t.Fatalf("expected 11 bytes at %d to be ignored, got %d at %d", len(frame), ignored.Size, ignored.Offset)
+	}
+	if len(fs) != 1 || fs[0].Header.ID != "TIT2" {
+		t.Fatalf("expected the frames before the null byte to be read, got %v", fs)
+	}
+}
@@ -0,0 +1,119 @@
+package frames
+
+import (
+	"sort"
+)
+
+// Field is a field of the JSON form of a frame body.
+type Field struct {
+	Name        string
+	Description string
+	// Enum lists the only values the field accepts.
+	Enum []string
+	// Data fields also take an object that reads the value from a file, e.g. {"File": "./cover.png"}.
+	Data bool
+	// Values fields accept a number or a list of values as well as a string.
+	Values bool
+}
+
+var languageField = Field{Name: "Language", Description: "ISO-639-2 language code, defaults to eng"}
+
+var encodingField = Field{
+	Name:        "TextEncoding",
+	Description: "defaults to ISO-8859-1 when all text is ascii and UTF-16 otherwise",
+	Enum:        Encodings,
+}
+
+// Fields returns the JSON fields of the body of the frame id or nil if the frame is not supported.
+func Fields(id string) []Field {
+	fields := bodyFields(id)
+	body, err := NewFrameBody(id)
+	if err != nil {
+		return fields
+	}
+	if _, _, ok := encodedText(body); ok {
+		fields = append(fields, encodingField)
+	}
+	return fields
+}
+
+func bodyFields(id string) []Field {
+	switch IDToFrameKind[id] {
+	case TextInformationKind, NonStandardTextInformationKind:
+		return []Field{{Name: "Information", Description: Descriptions[id], Values: true}}
+	case CommentKind:
+		return []Field{languageField, {Name: "ShortContentDescription"}, {Name: "ActualText"}}
+	case AttachedPictureKind:
+		return []Field{
+			{Name: "MIMEType"},
+			{Name: "PictureType", Enum: pictureTypeNames()},
+			{Name: "Description"},
+			{Name: "Data", Description: "path to the picture file, optionally prefixed with @", Data: true},
+		}
+	case UserDefinedURLKind:
+		return []Field{{Name: "Description"}, {Name: "URL"}}
+	case PrivateKind:
+		return []Field{{Name: "OwnerIdentifier"}, {Name: "Data", Data: true}}
+	case UnsynchronizedLyricsKind:
+		return []Field{languageField, {Name: "ContentDescriptor"}, {Name: "Lyrics", Data: true}}
+	case UserDefinedTextInformationKind:
+		return []Field{{Name: "Description"}, {Name: "Value"}}
+	case MusicCDIdentifierKind:
+		return []Field{{Name: "TableOfContents", Data: true}}
+	case GeneralEncapsulationObjectKind:
+		return []Field{
+			{Name: "MIMEType"},
+			{Name: "Filename", Description: "defaults to the name of the EncapsulatedObject file"},
+			{Name: "ContentDescription"},
+			{Name: "EncapsulatedObject", Data: true},
+		}
+	case TermsOfUseKind:
+		return []Field{languageField, {Name: "Text"}}
+	}
+	return nil
+}
+
+// ValueField is the field a lone value sets, e.g. the Information of TIT2 or the Data of APIC.
+func ValueField(id string) string {
+	switch IDToFrameKind[id] {
+	case TextInformationKind, NonStandardTextInformationKind:
+		return "Information"
+	case CommentKind:
+		return "ActualText"
+	case AttachedPictureKind, PrivateKind:
+		return "Data"
+	case UserDefinedURLKind:
+		return "URL"
+	case UnsynchronizedLyricsKind:
+		return "Lyrics"
+	case UserDefinedTextInformationKind:
+		return "Value"
+	case MusicCDIdentifierKind:
+		return "TableOfContents"
+	case GeneralEncapsulationObjectKind:
+		return "EncapsulatedObject"
+	case TermsOfUseKind:
+		return "Text"
+	}
+	return ""
+}
+
+// SupportedIDs are the frame IDs that can be read from and written to a config, sorted.
+func SupportedIDs() []string {
+	ids := []string{}
+	for id := range IDToFrameKind {
+		if Fields(id) != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func pictureTypeNames() []string {
+	names := make([]string, 0, len(PictureTypes))
+	for i := 0; i < len(PictureTypes); i++ {
+		names = append(names, PictureTypes[byte(i)])
+	}
+	return names
+}
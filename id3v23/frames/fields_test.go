@@ -0,0 +1,55 @@
+package frames
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	picture := filepath.Join(t.TempDir(), "cover.png")
+	if err := os.WriteFile(picture, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ids := SupportedIDs()
+	if len(ids) == 0 {
+		t.Fatal("expected supported frames")
+	}
+	for _, id := range ids {
+		body, err := NewFrameBody(id)
+		if err != nil {
+			t.Fatalf("%s is in the supported frames but has no body: %v", id, err)
+		}
+		in := map[string]string{}
+		for _, field := range Fields(id) {
+			value := "value"
+			if field.Enum != nil {
+				value = field.Enum[len(field.Enum)-1]
+			}
+			if field.Name == "Language" {
+				value = "deu"
+			}
+			if id == "APIC" && field.Name == "Data" {
+				value = picture
+			}
+			in[field.Name] = value
+		}
+		b, err := json.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := body.UnmarshalJSON(b); err != nil {
+			t.Fatalf("%s: %v", id, err)
+		}
+		if _, ok := in[ValueField(id)]; !ok {
+			t.Errorf("%s: expected the value field %q to be one of the fields", id, ValueField(id))
+		}
+		if id != "MCDI" && Text(body) == "" && id != "APIC" && id != "PRIV" && id != "GEOB" {
+			t.Errorf("%s: expected the fields to set the text of the frame, got %s", id, body)
+		}
+	}
+	if Fields("XXXX") != nil {
+		t.Fatal("expected an unknown frame to have no fields")
+	}
+}
@@ -1,9 +1,15 @@
 package frames
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"path/filepath"
 
 	"github.com/chuckha/tagger/id3string"
+
+	"gitlab.com/tozd/go/errors"
 )
 
 // GeneralEncapsulationObject have the ID GEOB
@@ -16,22 +22,73 @@ type GeneralEncapsulationObject struct {
 }
 
 func (g *GeneralEncapsulationObject) UnmarshalBinary(data []byte) error {
-	g.TextEncoding = data[0]
+	enc, err := readEncoding(data, 1)
+	if err != nil {
+		return err
+	}
+	g.TextEncoding = enc
 	ptr := 1
 	g.MIMEType = id3string.ExtractNullTerminatedASCII(data[ptr:])
-	ptr += len(g.MIMEType) + 1
-	filename, n := id3string.ExtractNullTerminatedValueWithEncoding(g.TextEncoding, data[ptr:])
+	ptr = min(ptr+len(g.MIMEType)+1, len(data))
+	filename, n, err := id3string.ExtractNullTerminatedValueWithEncoding(g.TextEncoding, data[ptr:])
+	if err != nil {
+		return errors.WithStack(err)
+	}
 	g.Filename = filename
-	ptr += len(g.Filename) + n
-	contentDescription, n := id3string.ExtractNullTerminatedValueWithEncoding(g.TextEncoding, data[ptr:])
+	ptr += n
+	contentDescription, n, err := id3string.ExtractNullTerminatedValueWithEncoding(g.TextEncoding, data[ptr:])
+	if err != nil {
+		return errors.WithStack(err)
+	}
 	g.ContentDescription = contentDescription
-	ptr += len(g.ContentDescription) + n
+	ptr += n
 	g.EncapsulatedObject = data[ptr:]
 	return nil
 }
 
+// UnmarshalJSON reads the EncapsulatedObject as text or, given {"File": "path"}, from a file.
+// The Filename defaults to the name of that file.
+func (g *GeneralEncapsulationObject) UnmarshalJSON(data []byte) error {
+	var in struct {
+		MIMEType           string
+		Filename           string
+		ContentDescription string
+		EncapsulatedObject dataValue
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return errors.WithStack(err)
+	}
+	if in.Filename == "" && in.EncapsulatedObject.File != "" {
+		in.Filename = filepath.Base(in.EncapsulatedObject.File)
+	}
+	g.MIMEType = in.MIMEType
+	g.Filename = []rune(in.Filename)
+	g.ContentDescription = []rune(in.ContentDescription)
+	g.EncapsulatedObject = in.EncapsulatedObject.Bytes
+	g.TextEncoding = textEncoding(g.Filename, g.ContentDescription)
+	return nil
+}
+
+// Reader reads the encapsulated object.
+func (g *GeneralEncapsulationObject) Reader() io.Reader {
+	return bytes.NewReader(g.EncapsulatedObject)
+}
+
+// FileName is the stored filename without any directories.
+// An object without a filename is named by its description and the extension of its MIME type or its contents.
+func (g *GeneralEncapsulationObject) FileName() string {
+	if name := SafeFileName(filepath.Base(string(g.Filename))); name != "" && name != "." && name != ".." {
+		return name
+	}
+	name := SafeFileName(string(g.ContentDescription))
+	if name == "" {
+		name = "object"
+	}
+	return name + Extension(g.MIMEType, g.EncapsulatedObject)
+}
+
 func (g *GeneralEncapsulationObject) String() string {
-	return fmt.Sprintf("mime: %q; filename: %q; contentdesc: %q; encapsulatedobject: %q", g.MIMEType, g.Filename, g.ContentDescription, g.EncapsulatedObject)
+	return fmt.Sprintf("enc: %x; mime: %q; filename: %q; contentdesc: %q; size: %d bytes", g.TextEncoding, g.MIMEType, string(g.Filename), string(g.ContentDescription), len(g.EncapsulatedObject))
 }
 
 func (g *GeneralEncapsulationObject) MarshalBinary() ([]byte, error) {
@@ -37,3 +37,23 @@ func TestGeneralEncapsulationObjectEncoding(t *testing.T) {
 		}
 	})
 }
+
+func TestGeneralEncapsulationObject_FileName(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    *GeneralEncapsulationObject
+		expected string
+	}{
+		{name: "stored filename", input: &GeneralEncapsulationObject{Filename: []rune("notes.txt")}, expected: "notes.txt"},
+		{name: "no directories", input: &GeneralEncapsulationObject{Filename: []rune("../../etc/passwd")}, expected: "passwd"},
+		{name: "description", input: &GeneralEncapsulationObject{MIMEType: "text/plain", ContentDescription: []rune("liner notes")}, expected: "liner notes.txt"},
+		{name: "nothing", input: &GeneralEncapsulationObject{EncapsulatedObject: []byte{0}}, expected: "object.bin"},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.input.FileName(); got != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
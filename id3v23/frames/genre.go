@@ -0,0 +1,223 @@
+package frames
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// GenreRemix is the TCON reference for a remix.
+	GenreRemix = "RX"
+	// GenreCover is the TCON reference for a cover.
+	GenreCover = "CR"
+)
+
+// SpecialGenres are the non-numeric references allowed in a TCON frame.
+var SpecialGenres = map[string]string{
+	GenreRemix: "Remix",
+	GenreCover: "Cover",
+}
+
+// Genres is the ID3v1 genre list including the Winamp extensions.
+// TCON frames refer to these genres by their index, e.g. "(17)" is Rock.
+var Genres = [...]string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge", "Hip-Hop",
+	"Jazz", "Metal", "New Age", "Oldies", "Other", "Pop", "R&B", "Rap",
+	"Reggae", "Rock", "Techno", "Industrial", "Alternative", "Ska", "Death Metal", "Pranks",
+	"Soundtrack", "Euro-Techno", "Ambient", "Trip-Hop", "Vocal", "Jazz+Funk", "Fusion", "Trance",
+	"Classical", "Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise",
+	"AlternRock", "Bass", "Soul", "Punk", "Space", "Meditative", "Instrumental Pop", "Instrumental Rock",
+	"Ethnic", "Gothic", "Darkwave", "Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream",
+	"Southern Rock", "Comedy", "Cult", "Gangsta", "Top 40", "Christian Rap", "Pop/Funk", "Jungle",
+	"Native American", "Cabaret", "New Wave", "Psychadelic", "Rave", "Showtunes", "Trailer", "Lo-Fi",
+	"Tribal", "Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll", "Hard Rock",
+	"Folk", "Folk-Rock", "National Folk", "Swing", "Fast Fusion", "Bebob", "Latin", "Revival",
+	"Celtic", "Bluegrass", "Avantgarde", "Gothic Rock", "Progressive Rock", "Psychedelic Rock", "Symphonic Rock", "Slow Rock",
+	"Big Band", "Chorus", "Easy Listening", "Acoustic", "Humour", "Speech", "Chanson", "Opera",
+	"Chamber Music", "Sonata", "Symphony", "Booty Bass", "Primus", "Porn Groove", "Satire", "Slow Jam",
+	"Club", "Tango", "Samba", "Folklore", "Ballad", "Power Ballad", "Rhythmic Soul", "Freestyle",
+	"Duet", "Punk Rock", "Drum Solo", "A capella", "Euro-House", "Dance Hall", "Goa", "Drum & Bass",
+	"Club-House", "Hardcore", "Terror", "Indie", "BritPop", "Afro-Punk", "Polsk Punk", "Beat",
+	"Christian Gangsta Rap", "Heavy Metal", "Black Metal", "Crossover", "Contemporary Christian", "Christian Rock", "Merengue", "Salsa",
+	"Thrash Metal", "Anime", "JPop", "Synthpop", "Abstract", "Art Rock", "Baroque", "Bhangra",
+	"Big Beat", "Breakbeat", "Chillout", "Downtempo", "Dub", "EBM", "Eclectic", "Electro",
+	"Electroclash", "Emo", "Experimental", "Garage", "Global", "IDM", "Illbient", "Industro-Goth",
+	"Jam Band", "Krautrock", "Leftfield", "Lounge", "Math Rock", "New Romantic", "Nu-Breakz", "Post-Punk",
+	"Post-Rock", "Psytrance", "Shoegaze", "Space Rock", "Trop Rock", "World Music", "Neoclassical", "Audiobook",
+	"Audio Theatre", "Neue Deutsche Welle", "Podcast", "Indie Rock", "G-Funk", "Dubstep", "Garage Rock", "Psybient",
+}
+
+// LookupGenre finds the index of a genre by its name, ignoring case.
+func LookupGenre(name string) (int, bool) {
+	for i, genre := range Genres {
+		if strings.EqualFold(genre, strings.TrimSpace(name)) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// GenreReference is a single "(17)", "(RX)" or "(CR)" reference found in a TCON frame.
+type GenreReference struct {
+	// ID is the index into Genres. It is -1 for special references.
+	ID int
+	// Special is either GenreRemix or GenreCover.
+	Special string
+}
+
+// Name resolves the reference to a human readable genre.
+func (g GenreReference) Name() string {
+	if g.Special != "" {
+		return SpecialGenres[g.Special]
+	}
+	if !g.known() {
+		return "Unknown"
+	}
+	return Genres[g.ID]
+}
+
+// known reports whether the reference is special or an index into Genres.
+func (g GenreReference) known() bool {
+	return g.Special != "" || (g.ID >= 0 && g.ID < len(Genres))
+}
+
+func (g GenreReference) String() string {
+	if g.Special != "" {
+		return "(" + g.Special + ")"
+	}
+	return "(" + strconv.Itoa(g.ID) + ")"
+}
+
+// ContentType is the parsed form of a TCON frame.
+// id3v2.3 encodes it as any number of references followed by an optional refinement, e.g. "(4)(80)Eurodisco".
+// Further refinements are null separated, the way TextInformation keeps multiple values.
+type ContentType struct {
+	References  []GenreReference
+	Refinements []string
+}
+
+// ParseContentType parses the information of a TCON frame.
+// Bare numbers and null separated values written by other taggers are also understood.
+func ParseContentType(info string) *ContentType {
+	ct := &ContentType{}
+	for _, val := range strings.Split(strings.TrimRight(info, NullSeparator), NullSeparator) {
+		if refinement := ct.parse(val); refinement != "" {
+			ct.Refinements = append(ct.Refinements, refinement)
+		}
+	}
+	return ct
+}
+
+// parse adds every reference in val and returns what is left over as the refinement.
+func (c *ContentType) parse(val string) string {
+	if id, err := strconv.Atoi(strings.TrimSpace(val)); err == nil && (GenreReference{ID: id}).known() {
+		c.References = append(c.References, GenreReference{ID: id})
+		return ""
+	}
+	for strings.HasPrefix(val, "(") {
+		// "((" escapes a refinement that starts with a parenthesis
+		if strings.HasPrefix(val, "((") {
+			return val[1:]
+		}
+		end := strings.Index(val, ")")
+		if end == -1 {
+			return val
+		}
+		ref := val[1:end]
+		if _, ok := SpecialGenres[ref]; ok {
+			c.References = append(c.References, GenreReference{ID: -1, Special: ref})
+		} else if id, err := strconv.Atoi(ref); err == nil {
+			c.References = append(c.References, GenreReference{ID: id})
+		} else {
+			return val
+		}
+		val = val[end+1:]
+	}
+	return val
+}
+
+// NewContentType builds a content type out of genre names, genre numbers or already encoded TCON values.
+// Anything that cannot be resolved to a genre becomes a refinement, including references outside of Genres.
+// When there is no refinement the name of the last reference is used so players that ignore references still display a genre.
+func NewContentType(vals ...string) *ContentType {
+	ct := &ContentType{}
+	for _, val := range vals {
+		val = strings.TrimSpace(val)
+		if val == "" {
+			continue
+		}
+		if id, ok := LookupGenre(val); ok {
+			ct.References = append(ct.References, GenreReference{ID: id})
+			continue
+		}
+		if special := specialGenre(val); special != "" {
+			ct.References = append(ct.References, GenreReference{ID: -1, Special: special})
+			continue
+		}
+		parsed := &ContentType{}
+		refinement := parsed.parse(val)
+		for _, ref := range parsed.References {
+			if !ref.known() {
+				parsed.References, refinement = nil, val
+				break
+			}
+		}
+		ct.References = append(ct.References, parsed.References...)
+		if refinement != "" {
+			ct.Refinements = append(ct.Refinements, refinement)
+		}
+	}
+	if len(ct.Refinements) == 0 && len(ct.References) > 0 {
+		last := ct.References[len(ct.References)-1]
+		if last.Special == "" && last.known() {
+			ct.Refinements = []string{last.Name()}
+		}
+	}
+	return ct
+}
+
+func specialGenre(val string) string {
+	for special, name := range SpecialGenres {
+		if strings.EqualFold(val, special) || strings.EqualFold(val, name) {
+			return special
+		}
+	}
+	return ""
+}
+
+// Names resolves every reference and appends each refinement unless it repeats a referenced genre.
+func (c *ContentType) Names() []string {
+	names := []string{}
+	for _, ref := range c.References {
+		names = append(names, ref.Name())
+	}
+	referenced := names
+refinements:
+	for _, refinement := range c.Refinements {
+		for _, name := range referenced {
+			if strings.EqualFold(name, refinement) {
+				continue refinements
+			}
+		}
+		names = append(names, refinement)
+	}
+	return names
+}
+
+// String encodes the content type the way id3v2.3 expects it in a TCON frame.
+func (c *ContentType) String() string {
+	var s strings.Builder
+	for _, ref := range c.References {
+		s.WriteString(ref.String())
+	}
+	for i, refinement := range c.Refinements {
+		if i > 0 {
+			s.WriteString(NullSeparator)
+		}
+		if strings.HasPrefix(refinement, "(") {
+			s.WriteString("(")
+		}
+		s.WriteString(refinement)
+	}
+	return s.String()
+}
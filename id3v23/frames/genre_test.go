@@ -0,0 +1,76 @@
+package frames
+
+import "testing"
+
+func TestGenres(t *testing.T) {
+	if len(Genres) != 192 {
+		t.Fatalf("expected 192 genres, got %d", len(Genres))
+	}
+	if Genres[17] != "Rock" || Genres[80] != "Folk" || Genres[183] != "Audiobook" {
+		t.Fatal("genre table is out of order")
+	}
+}
+
+func TestParseContentType(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{name: "plain text", input: "Audiobook", expected: []string{"Audiobook"}},
+		{name: "single reference", input: "(17)", expected: []string{"Rock"}},
+		{name: "references and refinement", input: "(17)(80)Folk", expected: []string{"Rock", "Folk"}},
+		{name: "refinement differs from reference", input: "(4)Eurodisco", expected: []string{"Disco", "Eurodisco"}},
+		{name: "special references", input: "(RX)(CR)", expected: []string{"Remix", "Cover"}},
+		{name: "escaped parenthesis", input: "((I think...)", expected: []string{"(I think...)"}},
+		{name: "bare number", input: "13", expected: []string{"Pop"}},
+		{name: "null separated", input: "(17)\x00Folk\x00", expected: []string{"Rock", "Folk"}},
+		{name: "unknown reference", input: "(250)", expected: []string{"Unknown"}},
+		{name: "bare number outside of the genres", input: "250", expected: []string{"250"}},
+		{name: "escaped reference outside of the genres", input: "(17)((250)", expected: []string{"Rock", "(250)"}},
+		{name: "several refinements", input: "Folk\x00Rock", expected: []string{"Folk", "Rock"}},
+		{name: "refinements after references", input: "(17)Fantasy\x00((SciFi)", expected: []string{"Rock", "Fantasy", "(SciFi)"}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseContentType(tt.input).Names()
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %q, got %q", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("expected %q, got %q", tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestNewContentType(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    []string
+		expected string
+	}{
+		{name: "by name", input: []string{"rock"}, expected: "(17)Rock"},
+		{name: "by number", input: []string{"80"}, expected: "(80)Folk"},
+		{name: "several genres", input: []string{"Rock", "Folk"}, expected: "(17)(80)Folk"},
+		{name: "unknown genre is a refinement", input: []string{"Rock", "Space Opera"}, expected: "(17)Space Opera"},
+		{name: "special genre", input: []string{"Remix"}, expected: "(RX)"},
+		{name: "already encoded", input: []string{"(17)(80)Folk"}, expected: "(17)(80)Folk"},
+		{name: "refinement with a parenthesis", input: []string{"(live)"}, expected: "((live)"},
+		{name: "number outside of the genres", input: []string{"250"}, expected: "250"},
+		{name: "reference outside of the genres", input: []string{"Rock", "(250)"}, expected: "(17)((250)"},
+		{name: "negative number", input: []string{"-1"}, expected: "-1"},
+		{name: "several refinements", input: []string{"Fantasy", "SciFi"}, expected: "Fantasy\x00SciFi"},
+		{name: "references and several refinements", input: []string{"Rock", "Fantasy", "(SciFi)"}, expected: "(17)Fantasy\x00((SciFi)"},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewContentType(tt.input...).String()
+			if got != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
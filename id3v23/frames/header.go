@@ -105,3 +105,24 @@ func (f *FrameHeader) FlagsAsBytes() [2]byte {
 	return flags
 
 }
+
+// FlagNames lists the flags that are set, in the order they appear in the header.
+func (f *FrameHeader) FlagNames() []string {
+	names := []string{}
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"PreserveTagOnAlteration", f.PreserveTagOnAlteration},
+		{"PreserveFileOnAlteration", f.PreserveFileOnAlteration},
+		{"ReadOnly", f.ReadOnly},
+		{"Compressed", f.Compressed},
+		{"Encrypted", f.Encrypted},
+		{"ContainsGroupingIdentity", f.ContainsGroupingIdentity},
+	} {
+		if flag.set {
+			names = append(names, flag.name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,62 @@
+package frames
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/chuckha/tagger/id3string"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// dataValue is frame data read from JSON.
+// A string is used as is; an object such as {"File": "cover.jpg"} reads the data from that file.
+type dataValue struct {
+	Bytes []byte
+	// File is the file the data was read from, if any.
+	File string
+}
+
+func (d *dataValue) UnmarshalJSON(b []byte) error {
+	var text string
+	if err := json.Unmarshal(b, &text); err == nil {
+		d.Bytes, d.File = []byte(text), ""
+		return nil
+	}
+	var in struct {
+		File string
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return errors.Errorf(`expected text or {"File": "path"}, got %s`, b)
+	}
+	if in.File == "" {
+		return errors.New(`{"File": "path"} needs a path`)
+	}
+	contents, err := os.ReadFile(in.File)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	d.Bytes, d.File = contents, in.File
+	return nil
+}
+
+// textEncoding picks ISO-8859-1 when every value is ascii and UTF-16 otherwise.
+func textEncoding(vals ...[]rune) byte {
+	for _, val := range vals {
+		if !id3string.IsASCII(val) {
+			return 1
+		}
+	}
+	return 0
+}
+
+// language defaults to english since every language field must be three characters.
+func language(in string) (string, error) {
+	if in == "" {
+		return "eng", nil
+	}
+	if len(in) != 3 {
+		return "", errors.Errorf("language must be a three letter ISO-639-2 code, got %q", in)
+	}
+	return in, nil
+}
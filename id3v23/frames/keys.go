@@ -0,0 +1,149 @@
+package frames
+
+import (
+	"strings"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// A frame key names a frame in a config or on the command line.
+// It is the frame ID optionally followed by a colon and a qualifier that picks out one of several frames with that ID:
+//
+//	TXXX:Narrator  the TXXX frame with the description "Narrator"
+//	COMM:eng       every COMM frame in english
+//	COMM:eng:Note  the english COMM frame with the description "Note"
+//	PRIV:owner     every PRIV frame owned by "owner"
+//
+// TXXX, WXXX, APIC and GEOB are qualified by their description.
+// COMM and USLT are qualified by their language and optionally their description.
+// USER is qualified by its language and PRIV by its owner.
+
+// ParseKey splits a frame key into the frame ID and the qualifier.
+func ParseKey(key string) (id, qualifier string) {
+	id, qualifier, _ = strings.Cut(key, ":")
+	return id, qualifier
+}
+
+// ValidateKey checks that the key names a supported frame and that the frame can be qualified with its qualifier.
+func ValidateKey(key string) error {
+	id, qualifier := ParseKey(key)
+	body, err := NewFrameBody(id)
+	if err != nil {
+		return err
+	}
+	return Qualify(id, body, qualifier)
+}
+
+// Qualify sets the fields the qualifier refers to on the body of a frame with the given id.
+func Qualify(id string, body FrameBody, qualifier string) error {
+	if qualifier == "" {
+		return nil
+	}
+	switch b := body.(type) {
+	case *UserDefinedTextInformation:
+		b.Description = []rune(qualifier)
+		b.TextEncoding = textEncoding(b.Description, b.Value)
+	case *UserDefinedURL:
+		b.Description = []rune(qualifier)
+		b.TextEncoding = textEncoding(b.Description)
+	case *AttachedPicture:
+		b.Description = []rune(qualifier)
+		b.TextEncoding = textEncoding(b.Description)
+	case *GeneralEncapsulationObject:
+		b.ContentDescription = []rune(qualifier)
+		b.TextEncoding = textEncoding(b.Filename, b.ContentDescription)
+	case *Comment:
+		lang, desc, hasDesc := strings.Cut(qualifier, ":")
+		if _, err := language(lang); err != nil {
+			return err
+		}
+		b.Language = lang
+		if hasDesc {
+			b.ShortContentDescription = []rune(desc)
+		}
+		b.TextEncoding = textEncoding(b.ShortContentDescription, b.ActualText)
+	case *UnsynchronizedLyrics:
+		lang, desc, hasDesc := strings.Cut(qualifier, ":")
+		if _, err := language(lang); err != nil {
+			return err
+		}
+		b.Language = lang
+		if hasDesc {
+			b.ContentDescriptor = []rune(desc)
+		}
+		b.TextEncoding = textEncoding(b.ContentDescriptor, []rune(b.Lyrics))
+	case *TermsOfUse:
+		if _, err := language(qualifier); err != nil {
+			return err
+		}
+		b.Language = qualifier
+	case *PrivateData:
+		b.OwnerIdentifier = qualifier
+	default:
+		return errors.Errorf("%s frames cannot be qualified with %q", id, qualifier)
+	}
+	return nil
+}
+
+// Qualifier returns the most specific qualifier for the body; it is the inverse of Qualify.
+func Qualifier(body FrameBody) string {
+	switch b := body.(type) {
+	case *UserDefinedTextInformation:
+		return string(b.Description)
+	case *UserDefinedURL:
+		return string(b.Description)
+	case *AttachedPicture:
+		return string(b.Description)
+	case *GeneralEncapsulationObject:
+		return string(b.ContentDescription)
+	case *Comment:
+		if len(b.ShortContentDescription) == 0 {
+			return b.Language
+		}
+		return b.Language + ":" + string(b.ShortContentDescription)
+	case *UnsynchronizedLyrics:
+		if len(b.ContentDescriptor) == 0 {
+			return b.Language
+		}
+		return b.Language + ":" + string(b.ContentDescriptor)
+	case *TermsOfUse:
+		return b.Language
+	case *PrivateData:
+		return b.OwnerIdentifier
+	default:
+		return ""
+	}
+}
+
+// Key returns the frame key of the frame.
+func (f *Frame) Key() string {
+	if q := Qualifier(f.Body); q != "" {
+		return f.Header.ID + ":" + q
+	}
+	return f.Header.ID
+}
+
+// MatchesKey reports if the frame is named by the key.
+// A key without a qualifier matches every frame with the ID.
+// A language qualifier on COMM or USLT matches every description in that language.
+func (f *Frame) MatchesKey(key string) bool {
+	id, qualifier := ParseKey(key)
+	if f.Header.ID != id {
+		return false
+	}
+	if qualifier == "" {
+		return true
+	}
+	actual := Qualifier(f.Body)
+	if actual == qualifier {
+		return true
+	}
+	switch f.Body.(type) {
+	case *Comment, *UnsynchronizedLyrics:
+		if !strings.Contains(qualifier, ":") {
+			lang, _, _ := strings.Cut(actual, ":")
+			return lang == qualifier
+		}
+	}
+	return false
+}
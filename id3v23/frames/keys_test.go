@@ -0,0 +1,70 @@
+package frames
+
+import "testing"
+
+func TestFrameKeys(t *testing.T) {
+	t.Run("qualify is the inverse of qualifier", func(t *testing.T) {
+		testcases := []struct {
+			key  string
+			body FrameBody
+		}{
+			{key: "TXXX:Narrator", body: &UserDefinedTextInformation{}},
+			{key: "WXXX:homepage", body: &UserDefinedURL{}},
+			{key: "APIC:cover", body: &AttachedPicture{}},
+			{key: "GEOB:object", body: &GeneralEncapsulationObject{}},
+			{key: "COMM:eng", body: &Comment{}},
+			{key: "COMM:eng:Note", body: &Comment{}},
+			{key: "USLT:jpn:歌詞", body: &UnsynchronizedLyrics{}},
+			{key: "USER:eng", body: &TermsOfUse{}},
+			{key: "PRIV:owner", body: &PrivateData{}},
+		}
+		for _, tt := range testcases {
+			t.Run(tt.key, func(t *testing.T) {
+				id, qualifier := ParseKey(tt.key)
+				if err := Qualify(id, tt.body, qualifier); err != nil {
+					t.Fatal(err)
+				}
+				if got := NewFrame(id, tt.body).Key(); got != tt.key {
+					t.Fatalf("expected %q, got %q", tt.key, got)
+				}
+			})
+		}
+	})
+
+	t.Run("text frames cannot be qualified", func(t *testing.T) {
+		if err := Qualify("TIT2", NewTextInformation("title"), "x"); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("matching", func(t *testing.T) {
+		comment := NewFrame("COMM", &Comment{Language: "eng", ShortContentDescription: []rune("Note")})
+		for key, expected := range map[string]bool{
+			"COMM":          true,
+			"COMM:eng":      true,
+			"COMM:eng:Note": true,
+			"COMM:eng:Else": false,
+			"COMM:jpn":      false,
+			"TXXX":          false,
+		} {
+			if got := comment.MatchesKey(key); got != expected {
+				t.Errorf("expected %q to match %v, got %v", key, expected, got)
+			}
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		for key, valid := range map[string]bool{
+			"TIT2":          true,
+			"TXXX:Narrator": true,
+			"COMM:eng:Note": true,
+			"COMM:english":  false,
+			"TIT2:x":        false,
+			"XXXX":          false,
+		} {
+			if err := ValidateKey(key); (err == nil) != valid {
+				t.Errorf("expected %q to be valid: %v, got %v", key, valid, err)
+			}
+		}
+	})
+}
@@ -16,10 +16,15 @@ func (m *MusicCDIdentifier) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// UnmarshalJSON reads the TableOfContents as text or, given {"File": "path"}, from a file.
 func (m *MusicCDIdentifier) UnmarshalJSON(data []byte) error {
-	if err := json.Unmarshal(data, m); err != nil {
+	var in struct {
+		TableOfContents dataValue
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
 		return errors.WithStack(err)
 	}
+	m.TableOfContents = in.TableOfContents.Bytes
 	return nil
 }
 
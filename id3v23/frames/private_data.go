@@ -21,10 +21,17 @@ func (p *PrivateData) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
-func (b *PrivateData) UnmarshalJSON(data []byte) error {
-	if err := json.Unmarshal(data, b); err != nil {
+// UnmarshalJSON reads Data as text or, given {"File": "path"}, from a file.
+func (p *PrivateData) UnmarshalJSON(data []byte) error {
+	var in struct {
+		OwnerIdentifier string
+		Data            dataValue
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
 		return errors.WithStack(err)
 	}
+	p.OwnerIdentifier = in.OwnerIdentifier
+	p.Data = in.Data.Bytes
 	return nil
 }
 
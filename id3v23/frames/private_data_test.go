@@ -1,6 +1,11 @@
 package frames
 
-import "testing"
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestPrivateDataEncoding(t *testing.T) {
 	t.Run("marshal is inverse of unmarshal", func(t *testing.T) {
@@ -34,3 +39,39 @@ func TestPrivateDataEncoding(t *testing.T) {
 		}
 	})
 }
+
+func TestPrivateDataUnmarshalJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte{0, 1, 2}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := json.Marshal(map[string]any{"OwnerIdentifier": "owner", "Data": map[string]string{"File": path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testcases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "text", input: `{"OwnerIdentifier": "owner", "Data": "data"}`, expected: "data"},
+		{name: "text that starts with an @", input: `{"OwnerIdentifier": "owner", "Data": "@handle"}`, expected: "@handle"},
+		{name: "file", input: string(file), expected: "\x00\x01\x02"},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PrivateData{}
+			if err := p.UnmarshalJSON([]byte(tt.input)); err != nil {
+				t.Fatal(err)
+			}
+			if string(p.Data) != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, p.Data)
+			}
+		})
+	}
+	for _, input := range []string{`{"Data": {"File": ""}}`, `{"Data": {"File": "does not exist"}}`, `{"Data": 1}`} {
+		if err := (&PrivateData{}).UnmarshalJSON([]byte(input)); err == nil {
+			t.Errorf("expected %s to be rejected", input)
+		}
+	}
+}
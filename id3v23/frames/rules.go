@@ -0,0 +1,154 @@
+package frames
+
+import (
+	"fmt"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// FrameRule describes how many frames with the same ID may exist in an id3v2.3 tag.
+type FrameRule struct {
+	// Description is the rule as the spec words it.
+	Description string
+	// Keys identify a frame among the frames that share its ID.
+	// Two frames with the same ID conflict when they share a key.
+	// When Keys is nil only one frame with the ID may exist.
+	Keys func(FrameBody) []string
+}
+
+// single is the rule for frames that may only appear once in a tag.
+var single = FrameRule{Description: "only one per tag"}
+
+// sameContent is the rule for frames that may appear more than once but not with the same contents.
+// The URL frames other than WXXX are not parsed so their contents are compared as bytes.
+var sameContent = FrameRule{
+	Description: "only one with the same contents",
+	Keys: func(fb FrameBody) []string {
+		b, err := fb.MarshalBinary()
+		if err != nil {
+			return []string{}
+		}
+		return []string{string(b)}
+	},
+}
+
+// FrameRules are the multiplicity rules for every frame ID that is not a text frame.
+// Text frames may only appear once per tag.
+var FrameRules = map[string]FrameRule{
+	"COMM": {
+		Description: "only one per language and description",
+		Keys: func(fb FrameBody) []string {
+			c := fb.(*Comment)
+			return []string{c.Language + "\x00" + string(c.ShortContentDescription)}
+		},
+	},
+	"APIC": {
+		Description: "only one per description and only one of each file icon type",
+		Keys: func(fb FrameBody) []string {
+			a := fb.(*AttachedPicture)
+			keys := []string{"desc\x00" + string(a.Description)}
+			if a.PictureType == 0x01 || a.PictureType == 0x02 {
+				keys = append(keys, fmt.Sprintf("type\x00%d", a.PictureType))
+			}
+			return keys
+		},
+	},
+	"WXXX": {
+		Description: "only one per description",
+		Keys: func(fb FrameBody) []string {
+			return []string{string(fb.(*UserDefinedURL).Description)}
+		},
+	},
+	"PRIV": {
+		Description: "only one with the same owner and contents",
+		Keys: func(fb FrameBody) []string {
+			p := fb.(*PrivateData)
+			return []string{p.OwnerIdentifier + "\x00" + string(p.Data)}
+		},
+	},
+	"USLT": {
+		Description: "only one per language and content descriptor",
+		Keys: func(fb FrameBody) []string {
+			u := fb.(*UnsynchronizedLyrics)
+			return []string{u.Language + "\x00" + string(u.ContentDescriptor)}
+		},
+	},
+	"TXXX": {
+		Description: "only one per description",
+		Keys: func(fb FrameBody) []string {
+			return []string{string(fb.(*UserDefinedTextInformation).Description)}
+		},
+	},
+	"MCDI": single,
+	"WCOM": sameContent,
+	"WCOP": single,
+	"WOAF": single,
+	"WOAR": sameContent,
+	"WOAS": single,
+	"WORS": single,
+	"WPAY": single,
+	"WPUB": single,
+	"GEOB": {
+		Description: "only one per content description",
+		Keys: func(fb FrameBody) []string {
+			return []string{string(fb.(*GeneralEncapsulationObject).ContentDescription)}
+		},
+	},
+	"USER": {
+		Description: "only one per language",
+		Keys: func(fb FrameBody) []string {
+			return []string{fb.(*TermsOfUse).Language}
+		},
+	},
+}
+
+// RuleFor finds the multiplicity rule for the frame ID.
+func RuleFor(id string) (FrameRule, error) {
+	if rule, ok := FrameRules[id]; ok {
+		return rule, nil
+	}
+	switch IDToFrameKind[id] {
+	case TextInformationKind, NonStandardTextInformationKind:
+		return single, nil
+	}
+	return FrameRule{}, errors.Errorf("there is no rule for how many %q frames may exist", id)
+}
+
+// Conflicts reports if two frames may not both exist in a tag.
+func (r FrameRule) Conflicts(a, b *Frame) bool {
+	if a.Header.ID != b.Header.ID {
+		return false
+	}
+	if r.Keys == nil {
+		return true
+	}
+	for _, ka := range r.Keys(a.Body) {
+		for _, kb := range r.Keys(b.Body) {
+			if ka == kb {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Validate checks that no two frames break the multiplicity rule of their ID.
+func (f Frames) Validate() error {
+	errs := []error{}
+	for i, a := range f {
+		rule, err := RuleFor(a.Header.ID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for j := i + 1; j < len(f); j++ {
+			if rule.Conflicts(a, f[j]) {
+				errs = append(errs, errors.Errorf("frames %d and %d are both %s (%s); %s", i, j, a.Header.ID, Descriptions[a.Header.ID], rule.Description))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package frames
+
+import "testing"
+
+func TestFrames_ApplyFrame(t *testing.T) {
+	testcases := []struct {
+		name     string
+		existing []*Frame
+		frame    *Frame
+		expected int
+	}{
+		{
+			name:     "text frames replace each other",
+			existing: []*Frame{NewFrame("TIT2", NewTextInformation("old"))},
+			frame:    NewFrame("TIT2", NewTextInformation("new")),
+			expected: 1,
+		},
+		{
+			name:     "one TXXX per description",
+			existing: []*Frame{NewFrame("TXXX", &UserDefinedTextInformation{Description: []rune("Narrator"), Value: []rune("a")})},
+			frame:    NewFrame("TXXX", &UserDefinedTextInformation{Description: []rune("Narrator"), Value: []rune("b")}),
+			expected: 1,
+		},
+		{
+			name:     "TXXX with different descriptions",
+			existing: []*Frame{NewFrame("TXXX", &UserDefinedTextInformation{Description: []rune("Narrator")})},
+			frame:    NewFrame("TXXX", &UserDefinedTextInformation{Description: []rune("Series")}),
+			expected: 2,
+		},
+		{
+			name:     "one COMM per language and description",
+			existing: []*Frame{NewFrame("COMM", &Comment{Language: "eng", ActualText: []rune("a")})},
+			frame:    NewFrame("COMM", &Comment{Language: "eng", ActualText: []rune("b")}),
+			expected: 1,
+		},
+		{
+			name:     "COMM in different languages",
+			existing: []*Frame{NewFrame("COMM", &Comment{Language: "eng"})},
+			frame:    NewFrame("COMM", &Comment{Language: "jpn"}),
+			expected: 2,
+		},
+		{
+			name:     "one USLT per language and descriptor",
+			existing: []*Frame{NewFrame("USLT", &UnsynchronizedLyrics{Language: "eng", Lyrics: "a"})},
+			frame:    NewFrame("USLT", &UnsynchronizedLyrics{Language: "eng", Lyrics: "b"}),
+			expected: 1,
+		},
+		{
+			name:     "identical PRIV frames",
+			existing: []*Frame{NewFrame("PRIV", &PrivateData{OwnerIdentifier: "owner", Data: []byte("a")})},
+			frame:    NewFrame("PRIV", &PrivateData{OwnerIdentifier: "owner", Data: []byte("a")}),
+			expected: 1,
+		},
+		{
+			name:     "PRIV frames with different data",
+			existing: []*Frame{NewFrame("PRIV", &PrivateData{OwnerIdentifier: "owner", Data: []byte("a")})},
+			frame:    NewFrame("PRIV", &PrivateData{OwnerIdentifier: "owner", Data: []byte("b")}),
+			expected: 2,
+		},
+		{
+			name:     "only one file icon",
+			existing: []*Frame{NewFrame("APIC", &AttachedPicture{PictureType: 0x01, Description: []rune("a")})},
+			frame:    NewFrame("APIC", &AttachedPicture{PictureType: 0x01, Description: []rune("b")}),
+			expected: 1,
+		},
+		{
+			name:     "several covers with different descriptions",
+			existing: []*Frame{NewFrame("APIC", &AttachedPicture{PictureType: 0x03, Description: []rune("a")})},
+			frame:    NewFrame("APIC", &AttachedPicture{PictureType: 0x03, Description: []rune("b")}),
+			expected: 2,
+		},
+		{
+			name:     "one MCDI",
+			existing: []*Frame{NewFrame("MCDI", &MusicCDIdentifier{TableOfContents: []byte("a")})},
+			frame:    NewFrame("MCDI", &MusicCDIdentifier{TableOfContents: []byte("b")}),
+			expected: 1,
+		},
+		{
+			name:     "one WOAF",
+			existing: []*Frame{NewFrame("WOAF", &Unknown{Data: []byte("https://a.example")})},
+			frame:    NewFrame("WOAF", &Unknown{Data: []byte("https://b.example")}),
+			expected: 1,
+		},
+		{
+			name:     "WOAR frames with different urls",
+			existing: []*Frame{NewFrame("WOAR", &Unknown{Data: []byte("https://a.example")})},
+			frame:    NewFrame("WOAR", &Unknown{Data: []byte("https://b.example")}),
+			expected: 2,
+		},
+		{
+			name:     "identical WCOM frames",
+			existing: []*Frame{NewFrame("WCOM", &Unknown{Data: []byte("https://a.example")})},
+			frame:    NewFrame("WCOM", &Unknown{Data: []byte("https://a.example")}),
+			expected: 1,
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := Frames(tt.existing)
+			if err := fs.ApplyFrame(tt.frame); err != nil {
+				t.Fatal(err)
+			}
+			if len(fs) != tt.expected {
+				t.Fatalf("expected %d frames, got %d", tt.expected, len(fs))
+			}
+			if err := fs.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	t.Run("replacing keeps the position of the frame", func(t *testing.T) {
+		fs := Frames{
+			NewFrame("TIT2", NewTextInformation("title")),
+			NewFrame("TALB", NewTextInformation("album")),
+		}
+		if err := fs.ApplyFrame(NewFrame("TIT2", NewTextInformation("new title"))); err != nil {
+			t.Fatal(err)
+		}
+		if fs[0].Header.ID != "TIT2" || string(fs[0].Body.(*TextInformation).Information) != "new title" {
+			t.Fatalf("expected TIT2 to be replaced in place, got %s", fs[0].Header.ID)
+		}
+	})
+
+	t.Run("unknown frames are rejected", func(t *testing.T) {
+		fs := Frames{}
+		if err := fs.ApplyFrame(NewFrame("ZZZZ", NewTextInformation("?"))); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestFrames_Validate(t *testing.T) {
+	fs := Frames{
+		NewFrame("TIT2", NewTextInformation("a")),
+		NewFrame("TIT2", NewTextInformation("b")),
+		NewFrame("COMM", &Comment{Language: "eng"}),
+		NewFrame("COMM", &Comment{Language: "jpn"}),
+	}
+	if err := fs.Validate(); err == nil {
+		t.Fatal("expected duplicate TIT2 frames to be invalid")
+	}
+	if err := fs[2:].Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
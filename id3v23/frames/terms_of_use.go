@@ -1,6 +1,13 @@
 package frames
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/chuckha/tagger/id3string"
+
+	"gitlab.com/tozd/go/errors"
+)
 
 // TermsOfUse have the ID USER
 type TermsOfUse struct {
@@ -10,11 +17,37 @@ type TermsOfUse struct {
 }
 
 func (t *TermsOfUse) UnmarshalBinary(data []byte) error {
-	t.TextEncoding = data[0]
+	enc, err := readEncoding(data, 4)
+	if err != nil {
+		return err
+	}
+	t.TextEncoding = enc
 	ptr := 1
 	t.Language = string(data[ptr : ptr+3])
 	ptr += 3
-	t.Text = string(data[ptr:])
+	text, _, err := id3string.ExtractNullTerminatedValueWithEncoding(t.TextEncoding, data[ptr:])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	t.Text = string(text)
+	return nil
+}
+
+func (t *TermsOfUse) UnmarshalJSON(data []byte) error {
+	var in struct {
+		Language string
+		Text     string
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return errors.WithStack(err)
+	}
+	lang, err := language(in.Language)
+	if err != nil {
+		return err
+	}
+	t.Language = lang
+	t.Text = in.Text
+	t.TextEncoding = textEncoding([]rune(t.Text))
 	return nil
 }
 
@@ -25,7 +58,7 @@ func (t *TermsOfUse) String() string {
 func (t *TermsOfUse) MarshalBinary() ([]byte, error) {
 	out := []byte{t.TextEncoding}
 	out = append(out, []byte(t.Language)...)
-	out = append(out, []byte(t.Text)...)
+	out = append(out, id3string.EncodeRunes(t.TextEncoding, []rune(t.Text))...)
 	return out, nil
 }
 
@@ -0,0 +1,30 @@
+package frames
+
+import "strings"
+
+// Text returns the main text of a frame body; the part a person would read or search.
+// Multiple values of a text frame are joined with a "/".
+func Text(body FrameBody) string {
+	switch b := body.(type) {
+	case *TextInformation:
+		return strings.ReplaceAll(strings.TrimRight(string(b.Information), NullSeparator), NullSeparator, "/")
+	case *UserDefinedTextInformation:
+		return string(b.Value)
+	case *UserDefinedURL:
+		return b.URL
+	case *Comment:
+		return string(b.ActualText)
+	case *UnsynchronizedLyrics:
+		return b.Lyrics
+	case *TermsOfUse:
+		return b.Text
+	case *AttachedPicture:
+		return string(b.Description)
+	case *GeneralEncapsulationObject:
+		return string(b.Filename)
+	case *PrivateData:
+		return string(b.Data)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,161 @@
+package frames
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+var (
+	positionFormat = regexp.MustCompile(`^(\d+)(?:/(\d+))?$`)
+	fourDigits     = regexp.MustCompile(`^\d{4}$`)
+	digits         = regexp.MustCompile(`^\d+$`)
+)
+
+// TextFormats are the text frames whose information has a format defined by the id3v2.3 spec.
+// Each function returns an error describing why the information does not match the format.
+var TextFormats = map[string]func(info string) error{
+	"TRCK": validatePosition,
+	"TPOS": validatePosition,
+	"TYER": validateYear,
+	"TORY": validateYear,
+	"TDAT": validateDate,
+	"TIME": validateTime,
+	"TLEN": validateNumeric,
+	"TBPM": validateNumeric,
+	"TDLY": validateNumeric,
+	"TSIZ": validateNumeric,
+}
+
+// ValidateText checks the information of the text frame id against the format the spec defines for it.
+// Frames without a defined format are always valid.
+func ValidateText(id, info string) error {
+	validate, ok := TextFormats[id]
+	if !ok {
+		return nil
+	}
+	if err := validate(info); err != nil {
+		return errors.Errorf("%s (%s): %w", id, Descriptions[id], err)
+	}
+	return nil
+}
+
+// Position is the value of a TRCK or TPOS frame, e.g. track 1 of 17.
+type Position struct {
+	Number int
+	// Total is 0 when the total is unknown.
+	Total int
+}
+
+// ParsePosition parses a "N" or "N/M" string.
+func ParsePosition(info string) (Position, error) {
+	matches := positionFormat.FindStringSubmatch(info)
+	if matches == nil {
+		return Position{}, errors.Errorf("%q is not in the format N or N/M", info)
+	}
+	n, _ := strconv.Atoi(matches[1])
+	pos := Position{Number: n}
+	if matches[2] != "" {
+		pos.Total, _ = strconv.Atoi(matches[2])
+	}
+	if pos.Number < 1 {
+		return Position{}, errors.Errorf("%q must start counting at 1", info)
+	}
+	if pos.Total != 0 && pos.Number > pos.Total {
+		return Position{}, errors.Errorf("%q is past the total", info)
+	}
+	return pos, nil
+}
+
+func (p Position) String() string {
+	if p.Total == 0 {
+		return strconv.Itoa(p.Number)
+	}
+	return fmt.Sprintf("%d/%d", p.Number, p.Total)
+}
+
+// ParseYear parses a TYER or TORY value which is always four digits.
+func ParseYear(info string) (int, error) {
+	if !fourDigits.MatchString(info) {
+		return 0, errors.Errorf("%q is not a four digit year", info)
+	}
+	year, _ := strconv.Atoi(info)
+	return year, nil
+}
+
+// ParseDate parses a TDAT value which is in the format DDMM.
+func ParseDate(info string) (day, month int, err error) {
+	if !fourDigits.MatchString(info) {
+		return 0, 0, errors.Errorf("%q is not in the format DDMM", info)
+	}
+	day, _ = strconv.Atoi(info[0:2])
+	month, _ = strconv.Atoi(info[2:4])
+	if month < 1 || month > 12 {
+		return 0, 0, errors.Errorf("%q has an invalid month", info)
+	}
+	if day < 1 || day > daysIn[month-1] {
+		return 0, 0, errors.Errorf("%q has an invalid day", info)
+	}
+	return day, month, nil
+}
+
+// daysIn is the most days each month can have since TDAT does not include the year.
+var daysIn = [12]int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// FormatDate formats a day and month as a TDAT value.
+func FormatDate(day, month int) string {
+	return fmt.Sprintf("%02d%02d", day, month)
+}
+
+// ParseTime parses a TIME value which is in the format HHMM.
+func ParseTime(info string) (hour, minute int, err error) {
+	if !fourDigits.MatchString(info) {
+		return 0, 0, errors.Errorf("%q is not in the format HHMM", info)
+	}
+	hour, _ = strconv.Atoi(info[0:2])
+	minute, _ = strconv.Atoi(info[2:4])
+	if hour > 23 || minute > 59 {
+		return 0, 0, errors.Errorf("%q is not a valid time", info)
+	}
+	return hour, minute, nil
+}
+
+// FormatTime formats an hour and minute as a TIME value.
+func FormatTime(hour, minute int) string {
+	return fmt.Sprintf("%02d%02d", hour, minute)
+}
+
+// ParseNumeric parses frames that only contain a number such as TLEN in milliseconds.
+func ParseNumeric(info string) (int, error) {
+	if !digits.MatchString(info) {
+		return 0, errors.Errorf("%q is not a number", info)
+	}
+	return strconv.Atoi(info)
+}
+
+func validatePosition(info string) error {
+	_, err := ParsePosition(info)
+	return err
+}
+
+func validateYear(info string) error {
+	_, err := ParseYear(info)
+	return err
+}
+
+func validateDate(info string) error {
+	_, _, err := ParseDate(info)
+	return err
+}
+
+func validateTime(info string) error {
+	_, _, err := ParseTime(info)
+	return err
+}
+
+func validateNumeric(info string) error {
+	_, err := ParseNumeric(info)
+	return err
+}
@@ -0,0 +1,55 @@
+package frames
+
+import "testing"
+
+func TestValidateText(t *testing.T) {
+	testcases := []struct {
+		id    string
+		info  string
+		valid bool
+	}{
+		{id: "TRCK", info: "1", valid: true},
+		{id: "TRCK", info: "1/17", valid: true},
+		{id: "TRCK", info: "01/", valid: false},
+		{id: "TRCK", info: "0/17", valid: false},
+		{id: "TRCK", info: "18/17", valid: false},
+		{id: "TPOS", info: "disc 1", valid: false},
+		{id: "TYER", info: "2023", valid: true},
+		{id: "TYER", info: "2023-05-01", valid: false},
+		{id: "TDAT", info: "0105", valid: true},
+		{id: "TDAT", info: "3102", valid: false},
+		{id: "TDAT", info: "0113", valid: false},
+		{id: "TIME", info: "2359", valid: true},
+		{id: "TIME", info: "2400", valid: false},
+		{id: "TLEN", info: "183000", valid: true},
+		{id: "TLEN", info: "3:03", valid: false},
+		{id: "TIT2", info: "anything at all", valid: true},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.id+" "+tt.info, func(t *testing.T) {
+			err := ValidateText(tt.id, tt.info)
+			if tt.valid && err != nil {
+				t.Fatalf("expected %q to be valid: %v", tt.info, err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatalf("expected %q to be invalid", tt.info)
+			}
+		})
+	}
+}
+
+func TestPosition(t *testing.T) {
+	pos, err := ParsePosition("3/12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.Number != 3 || pos.Total != 12 {
+		t.Fatalf("unexpected position %+v", pos)
+	}
+	if pos.String() != "3/12" {
+		t.Fatalf("unexpected string %q", pos.String())
+	}
+	if (Position{Number: 4}).String() != "4" {
+		t.Fatal("position without a total should not include a slash")
+	}
+}
@@ -3,12 +3,34 @@ package frames
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/chuckha/tagger/id3string"
 
 	"gitlab.com/tozd/go/errors"
 )
 
+// NullSeparator separates multiple values in a text frame.
+// id3v2.3 only defines the "/" convention for a handful of frames so everything else follows id3v2.4.
+const NullSeparator = "\x00"
+
+// SlashSeparatedFrames are the text frames that id3v2.3 says separate multiple values with a "/".
+var SlashSeparatedFrames = map[string]bool{
+	"TCOM": true,
+	"TEXT": true,
+	"TOLY": true,
+	"TOPE": true,
+	"TPE1": true,
+}
+
+// ValueSeparator returns the separator placed between multiple values of the text frame with the given id.
+func ValueSeparator(id string) string {
+	if SlashSeparatedFrames[id] {
+		return "/"
+	}
+	return NullSeparator
+}
+
 // TextInformation are all of the text frames.
 // Text frames have IDs of T000-TZZZ excluding TXXX.
 type TextInformation struct {
@@ -17,47 +39,99 @@ type TextInformation struct {
 }
 
 func NewTextInformation(info string) *TextInformation {
-	// encode to utf-16
-	val := []rune(info)
-	// check if any value is outside of ascii
-	ti := &TextInformation{Information: val}
-	if !id3string.IsASCII(val) {
-		ti.TextEncoding = 1
-	}
+	ti := &TextInformation{}
+	ti.setInformation(info)
+	return ti
+}
+
+// NewTextInformationValues creates a text frame body holding every value joined with the separator of the frame id.
+func NewTextInformationValues(id string, vals ...string) *TextInformation {
+	ti := &TextInformation{}
+	ti.SetValues(id, vals)
 	return ti
 }
 
 func (t *TextInformation) UnmarshalBinary(data []byte) error {
-	t.TextEncoding = data[0]
+	enc, err := readEncoding(data, 1)
+	if err != nil {
+		return err
+	}
+	t.TextEncoding = enc
 	// this extracts the string that is either null terminated; double null terminated; or all the bytes.
-	info, _ := id3string.ExtractValueWithEncoding(t.TextEncoding, data[1:])
+	info, _, err := id3string.ExtractValueWithEncoding(t.TextEncoding, data[1:])
+	if err != nil {
+		return errors.WithStack(err)
+	}
 	t.Information = info
 	return nil
 }
 
+// UnmarshalJSON accepts Information as a single string, a number or a list of strings.
+// A list is joined with the NullSeparator; use SetValues with the frame ID to apply the frame's own separator.
 // JSON comes in as utf-8...
 func (t *TextInformation) UnmarshalJSON(data []byte) error {
 	var in struct {
-		Information string
+		Information json.RawMessage
 	}
 	if err := json.Unmarshal(data, &in); err != nil {
 		return errors.WithStack(err)
 	}
-	if id3string.IsASCIIBytes([]byte(in.Information)) {
-		t.Information = []rune(string(in.Information))
+	var info string
+	if err := json.Unmarshal(in.Information, &info); err == nil {
+		t.setInformation(info)
 		return nil
 	}
-	t.TextEncoding = 1
-	t.Information = id3string.DecodeUTF8(in.Information)
+	var num json.Number
+	if err := json.Unmarshal(in.Information, &num); err == nil {
+		t.setInformation(num.String())
+		return nil
+	}
+	var vals []string
+	if err := json.Unmarshal(in.Information, &vals); err != nil {
+		return errors.Errorf("Information must be a string, a number or a list of strings, got %s", in.Information)
+	}
+	t.setInformation(strings.Join(vals, NullSeparator))
 	return nil
 }
 
+// Values splits the information into its individual values with the separator SetValues joins them with.
+// SlashSeparatedFrames are split on null bytes too, which other tools write following id3v2.4.
+// id3v2.3 has no way to escape a "/", so a value such as "AC/DC" in those frames reads back as two values.
+func (t *TextInformation) Values(id string) []string {
+	info := strings.TrimRight(string(t.Information), NullSeparator)
+	if info == "" {
+		return []string{}
+	}
+	vals := strings.Split(info, NullSeparator)
+	if ValueSeparator(id) == NullSeparator {
+		return vals
+	}
+	out := []string{}
+	for _, val := range vals {
+		out = append(out, strings.Split(val, ValueSeparator(id))...)
+	}
+	return out
+}
+
+// SetValues replaces the information with vals joined by the separator of the frame id.
+func (t *TextInformation) SetValues(id string, vals []string) {
+	t.setInformation(strings.Join(vals, ValueSeparator(id)))
+}
+
+func (t *TextInformation) setInformation(info string) {
+	t.Information = []rune(info)
+	t.TextEncoding = 0
+	if !id3string.IsASCII(t.Information) {
+		t.TextEncoding = 1
+	}
+}
+
 func (t *TextInformation) MarshalBinary() ([]byte, error) {
 	return append([]byte{t.TextEncoding}, id3string.EncodeRunes(t.TextEncoding, t.Information)...), nil
 }
 
 func (t *TextInformation) String() string {
-	return fmt.Sprintf("enc: %x; info: %v", t.TextEncoding, t.Information)
+	return fmt.Sprintf("enc: %x; info: %q", t.TextEncoding, string(t.Information))
 }
 
 func (t *TextInformation) Equal(t2 *TextInformation) bool {
@@ -95,4 +95,88 @@ func TestTextInformationEncoding(t *testing.T) {
 			t.Fatalf("expected information to be しろくまカフェ, got %s", string(ti.Information))
 		}
 	})
+
+	t.Run("UnmarshalJSON accepts a list of values", func(t *testing.T) {
+		ti := &TextInformation{}
+		if err := ti.UnmarshalJSON([]byte(`{"Information":["Folk","Rock"]}`)); err != nil {
+			t.Fatal(err)
+		}
+		if string(ti.Information) != "Folk\x00Rock" {
+			t.Fatalf("expected values to be null separated, got %q", string(ti.Information))
+		}
+	})
+}
+
+func TestTextInformationValues(t *testing.T) {
+	testcases := []struct {
+		name     string
+		id       string
+		info     string
+		expected []string
+	}{
+		{name: "empty", id: "TPE1", info: "", expected: []string{}},
+		{name: "single value", id: "TIT2", info: "title", expected: []string{"title"}},
+		{name: "slash separated", id: "TPE1", info: "Stephen Fry/Jim Dale", expected: []string{"Stephen Fry", "Jim Dale"}},
+		{name: "slash is not a separator for other frames", id: "TRCK", info: "1/17", expected: []string{"1/17"}},
+		{name: "null separated", id: "TCON", info: "Folk\x00Rock\x00", expected: []string{"Folk", "Rock"}},
+		{name: "null separated slash frame", id: "TCOM", info: "a\x00b/c", expected: []string{"a", "b", "c"}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewTextInformation(tt.info).Values(tt.id)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %q, got %q", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("expected %q, got %q", tt.expected, got)
+				}
+			}
+		})
+	}
+
+	t.Run("SetValues uses the frame separator", func(t *testing.T) {
+		ti := NewTextInformationValues("TPE1", "J. K. Rowling", "Stephen Fry")
+		if string(ti.Information) != "J. K. Rowling/Stephen Fry" {
+			t.Fatalf("unexpected information %q", string(ti.Information))
+		}
+		ti = NewTextInformationValues("TCON", "Folk", "しろくま")
+		if string(ti.Information) != "Folk\x00しろくま" {
+			t.Fatalf("unexpected information %q", string(ti.Information))
+		}
+		if ti.TextEncoding != 1 {
+			t.Fatalf("expected text encoding to be 1, got %d", ti.TextEncoding)
+		}
+	})
+}
+
+func TestTextInformationUnmarshalBinaryErrors(t *testing.T) {
+	testcases := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty body", data: []byte{}},
+		{name: "unknown encoding", data: []byte{3, 'a', 'b'}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (&TextInformation{}).UnmarshalBinary(tt.data); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestFrameBodiesRejectShortAndUnknownEncodings(t *testing.T) {
+	for _, id := range []string{"TXXX", "WXXX", "COMM", "USLT", "USER", "APIC", "GEOB"} {
+		for _, data := range [][]byte{{}, {3, 'e', 'n', 'g', 'a'}} {
+			body, err := NewFrameBody(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := body.UnmarshalBinary(data); err == nil {
+				t.Errorf("expected %s to reject % x", id, data)
+			}
+		}
+	}
 }
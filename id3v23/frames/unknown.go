@@ -0,0 +1,37 @@
+package frames
+
+import (
+	"fmt"
+
+	"github.com/chuckha/tagger/id3string"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// Unknown is the body of a frame tagger does not support.
+// The body is kept as is so reading a tag never fails because of one frame and writing it back loses nothing.
+type Unknown struct {
+	Data []byte
+}
+
+func (u *Unknown) UnmarshalBinary(data []byte) error {
+	u.Data = append([]byte{}, data...)
+	return nil
+}
+
+// UnmarshalJSON fails because an unsupported frame cannot be configured.
+func (u *Unknown) UnmarshalJSON(data []byte) error {
+	return errors.New("unsupported frames cannot be read from JSON")
+}
+
+func (u *Unknown) String() string {
+	return fmt.Sprintf("unsupported; size: %d bytes", len(u.Data))
+}
+
+func (u *Unknown) MarshalBinary() ([]byte, error) {
+	return u.Data, nil
+}
+
+func (u *Unknown) Equal(u2 *Unknown) bool {
+	return id3string.EqualBytes(u.Data, u2.Data)
+}
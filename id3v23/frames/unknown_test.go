@@ -0,0 +1,29 @@
+package frames
+
+import "testing"
+
+func TestUnknownFrame(t *testing.T) {
+	frame := NewFrame("RVAD", &Unknown{Data: []byte{0x03, 0x10, 0x00, 0x01}})
+	b, err := frame.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &FrameHeader{}
+	if err := header.UnmarshalBinary(b[:HeaderMinSize]); err != nil {
+		t.Fatal(err)
+	}
+	got := &Frame{Header: header}
+	if err := got.UnmarshalBinary(b[HeaderMinSize:]); err != nil {
+		t.Fatal(err)
+	}
+	u, ok := got.Body.(*Unknown)
+	if !ok {
+		t.Fatalf("expected an unknown body, got %T", got.Body)
+	}
+	if !u.Equal(frame.Body.(*Unknown)) {
+		t.Fatalf("expected %v, got %v", frame.Body, u)
+	}
+	if err := u.UnmarshalJSON([]byte(`{}`)); err == nil {
+		t.Fatal("expected unknown frames not to be configurable")
+	}
+}
@@ -1,9 +1,12 @@
 package frames
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/chuckha/tagger/id3string"
+
+	"gitlab.com/tozd/go/errors"
 )
 
 // UnsynchronizedLyrics have an ID of USLT.
@@ -15,26 +18,58 @@ type UnsynchronizedLyrics struct {
 }
 
 func (u *UnsynchronizedLyrics) UnmarshalBinary(data []byte) error {
-	u.TextEncoding = data[0]
+	enc, err := readEncoding(data, 4)
+	if err != nil {
+		return err
+	}
+	u.TextEncoding = enc
 	ptr := 1
 	u.Language = string(data[ptr : ptr+3])
 	ptr += 3
-	contentDesc, n := id3string.ExtractNullTerminatedValueWithEncoding(u.TextEncoding, data[ptr:])
+	contentDesc, n, err := id3string.ExtractNullTerminatedValueWithEncoding(u.TextEncoding, data[ptr:])
+	if err != nil {
+		return errors.WithStack(err)
+	}
 	u.ContentDescriptor = contentDesc
-	ptr += len(u.ContentDescriptor) + n
-	u.Lyrics = string(data[ptr:])
+	ptr += n
+	lyrics, _, err := id3string.ExtractNullTerminatedValueWithEncoding(u.TextEncoding, data[ptr:])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	u.Lyrics = string(lyrics)
+	return nil
+}
+
+// UnmarshalJSON reads the Lyrics as text or, given {"File": "path"}, from a file.
+func (u *UnsynchronizedLyrics) UnmarshalJSON(data []byte) error {
+	var in struct {
+		Language          string
+		ContentDescriptor string
+		Lyrics            dataValue
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return errors.WithStack(err)
+	}
+	lang, err := language(in.Language)
+	if err != nil {
+		return err
+	}
+	u.Language = lang
+	u.ContentDescriptor = []rune(in.ContentDescriptor)
+	u.Lyrics = string(in.Lyrics.Bytes)
+	u.TextEncoding = textEncoding(u.ContentDescriptor, []rune(u.Lyrics))
 	return nil
 }
 
 func (u *UnsynchronizedLyrics) String() string {
-	return fmt.Sprintf("enc: %x; lang: %q; desc: %q; lyrics: %q", u.TextEncoding, u.Language, u.ContentDescriptor, u.Lyrics)
+	return fmt.Sprintf("enc: %x; lang: %q; desc: %q; lyrics: %q", u.TextEncoding, u.Language, string(u.ContentDescriptor), u.Lyrics)
 }
 
 func (u *UnsynchronizedLyrics) MarshalBinary() ([]byte, error) {
 	out := []byte{u.TextEncoding}
 	out = append(out, []byte(u.Language)...)
 	out = append(out, id3string.EncodeRunesWithNullTerminator(u.TextEncoding, u.ContentDescriptor)...)
-	out = append(out, []byte(u.Lyrics)...)
+	out = append(out, id3string.EncodeRunes(u.TextEncoding, []rune(u.Lyrics))...)
 	return out, nil
 }
 
@@ -17,27 +17,38 @@ type UserDefinedTextInformation struct {
 }
 
 func (u *UserDefinedTextInformation) UnmarshalBinary(data []byte) error {
-	u.TextEncoding = data[0]
+	enc, err := readEncoding(data, 1)
+	if err != nil {
+		return err
+	}
+	u.TextEncoding = enc
 	ptr := 1
-	desc, n := id3string.ExtractNullTerminatedValueWithEncoding(u.TextEncoding, data[ptr:])
+	desc, n, err := id3string.ExtractNullTerminatedValueWithEncoding(u.TextEncoding, data[ptr:])
+	if err != nil {
+		return errors.WithStack(err)
+	}
 	u.Description = desc
-	ptr += len(u.Description) + n
-	u.Value = []rune(string(data[ptr:]))
-	return nil
+	ptr += n
+	u.Value, _, err = id3string.ExtractNullTerminatedValueWithEncoding(u.TextEncoding, data[ptr:])
+	return errors.WithStack(err)
 }
 
 func (u *UserDefinedTextInformation) UnmarshalJSON(data []byte) error {
-	if err := json.Unmarshal(data, u); err != nil {
-		return errors.WithStack(err)
+	var in struct {
+		Description string
+		Value       string
 	}
-	if !id3string.IsASCII(u.Description) || !id3string.IsASCII(u.Value) {
-		u.TextEncoding = 1
+	if err := json.Unmarshal(data, &in); err != nil {
+		return errors.WithStack(err)
 	}
+	u.Description = []rune(in.Description)
+	u.Value = []rune(in.Value)
+	u.TextEncoding = textEncoding(u.Description, u.Value)
 	return nil
 }
 
 func (u *UserDefinedTextInformation) String() string {
-	return fmt.Sprintf("enc: %x; desc: %q; value: %q", u.TextEncoding, u.Description, u.Value)
+	return fmt.Sprintf("enc: %x; desc: %q; value: %q", u.TextEncoding, string(u.Description), string(u.Value))
 }
 
 func (u *UserDefinedTextInformation) MarshalBinary() ([]byte, error) {
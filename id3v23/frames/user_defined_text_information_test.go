@@ -16,6 +16,14 @@ func TestUserDefinedTextInformationEncoding(t *testing.T) {
 					Value:        []rune("value"),
 				},
 			},
+			{
+				name: "unicode user defined text information",
+				input: &UserDefinedTextInformation{
+					TextEncoding: 1,
+					Description:  []rune("Narrator"),
+					Value:        []rune("ヒガアロハ"),
+				},
+			},
 		}
 
 		for _, tt := range testcases {
@@ -34,4 +42,15 @@ func TestUserDefinedTextInformationEncoding(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("UnmarshalJSON", func(t *testing.T) {
+		u := &UserDefinedTextInformation{}
+		if err := u.UnmarshalJSON([]byte(`{"Description":"Narrator","Value":"Stephen Fry"}`)); err != nil {
+			t.Fatal(err)
+		}
+		expected := &UserDefinedTextInformation{Description: []rune("Narrator"), Value: []rune("Stephen Fry")}
+		if !u.Equal(expected) {
+			t.Fatalf("\nexpected: %v\n     got: %v", expected, u)
+		}
+	})
 }
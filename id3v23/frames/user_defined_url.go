@@ -18,25 +18,36 @@ type UserDefinedURL struct {
 }
 
 func (u *UserDefinedURL) UnmarshalBinary(data []byte) error {
-	u.TextEncoding = data[0]
-	info, n := id3string.ExtractNullTerminatedValueWithEncoding(u.TextEncoding, data[1:])
+	enc, err := readEncoding(data, 1)
+	if err != nil {
+		return err
+	}
+	u.TextEncoding = enc
+	info, n, err := id3string.ExtractNullTerminatedValueWithEncoding(u.TextEncoding, data[1:])
+	if err != nil {
+		return errors.WithStack(err)
+	}
 	u.Description = info
-	u.URL = string(data[len(info)+1+n:])
+	u.URL = string(data[1+n:])
 	return nil
 }
 
 func (u *UserDefinedURL) UnmarshalJSON(data []byte) error {
-	if err := json.Unmarshal(data, u); err != nil {
-		return errors.WithStack(err)
+	var in struct {
+		Description string
+		URL         string
 	}
-	if !id3string.IsASCII(u.Description) {
-		u.TextEncoding = 1
+	if err := json.Unmarshal(data, &in); err != nil {
+		return errors.WithStack(err)
 	}
+	u.Description = []rune(in.Description)
+	u.URL = in.URL
+	u.TextEncoding = textEncoding(u.Description)
 	return nil
 }
 
 func (u *UserDefinedURL) String() string {
-	return fmt.Sprintf("enc: %x; desc: %q; url: %q", u.TextEncoding, u.Description, u.URL)
+	return fmt.Sprintf("enc: %x; desc: %q; url: %q", u.TextEncoding, string(u.Description), u.URL)
 }
 
 func (u *UserDefinedURL) MarshalBinary() ([]byte, error) {
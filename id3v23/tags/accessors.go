@@ -0,0 +1,196 @@
+package tags
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+type MissingFrameError struct {
+	id string
+}
+
+func NewMissingFrameError(id string) *MissingFrameError {
+	return &MissingFrameError{id: id}
+}
+
+func (m *MissingFrameError) Error() string {
+	return fmt.Sprintf("tag does not have a %s (%s) frame", m.id, frames.Descriptions[m.id])
+}
+
+// TextFrame returns the body of the first text frame with the given id or nil if the tag does not have one.
+func (i *ID3v2) TextFrame(id string) *frames.TextInformation {
+	for _, frame := range *i.Frames {
+		if frame.Header.ID != id {
+			continue
+		}
+		if ti, ok := frame.Body.(*frames.TextInformation); ok {
+			return ti
+		}
+	}
+	return nil
+}
+
+// FramesWithKey returns every frame named by the frame key, e.g. "TIT2" or "COMM:eng".
+func (i *ID3v2) FramesWithKey(key string) []*frames.Frame {
+	out := []*frames.Frame{}
+	for _, frame := range *i.Frames {
+		if frame.MatchesKey(key) {
+			out = append(out, frame)
+		}
+	}
+	return out
+}
+
+// text returns the information of the text frame id or a MissingFrameError.
+func (i *ID3v2) text(id string) (string, error) {
+	ti := i.TextFrame(id)
+	if ti == nil {
+		return "", errors.WithStack(NewMissingFrameError(id))
+	}
+	return string(ti.Information), nil
+}
+
+// setText validates the information against the frame's format before replacing the frame.
+func (i *ID3v2) setText(id, info string) error {
+	if err := frames.ValidateText(id, info); err != nil {
+		return err
+	}
+	return i.Frames.ApplyFrame(frames.NewFrame(id, frames.NewTextInformation(info)))
+}
+
+// Genres resolves the TCON frame into genre names.
+func (i *ID3v2) Genres() []string {
+	ti := i.TextFrame("TCON")
+	if ti == nil {
+		return []string{}
+	}
+	return frames.ParseContentType(string(ti.Information)).Names()
+}
+
+// SetGenres replaces the TCON frame. Genres may be given by name or by ID3v1 genre number.
+func (i *ID3v2) SetGenres(genres ...string) error {
+	return i.setText("TCON", frames.NewContentType(genres...).String())
+}
+
+// Track is the TRCK frame.
+func (i *ID3v2) Track() (frames.Position, error) {
+	info, err := i.text("TRCK")
+	if err != nil {
+		return frames.Position{}, err
+	}
+	return frames.ParsePosition(info)
+}
+
+// SetTrack sets the TRCK frame. A total of 0 leaves the total out.
+func (i *ID3v2) SetTrack(number, total int) error {
+	return i.setText("TRCK", frames.Position{Number: number, Total: total}.String())
+}
+
+// Disc is the TPOS frame.
+func (i *ID3v2) Disc() (frames.Position, error) {
+	info, err := i.text("TPOS")
+	if err != nil {
+		return frames.Position{}, err
+	}
+	return frames.ParsePosition(info)
+}
+
+// SetDisc sets the TPOS frame. A total of 0 leaves the total out.
+func (i *ID3v2) SetDisc(number, total int) error {
+	return i.setText("TPOS", frames.Position{Number: number, Total: total}.String())
+}
+
+// Year is the TYER frame.
+func (i *ID3v2) Year() (int, error) {
+	info, err := i.text("TYER")
+	if err != nil {
+		return 0, err
+	}
+	return frames.ParseYear(info)
+}
+
+// SetYear sets the TYER frame to the four digit year.
+func (i *ID3v2) SetYear(year int) error {
+	return i.setText("TYER", fmt.Sprintf("%04d", year))
+}
+
+// Date is the day and month of the TDAT frame.
+func (i *ID3v2) Date() (day, month int, err error) {
+	info, err := i.text("TDAT")
+	if err != nil {
+		return 0, 0, err
+	}
+	return frames.ParseDate(info)
+}
+
+// SetDate sets the TDAT frame to the day and month as DDMM.
+func (i *ID3v2) SetDate(day, month int) error {
+	return i.setText("TDAT", frames.FormatDate(day, month))
+}
+
+// Time is the hour and minute of the TIME frame.
+func (i *ID3v2) Time() (hour, minute int, err error) {
+	info, err := i.text("TIME")
+	if err != nil {
+		return 0, 0, err
+	}
+	return frames.ParseTime(info)
+}
+
+// SetTime sets the TIME frame to the hour and minute as HHMM.
+func (i *ID3v2) SetTime(hour, minute int) error {
+	return i.setText("TIME", frames.FormatTime(hour, minute))
+}
+
+// Length is the TLEN frame which is stored in milliseconds.
+func (i *ID3v2) Length() (time.Duration, error) {
+	info, err := i.text("TLEN")
+	if err != nil {
+		return 0, err
+	}
+	ms, err := frames.ParseNumeric(info)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// SetLength sets the TLEN frame to the length in whole milliseconds.
+func (i *ID3v2) SetLength(length time.Duration) error {
+	return i.setText("TLEN", strconv.FormatInt(length.Milliseconds(), 10))
+}
+
+// TemplateData is the tag as plain values for use in templates.
+// Text frames are strings keyed by their ID, e.g. "TIT2".
+// Frames that may appear more than once are maps keyed by their qualifier, e.g. TXXX by description and COMM by language.
+func (i *ID3v2) TemplateData() map[string]any {
+	data := map[string]any{}
+	for _, frame := range *i.Frames {
+		id := frame.Header.ID
+		if _, ok := frame.Body.(*frames.TextInformation); ok {
+			data[id] = frames.Text(frame.Body)
+			continue
+		}
+		var value any = frames.Text(frame.Body)
+		if ap, ok := frame.Body.(*frames.AttachedPicture); ok {
+			value = map[string]any{
+				"MIMEType":    ap.MIMEType,
+				"PictureType": frames.PictureTypes[ap.PictureType],
+				"Description": string(ap.Description),
+				"Size":        len(ap.PictureData),
+			}
+		}
+		qualified, ok := data[id].(map[string]any)
+		if !ok {
+			qualified = map[string]any{}
+			data[id] = qualified
+		}
+		qualified[frames.Qualifier(frame.Body)] = value
+	}
+	return data
+}
@@ -0,0 +1,118 @@
+package tags
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func TestID3v2_Accessors(t *testing.T) {
+	t.Run("set and get typed frames", func(t *testing.T) {
+		tag := createTag(t)
+		if err := tag.SetTrack(1, 17); err != nil {
+			t.Fatal(err)
+		}
+		if err := tag.SetDisc(2, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := tag.SetYear(2005); err != nil {
+			t.Fatal(err)
+		}
+		if err := tag.SetDate(16, 7); err != nil {
+			t.Fatal(err)
+		}
+		if err := tag.SetTime(9, 5); err != nil {
+			t.Fatal(err)
+		}
+		if err := tag.SetLength(3*time.Minute + 3*time.Second); err != nil {
+			t.Fatal(err)
+		}
+
+		track, err := tag.Track()
+		if err != nil || track.Number != 1 || track.Total != 17 {
+			t.Fatalf("unexpected track %+v: %v", track, err)
+		}
+		disc, err := tag.Disc()
+		if err != nil || disc.Number != 2 || disc.Total != 0 {
+			t.Fatalf("unexpected disc %+v: %v", disc, err)
+		}
+		if year, err := tag.Year(); err != nil || year != 2005 {
+			t.Fatalf("unexpected year %d: %v", year, err)
+		}
+		if day, month, err := tag.Date(); err != nil || day != 16 || month != 7 {
+			t.Fatalf("unexpected date %d %d: %v", day, month, err)
+		}
+		if got := string(tag.TextFrame("TIME").Information); got != "0905" {
+			t.Fatalf("unexpected time %q", got)
+		}
+		if length, err := tag.Length(); err != nil || length != 183*time.Second {
+			t.Fatalf("unexpected length %v: %v", length, err)
+		}
+	})
+
+	t.Run("invalid values are rejected", func(t *testing.T) {
+		tag := createTag(t)
+		if err := tag.SetTrack(18, 17); err == nil {
+			t.Fatal("expected an error for a track past the total")
+		}
+		if err := tag.SetDate(31, 2); err == nil {
+			t.Fatal("expected an error for an invalid date")
+		}
+	})
+
+	t.Run("missing frames", func(t *testing.T) {
+		tag := createTag(t)
+		_, err := tag.Track()
+		var e *MissingFrameError
+		if !errors.As(err, &e) {
+			t.Fatalf("expected a missing frame error, got %v", err)
+		}
+	})
+
+	t.Run("malformed frames", func(t *testing.T) {
+		tag := createTag(t)
+		if err := tag.Frames.ApplyFrame(frames.NewFrame("TRCK", frames.NewTextInformation("01/"))); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tag.Track(); err == nil {
+			t.Fatal("expected an error for a malformed track")
+		}
+	})
+
+	t.Run("frames by key", func(t *testing.T) {
+		tag := createTag(t,
+			frames.NewFrame("COMM", &frames.Comment{Language: "eng", ShortContentDescription: []rune("a")}),
+			frames.NewFrame("COMM", &frames.Comment{Language: "eng", ShortContentDescription: []rune("b")}),
+			frames.NewFrame("COMM", &frames.Comment{Language: "deu"}),
+		)
+		for key, expected := range map[string]int{"COMM": 3, "COMM:eng": 2, "COMM:eng:b": 1, "TIT2": 1, "TRCK": 0} {
+			if got := len(tag.FramesWithKey(key)); got != expected {
+				t.Errorf("expected %d %s frames, got %d", expected, key, got)
+			}
+		}
+	})
+}
+
+func TestID3v2_TemplateData(t *testing.T) {
+	tag := createTag(t,
+		frames.NewFrame("TPE1", frames.NewTextInformationValues("TPE1", "J. K. Rowling", "Stephen Fry")),
+		frames.NewFrame("TXXX", &frames.UserDefinedTextInformation{Description: []rune("Narrator"), Value: []rune("Stephen Fry")}),
+		frames.NewFrame("COMM", &frames.Comment{Language: "eng", ActualText: []rune("Unabridged")}),
+	)
+	data := tag.TemplateData()
+	if data["TIT2"] != "test2" {
+		t.Fatalf("unexpected TIT2 %v", data["TIT2"])
+	}
+	if data["TPE1"] != "J. K. Rowling/Stephen Fry" {
+		t.Fatalf("unexpected TPE1 %v", data["TPE1"])
+	}
+	if data["TXXX"].(map[string]any)["Narrator"] != "Stephen Fry" {
+		t.Fatalf("unexpected TXXX %v", data["TXXX"])
+	}
+	if data["COMM"].(map[string]any)["eng"] != "Unabridged" {
+		t.Fatalf("unexpected COMM %v", data["COMM"])
+	}
+}
@@ -0,0 +1,45 @@
+package tags
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+)
+
+// Attachment is an embedded picture or object.
+type Attachment struct {
+	Frame *frames.Frame
+	// Name is a file name for the attachment that is unique within the tag.
+	Name   string
+	Reader io.Reader
+}
+
+type attachment interface {
+	FileName() string
+	Reader() io.Reader
+}
+
+// Attachments returns every APIC and GEOB frame in the tag.
+// Attachments that would get the same name are numbered, e.g. "Cover (front) (2).jpg".
+func (i *ID3v2) Attachments() []Attachment {
+	out := []Attachment{}
+	used := map[string]bool{}
+	for _, frame := range *i.Frames {
+		body, ok := frame.Body.(attachment)
+		if !ok {
+			continue
+		}
+		name := body.FileName()
+		ext := filepath.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s (%d)%s", base, n, ext)
+		}
+		used[strings.ToLower(name)] = true
+		out = append(out, Attachment{Frame: frame, Name: name, Reader: body.Reader()})
+	}
+	return out
+}
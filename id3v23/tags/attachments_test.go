@@ -0,0 +1,51 @@
+package tags
+
+import (
+	"io"
+	"testing"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+)
+
+func TestID3v2_Attachments(t *testing.T) {
+	tag := createTag(t)
+	*tag.Frames = append(*tag.Frames,
+		&frames.Frame{
+			Header: &frames.FrameHeader{ID: "APIC"},
+			Body:   &frames.AttachedPicture{MIMEType: "image/jpeg", PictureType: 3, PictureData: []byte("front")},
+		},
+		&frames.Frame{
+			Header: &frames.FrameHeader{ID: "APIC"},
+			Body:   &frames.AttachedPicture{MIMEType: "image/jpeg", PictureType: 3, PictureData: []byte("front again")},
+		},
+		&frames.Frame{
+			Header: &frames.FrameHeader{ID: "GEOB"},
+			Body:   &frames.GeneralEncapsulationObject{Filename: []rune("notes.txt"), EncapsulatedObject: []byte("notes")},
+		},
+	)
+
+	attachments := tag.Attachments()
+	expected := []struct {
+		name string
+		data string
+	}{
+		{name: "Cover (front).jpg", data: "front"},
+		{name: "Cover (front) (2).jpg", data: "front again"},
+		{name: "notes.txt", data: "notes"},
+	}
+	if len(attachments) != len(expected) {
+		t.Fatalf("expected %d attachments, got %d", len(expected), len(attachments))
+	}
+	for i, attachment := range attachments {
+		if attachment.Name != expected[i].name {
+			t.Fatalf("expected %q, got %q", expected[i].name, attachment.Name)
+		}
+		data, err := io.ReadAll(attachment.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected[i].data {
+			t.Fatalf("expected %q, got %q", expected[i].data, data)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package tags
+
+import (
+	"fmt"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+)
+
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "+"
+	Removed ChangeKind = "-"
+	Changed ChangeKind = "~"
+)
+
+// Change is a difference in one frame between two tags.
+type Change struct {
+	Kind ChangeKind
+	Key  string
+	// Before is nil when the frame was added and After is nil when it was removed.
+	Before *frames.Frame
+	After  *frames.Frame
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s %s: %s", c.Kind, c.Key, c.After.Body)
+	case Removed:
+		return fmt.Sprintf("%s %s: %s", c.Kind, c.Key, c.Before.Body)
+	}
+	return fmt.Sprintf("%s %s: %s -> %s", c.Kind, c.Key, c.Before.Body, c.After.Body)
+}
+
+// Diff compares the frames of two tags by frame key, ignoring frame order and padding.
+// Frames that share a key are paired up by their values first and by their order second.
+func Diff(before, after *ID3v2) []Change {
+	keys := []string{}
+	olds := map[string][]*frames.Frame{}
+	news := map[string][]*frames.Frame{}
+	for _, frame := range *before.Frames {
+		key := frame.Key()
+		if _, ok := olds[key]; !ok {
+			keys = append(keys, key)
+		}
+		olds[key] = append(olds[key], frame)
+		news[key] = news[key]
+	}
+	for _, frame := range *after.Frames {
+		key := frame.Key()
+		if _, ok := news[key]; !ok {
+			keys = append(keys, key)
+		}
+		news[key] = append(news[key], frame)
+	}
+	changes := []Change{}
+	for _, key := range keys {
+		old, new := unequal(olds[key], news[key])
+		for i := 0; i < len(old) || i < len(new); i++ {
+			switch {
+			case i >= len(new):
+				changes = append(changes, Change{Kind: Removed, Key: key, Before: old[i]})
+			case i >= len(old):
+				changes = append(changes, Change{Kind: Added, Key: key, After: new[i]})
+			default:
+				changes = append(changes, Change{Kind: Changed, Key: key, Before: old[i], After: new[i]})
+			}
+		}
+	}
+	return changes
+}
+
+// unequal drops every frame that has an equal frame on the other side.
+func unequal(old, new []*frames.Frame) ([]*frames.Frame, []*frames.Frame) {
+	matched := make([]bool, len(new))
+	kept := []*frames.Frame{}
+	for _, o := range old {
+		found := false
+		for j, n := range new {
+			if !matched[j] && frames.BodiesEqual(o.Body, n.Body) {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			kept = append(kept, o)
+		}
+	}
+	added := []*frames.Frame{}
+	for j, n := range new {
+		if !matched[j] {
+			added = append(added, n)
+		}
+	}
+	return kept, added
+}
@@ -0,0 +1,40 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+)
+
+func TestDiff(t *testing.T) {
+	before := createTag(t,
+		frames.NewFrame("PRIV", &frames.PrivateData{OwnerIdentifier: "x", Data: []byte{1}}),
+		frames.NewFrame("PRIV", &frames.PrivateData{OwnerIdentifier: "x", Data: []byte{2}}),
+		frames.NewFrame("TPE1", frames.NewTextInformation("gone")),
+	)
+	after := createTag(t,
+		frames.NewFrame("TALB", frames.NewTextInformation("new")),
+		frames.NewFrame("PRIV", &frames.PrivateData{OwnerIdentifier: "x", Data: []byte{2}}),
+		frames.NewFrame("PRIV", &frames.PrivateData{OwnerIdentifier: "x", Data: []byte{1}}),
+	)
+	if err := after.Frames.ApplyFrame(frames.NewFrame("TIT2", frames.NewTextInformation("changed"))); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`~ TIT2: enc: 0; info: "test2" -> enc: 0; info: "changed"`,
+		`- TPE1: enc: 0; info: "gone"`,
+		`+ TALB: enc: 0; info: "new"`,
+	}
+	changes := Diff(before, after)
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %v", len(expected), changes)
+	}
+	for i, change := range changes {
+		if change.String() != expected[i] {
+			t.Errorf("\nexpected: %s\n     got: %s", expected[i], change)
+		}
+	}
+	if changes := Diff(before, before); len(changes) != 0 {
+		t.Fatalf("expected a tag to equal itself, got %v", changes)
+	}
+}
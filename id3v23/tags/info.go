@@ -0,0 +1,58 @@
+package tags
+
+import (
+	"fmt"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+)
+
+// Info is the stable, machine readable form of a tag.
+type Info struct {
+	Header HeaderInfo  `json:"header" yaml:"header"`
+	Frames []FrameInfo `json:"frames" yaml:"frames"`
+}
+
+type HeaderInfo struct {
+	Version           string `json:"version" yaml:"version"`
+	Size              int    `json:"size" yaml:"size"`
+	Unsynchronisation bool   `json:"unsynchronisation" yaml:"unsynchronisation"`
+	ExtendedHeader    bool   `json:"extendedHeader" yaml:"extendedHeader"`
+	Experimental      bool   `json:"experimental" yaml:"experimental"`
+}
+
+type FrameInfo struct {
+	ID          string   `json:"id" yaml:"id"`
+	Key         string   `json:"key" yaml:"key"`
+	Description string   `json:"description" yaml:"description"`
+	Size        int      `json:"size" yaml:"size"`
+	Flags       []string `json:"flags" yaml:"flags"`
+	// Text is the part of the frame a person would read; see frames.Text.
+	Text   string         `json:"text" yaml:"text"`
+	Fields map[string]any `json:"fields" yaml:"fields"`
+}
+
+// Info describes the header and every frame of the tag.
+func (i *ID3v2) Info() *Info {
+	info := &Info{
+		Header: HeaderInfo{
+			Version:           fmt.Sprintf("2.%d.%d", i.Header.MajorVersion, i.Header.Revision),
+			Size:              i.Header.Size,
+			Unsynchronisation: i.Header.Unsynchronisation,
+			ExtendedHeader:    i.Header.ExtendedHeader,
+			Experimental:      i.Header.Experimental,
+		},
+		Frames: []FrameInfo{},
+	}
+	for _, frame := range *i.Frames {
+		info.Frames = append(info.Frames, FrameInfo{
+			ID:          frame.Header.ID,
+			Key:         frame.Key(),
+			Description: frames.Descriptions[frame.Header.ID],
+			Size:        frame.Header.Size,
+			Flags:       frame.Header.FlagNames(),
+			Text:        frames.Text(frame.Body),
+			Fields:      frames.Decode(frame.Header.ID, frame.Body),
+		})
+	}
+	return info
+}
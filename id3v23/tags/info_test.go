@@ -0,0 +1,91 @@
+package tags
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func TestID3v2_Info(t *testing.T) {
+	tag := createTag(t, &frames.Frame{
+		Header: &frames.FrameHeader{ID: "APIC", ReadOnly: true},
+		Body:   &frames.AttachedPicture{MIMEType: "image/png", PictureType: 3, PictureData: []byte{1, 2, 3, 4}},
+	})
+	b, err := tag.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "a.mp3")
+	if err := os.WriteFile(file, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	read, err := NewID3v2FromFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := read.Info()
+	if info.Header.Version != "2.3.0" {
+		t.Fatalf("expected version 2.3.0, got %q", info.Header.Version)
+	}
+	if len(info.Frames) != 4 {
+		t.Fatalf("expected 4 frames, got %d", len(info.Frames))
+	}
+	expected := FrameInfo{
+		ID:          "APIC",
+		Key:         "APIC",
+		Description: frames.Descriptions["APIC"],
+		Size:        17,
+		Flags:       []string{"ReadOnly"},
+		Text:        "",
+		Fields: map[string]any{
+			"TextEncoding": "ISO-8859-1",
+			"MIMEType":     "image/png",
+			"PictureType":  "Cover (front)",
+			"Description":  "",
+			"Size":         4,
+		},
+	}
+	for _, frame := range info.Frames {
+		if frame.ID != "APIC" {
+			continue
+		}
+		if !reflect.DeepEqual(frame, expected) {
+			t.Fatalf("\nexpected: %+v\n     got: %+v", expected, frame)
+		}
+	}
+}
+
+func TestNewID3v2FromFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	testcases := []struct {
+		name  string
+		data  []byte
+		noTag bool
+	}{
+		{name: "empty file", data: []byte{}, noTag: true},
+		{name: "no tag", data: []byte("not an mp3 tag at all"), noTag: true},
+		{name: "tag cut off", data: []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 1, 0, 'T', 'I', 'T', '2'}},
+		{name: "frame cut off", data: []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 12, 'T', 'I', 'T', '2', 0, 0, 0, 9, 0, 0, 0, 'a'}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(file, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := NewID3v2FromFile(file)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			var noTag *NoID3v2IdentifierError
+			if errors.As(err, &noTag) != tt.noTag {
+				t.Fatalf("expected no tag to be %v, got %v", tt.noTag, err)
+			}
+		})
+	}
+}
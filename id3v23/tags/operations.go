@@ -0,0 +1,206 @@
+package tags
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+type OperationKind string
+
+const (
+	// Set adds the frame, replacing any frame it conflicts with.
+	Set OperationKind = "set"
+	// Remove removes every frame named by the key.
+	Remove OperationKind = "remove"
+	// RemoveMatching removes every frame named by the key whose text matches the pattern.
+	RemoveMatching OperationKind = "remove-matching"
+	// SetIfMissing adds the frame only when there is no conflicting frame or the conflicting frame is empty.
+	SetIfMissing OperationKind = "set-if-missing"
+	// AppendValue adds the values of a text frame to the values already in the tag.
+	AppendValue OperationKind = "append-value"
+)
+
+// Operation is a single change to a tag.
+type Operation struct {
+	Kind OperationKind
+	// Key names the frames to remove, e.g. "PRIV" or "COMM:eng".
+	Key string
+	// Frame is the frame to add for Set, SetIfMissing and AppendValue.
+	Frame *frames.Frame
+	// Pattern is matched against the text of each frame for RemoveMatching.
+	Pattern *regexp.Regexp
+}
+
+// ApplyOperations executes each operation in order.
+func (i *ID3v2) ApplyOperations(ops ...Operation) error {
+	for _, op := range ops {
+		if err := i.applyOperation(op); err != nil {
+			return errors.WithMessagef(err, "%s", op.Kind)
+		}
+	}
+	// Just put APIC at the end; don't change anything else
+	sort.Stable(i.Frames)
+	return nil
+}
+
+func (i *ID3v2) applyOperation(op Operation) error {
+	switch op.Kind {
+	case Set:
+		return i.Frames.ApplyFrame(op.Frame)
+	case Remove:
+		i.removeFrames(op.Key, nil)
+		return nil
+	case RemoveMatching:
+		if op.Pattern == nil {
+			return errors.Errorf("%q needs a pattern", op.Key)
+		}
+		i.removeFrames(op.Key, op.Pattern)
+		return nil
+	case SetIfMissing:
+		rule, err := frames.RuleFor(op.Frame.Header.ID)
+		if err != nil {
+			return err
+		}
+		for _, frame := range *i.Frames {
+			if rule.Conflicts(frame, op.Frame) && frames.Text(frame.Body) != "" {
+				return nil
+			}
+		}
+		return i.Frames.ApplyFrame(op.Frame)
+	case AppendValue:
+		return i.appendValues(op.Frame)
+	default:
+		return errors.Errorf("unknown operation %q", op.Kind)
+	}
+}
+
+func (i *ID3v2) removeFrames(key string, pattern *regexp.Regexp) {
+	kept := frames.Frames{}
+	for _, frame := range *i.Frames {
+		if frame.MatchesKey(key) && (pattern == nil || pattern.MatchString(frames.Text(frame.Body))) {
+			continue
+		}
+		kept = append(kept, frame)
+	}
+	*i.Frames = kept
+}
+
+// appendValues adds every value that is not already in the text frame.
+func (i *ID3v2) appendValues(frame *frames.Frame) error {
+	id := frame.Header.ID
+	incoming, ok := frame.Body.(*frames.TextInformation)
+	if !ok {
+		return errors.Errorf("%s is not a text frame; only text frames have values to append to", id)
+	}
+	existing := i.TextFrame(id)
+	if existing == nil {
+		return i.Frames.ApplyFrame(frame)
+	}
+	vals := existing.Values(id)
+	if id == "TCON" {
+		vals = frames.ParseContentType(string(existing.Information)).Names()
+	}
+	seen := map[string]bool{}
+	for _, val := range vals {
+		seen[val] = true
+	}
+	for _, val := range incoming.Values(id) {
+		if seen[val] {
+			continue
+		}
+		seen[val] = true
+		vals = append(vals, val)
+	}
+	if id == "TCON" {
+		return i.SetGenres(vals...)
+	}
+	return i.Frames.ApplyFrame(frames.NewFrame(id, frames.NewTextInformationValues(id, vals...)))
+}
+
+// CopyFrames applies the frames of src that are named by a key in only, or every frame if only is empty,
+// and not named by a key in except. A copied frame replaces the frames it conflicts with.
+func (i *ID3v2) CopyFrames(src *ID3v2, only, except []string) error {
+	for _, frame := range *src.Frames {
+		if len(only) > 0 && !matchesAny(frame, only) || matchesAny(frame, except) {
+			continue
+		}
+		if _, ok := frame.Body.(*frames.Unknown); ok {
+			// there is no rule for how many unsupported frames may exist; just avoid copying duplicates
+			if !i.hasEqualFrame(frame) {
+				*i.Frames = append(*i.Frames, frame)
+			}
+			continue
+		}
+		if err := i.Frames.ApplyFrame(frame); err != nil {
+			return errors.WithMessagef(err, "copying %s", frame.Key())
+		}
+	}
+	sort.Stable(i.Frames)
+	return nil
+}
+
+func matchesAny(frame *frames.Frame, keys []string) bool {
+	for _, key := range keys {
+		if frame.MatchesKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *ID3v2) hasEqualFrame(frame *frames.Frame) bool {
+	for _, f := range *i.Frames {
+		if f.Header.ID == frame.Header.ID && frames.BodiesEqual(f.Body, frame.Body) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResetFrames removes every frame except the unsupported ones, which cannot be configured and so could not be set again.
+func (i *ID3v2) ResetFrames() {
+	kept := frames.Frames{}
+	for _, frame := range *i.Frames {
+		if _, ok := frame.Body.(*frames.Unknown); ok {
+			kept = append(kept, frame)
+		}
+	}
+	*i.Frames = kept
+}
+
+// ReplaceFrames removes every frame except the unsupported ones and applies the operations.
+// Each unsupported frame goes back after as many supported frames as were in front of it,
+// so replacing the frames with the ones already in the tag leaves the tag as it was.
+func (i *ID3v2) ReplaceFrames(ops ...Operation) error {
+	after := []int{}
+	count := 0
+	for _, frame := range *i.Frames {
+		if _, ok := frame.Body.(*frames.Unknown); ok {
+			after = append(after, count)
+			continue
+		}
+		count++
+	}
+	i.ResetFrames()
+	unsupported := *i.Frames
+	*i.Frames = frames.Frames{}
+	if err := i.ApplyOperations(ops...); err != nil {
+		*i.Frames = append(*i.Frames, unsupported...)
+		return err
+	}
+	supported := *i.Frames
+	merged := make(frames.Frames, 0, len(supported)+len(unsupported))
+	next := 0
+	for k, frame := range unsupported {
+		end := min(after[k], len(supported))
+		merged = append(merged, supported[next:end]...)
+		merged = append(merged, frame)
+		next = end
+	}
+	*i.Frames = append(merged, supported[next:]...)
+	return nil
+}
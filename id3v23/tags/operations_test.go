@@ -0,0 +1,215 @@
+package tags
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+)
+
+func TestID3v2_ApplyOperations(t *testing.T) {
+	comment := func(lang, text string) *frames.Frame {
+		return frames.NewFrame("COMM", &frames.Comment{Language: lang, ActualText: []rune(text)})
+	}
+	private := func(owner string) *frames.Frame {
+		return frames.NewFrame("PRIV", &frames.PrivateData{OwnerIdentifier: owner, Data: []byte(owner)})
+	}
+
+	t.Run("remove every frame with an id", func(t *testing.T) {
+		tag := createTag(t, private("a"), private("b"))
+		if err := tag.ApplyOperations(Operation{Kind: Remove, Key: "PRIV"}); err != nil {
+			t.Fatal(err)
+		}
+		if len(*tag.Frames) != 3 {
+			t.Fatalf("expected only the text frames to remain, got %d frames", len(*tag.Frames))
+		}
+	})
+
+	t.Run("remove by qualifier", func(t *testing.T) {
+		tag := createTag(t, comment("eng", "a"), comment("jpn", "b"))
+		if err := tag.ApplyOperations(Operation{Kind: Remove, Key: "COMM:eng"}); err != nil {
+			t.Fatal(err)
+		}
+		for _, frame := range *tag.Frames {
+			if frame.MatchesKey("COMM:eng") {
+				t.Fatal("expected the english comment to be removed")
+			}
+		}
+		if len(*tag.Frames) != 4 {
+			t.Fatalf("expected the japanese comment to remain, got %d frames", len(*tag.Frames))
+		}
+	})
+
+	t.Run("remove matching", func(t *testing.T) {
+		tag := createTag(t, comment("eng", "Amazon.com Song ID: 1234"), comment("jpn", "keep me"))
+		err := tag.ApplyOperations(Operation{Kind: RemoveMatching, Key: "COMM", Pattern: regexp.MustCompile(`^Amazon`)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(*tag.Frames) != 4 {
+			t.Fatalf("expected one comment to be removed, got %d frames", len(*tag.Frames))
+		}
+	})
+
+	t.Run("set if missing", func(t *testing.T) {
+		tag := createTag(t)
+		err := tag.ApplyOperations(
+			Operation{Kind: SetIfMissing, Frame: frames.NewFrame("TCON", frames.NewTextInformation("Audiobook"))},
+			Operation{Kind: SetIfMissing, Frame: frames.NewFrame("TIT2", frames.NewTextInformation("ignored"))},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(tag.TextFrame("TCON").Information); got != "Audiobook" {
+			t.Fatalf("expected TCON to be set, got %q", got)
+		}
+		if got := string(tag.TextFrame("TIT2").Information); got != "test2" {
+			t.Fatalf("expected TIT2 to be left alone, got %q", got)
+		}
+	})
+
+	t.Run("set if missing replaces empty frames", func(t *testing.T) {
+		tag := createTag(t, frames.NewFrame("TCON", frames.NewTextInformation("")))
+		err := tag.ApplyOperations(Operation{Kind: SetIfMissing, Frame: frames.NewFrame("TCON", frames.NewTextInformation("Audiobook"))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(tag.TextFrame("TCON").Information); got != "Audiobook" {
+			t.Fatalf("expected TCON to be set, got %q", got)
+		}
+	})
+
+	t.Run("append value", func(t *testing.T) {
+		tag := createTag(t, frames.NewFrame("TPE1", frames.NewTextInformation("J. K. Rowling")))
+		err := tag.ApplyOperations(
+			Operation{Kind: AppendValue, Frame: frames.NewFrame("TPE1", frames.NewTextInformation("Stephen Fry"))},
+			Operation{Kind: AppendValue, Frame: frames.NewFrame("TPE1", frames.NewTextInformation("Stephen Fry"))},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(tag.TextFrame("TPE1").Information); got != "J. K. Rowling/Stephen Fry" {
+			t.Fatalf("unexpected TPE1 %q", got)
+		}
+	})
+
+	t.Run("set values read back as the same values", func(t *testing.T) {
+		tag := createTag(t)
+		body := frames.NewTextInformationValues("TPE1", "A", "B")
+		if err := tag.ApplyOperations(Operation{Kind: Set, Frame: frames.NewFrame("TPE1", body)}); err != nil {
+			t.Fatal(err)
+		}
+		out, err := tag.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		read := NewID3v2()
+		if err := read.UnmarshalBinary(out); err != nil {
+			t.Fatal(err)
+		}
+		if got := read.TextFrame("TPE1").Values("TPE1"); len(got) != 2 || got[0] != "A" || got[1] != "B" {
+			t.Fatalf("expected [A B], got %q", got)
+		}
+	})
+
+	t.Run("operations run in order", func(t *testing.T) {
+		tag := createTag(t)
+		err := tag.ApplyOperations(
+			Operation{Kind: Set, Frame: frames.NewFrame("TALB", frames.NewTextInformation("album"))},
+			Operation{Kind: Remove, Key: "TALB"},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tag.TextFrame("TALB") != nil {
+			t.Fatal("expected TALB to be removed after it was set")
+		}
+	})
+}
+
+func TestID3v2_CopyFrames(t *testing.T) {
+	src := func() *ID3v2 {
+		tag := createTag(t,
+			frames.NewFrame("TALB", frames.NewTextInformation("album")),
+			frames.NewFrame("PRIV", &frames.PrivateData{OwnerIdentifier: "shop", Data: []byte{1}}),
+		)
+		// an unsupported frame as it would be read from a file
+		*tag.Frames = append(*tag.Frames, frames.NewFrame("RVAD", &frames.Unknown{Data: []byte{1, 2}}))
+		return tag
+	}
+	testcases := []struct {
+		name         string
+		only, except []string
+		expected     []string
+	}{
+		{name: "everything", expected: []string{"TIT2", "TRCK", "TIT1", "TIT3", "TALB", "PRIV:shop", "RVAD"}},
+		{name: "only", only: []string{"TALB", "TIT2"}, expected: []string{"TIT2", "TRCK", "TALB"}},
+		{name: "except", except: []string{"PRIV", "RVAD", "TIT1", "TIT3"}, expected: []string{"TIT2", "TRCK", "TALB"}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := NewID3v2()
+			dst.SetFrames(
+				frames.NewFrame("TIT2", frames.NewTextInformation("old")),
+				frames.NewFrame("TRCK", frames.NewTextInformation("1")),
+			)
+			if err := dst.CopyFrames(src(), tt.only, tt.except); err != nil {
+				t.Fatal(err)
+			}
+			// copying twice changes nothing
+			if err := dst.CopyFrames(src(), tt.only, tt.except); err != nil {
+				t.Fatal(err)
+			}
+			keys := []string{}
+			for _, frame := range *dst.Frames {
+				keys = append(keys, frame.Key())
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.expected, ",") {
+				t.Fatalf("expected %v, got %v", tt.expected, keys)
+			}
+			if string(dst.TextFrame("TIT2").Information) != "test2" {
+				t.Fatalf("expected TIT2 to be copied, got %v", dst.TextFrame("TIT2"))
+			}
+		})
+	}
+}
+
+func TestID3v2_ReplaceFrames(t *testing.T) {
+	text := func(id, s string) Operation {
+		return Operation{Kind: Set, Key: id, Frame: frames.NewFrame(id, frames.NewTextInformation(s))}
+	}
+	testcases := []struct {
+		name     string
+		ops      []Operation
+		expected []string
+	}{
+		{name: "same frames", ops: []Operation{text("TIT2", "a"), text("TALB", "b"), text("TPE1", "c")}, expected: []string{"TIT2", "RVAD", "TALB", "TPE1", "EQUA"}},
+		{name: "fewer frames", ops: []Operation{text("TIT2", "a")}, expected: []string{"TIT2", "RVAD", "EQUA"}},
+		{name: "no frames", expected: []string{"RVAD", "EQUA"}},
+		{name: "more frames", ops: []Operation{text("TIT2", "a"), text("TALB", "b"), text("TPE1", "c"), text("TCOM", "d")}, expected: []string{"TIT2", "RVAD", "TALB", "TPE1", "EQUA", "TCOM"}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			tag := NewID3v2()
+			// unsupported frames as they would be read from a file
+			*tag.Frames = frames.Frames{
+				frames.NewFrame("TIT2", frames.NewTextInformation("old")),
+				frames.NewFrame("RVAD", &frames.Unknown{Data: []byte{1, 2}}),
+				frames.NewFrame("TALB", frames.NewTextInformation("old")),
+				frames.NewFrame("TPE1", frames.NewTextInformation("old")),
+				frames.NewFrame("EQUA", &frames.Unknown{Data: []byte{3}}),
+			}
+			if err := tag.ReplaceFrames(tt.ops...); err != nil {
+				t.Fatal(err)
+			}
+			keys := []string{}
+			for _, frame := range *tag.Frames {
+				keys = append(keys, frame.Key())
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.expected, ",") {
+				t.Fatalf("expected %v, got %v", tt.expected, keys)
+			}
+		})
+	}
+}
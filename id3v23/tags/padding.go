@@ -0,0 +1,42 @@
+package tags
+
+import (
+	"gitlab.com/tozd/go/errors"
+)
+
+// PaddingPolicy decides how much padding follows the frames of a tag that is written.
+// A tag that still fits in the space the old tag took up keeps that space, so the file does not have to be rewritten.
+type PaddingPolicy struct {
+	// Min is the padding a tag that has to grow gets.
+	Min int
+	// Max is the most padding a tag that fits keeps before it is shrunk to Min; 0 never shrinks a tag.
+	Max int
+	// Block rounds the size of a grown or shrunk tag, including its header, up to a multiple of Block; 0 does not round.
+	Block int
+}
+
+// DefaultPaddingPolicy grows a tag by at least MinimalPaddingSize in steps of MinimalPaddingSize and never shrinks it.
+var DefaultPaddingPolicy = PaddingPolicy{Min: MinimalPaddingSize, Block: MinimalPaddingSize}
+
+// Validate checks the policy can always keep a shrunk tag within Max.
+func (p PaddingPolicy) Validate() error {
+	if p.Min < 0 || p.Max < 0 || p.Block < 0 {
+		return errors.Errorf("padding must not be negative, got min %d, max %d and block %d", p.Min, p.Max, p.Block)
+	}
+	if p.Max > 0 && p.Max < p.Min+p.Block {
+		return errors.Errorf("max padding %d must be at least min padding %d plus block %d", p.Max, p.Min, p.Block)
+	}
+	return nil
+}
+
+// size returns the size of a tag of tagLen bytes with its padding when the old tag took up footprint bytes.
+func (p PaddingPolicy) size(tagLen, footprint int) int {
+	if tagLen <= footprint && (p.Max == 0 || footprint-tagLen <= p.Max) {
+		return footprint
+	}
+	size := tagLen + p.Min
+	if p.Block > 0 && size%p.Block != 0 {
+		size += p.Block - size%p.Block
+	}
+	return size
+}
@@ -0,0 +1,48 @@
+package tags
+
+import "testing"
+
+func TestPaddingPolicy_size(t *testing.T) {
+	testcases := []struct {
+		name      string
+		policy    PaddingPolicy
+		tagLen    int
+		footprint int
+		expected  int
+	}{
+		{name: "a new tag gets the minimum padding", policy: PaddingPolicy{Min: 100}, tagLen: 50, expected: 150},
+		{name: "a grown tag is rounded up to a block", policy: PaddingPolicy{Min: 100, Block: 64}, tagLen: 50, footprint: 40, expected: 192},
+		{name: "a tag that fits keeps its footprint", policy: PaddingPolicy{Min: 100}, tagLen: 50, footprint: 60, expected: 60},
+		{name: "a tag that fits exactly keeps its footprint", policy: PaddingPolicy{Min: 100}, tagLen: 50, footprint: 50, expected: 50},
+		{name: "a tag never shrinks without a maximum", policy: PaddingPolicy{Min: 100}, tagLen: 50, footprint: 100000, expected: 100000},
+		{name: "a tag within the maximum keeps its footprint", policy: PaddingPolicy{Min: 100, Max: 1000}, tagLen: 50, footprint: 1050, expected: 1050},
+		{name: "a tag over the maximum shrinks", policy: PaddingPolicy{Min: 100, Max: 1000, Block: 64}, tagLen: 50, footprint: 1051, expected: 192},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.size(tt.tagLen, tt.footprint); got != tt.expected {
+				t.Fatalf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPaddingPolicy_Validate(t *testing.T) {
+	testcases := []struct {
+		name   string
+		policy PaddingPolicy
+		valid  bool
+	}{
+		{name: "default", policy: DefaultPaddingPolicy, valid: true},
+		{name: "no padding", policy: PaddingPolicy{}, valid: true},
+		{name: "negative", policy: PaddingPolicy{Min: -1}},
+		{name: "maximum below a shrunk tag", policy: PaddingPolicy{Min: 100, Max: 150, Block: 100}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err == nil) != tt.valid {
+				t.Fatalf("expected valid to be %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package tags
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// audioSample is how much of the start and of the end of the audio is hashed.
+const audioSample = 64 << 10
+
+// Audio describes everything in a file after its ID3v2 tag.
+// Only the start and the end of the audio are hashed so describing a long audiobook stays cheap;
+// along with the size that is enough to tell a file that was re-encoded, cut or replaced.
+type Audio struct {
+	Size   int64
+	SHA256 string
+}
+
+// ReadRaw returns the bytes of the ID3v2 tag of the file, including its header and padding, and a description of the audio after it.
+// A file without a tag has an empty tag.
+func ReadRaw(file string) ([]byte, *Audio, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	defer f.Close()
+	length, err := tagLength(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	tag := make([]byte, length)
+	if _, err := io.ReadFull(f, tag); err != nil {
+		return nil, nil, errors.Errorf("%s: the tag is cut off: %w", file, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	size := info.Size() - length
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, min(size, audioSample)); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	if size > audioSample {
+		end := max(size-audioSample, audioSample)
+		if _, err := f.Seek(length+end, io.SeekStart); err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		if _, err := io.CopyN(h, f, size-end); err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+	}
+	return tag, &Audio{Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
@@ -0,0 +1,87 @@
+package tags
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadRaw(t *testing.T) {
+	dir := t.TempDir()
+	audio := []byte("not really audio, but it has to survive")
+	tagged := writeTagged(t, dir, audio)
+	original, err := os.ReadFile(tagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	untagged := filepath.Join(dir, "untagged.mp3")
+	if err := os.WriteFile(untagged, audio, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tag, tagAudio, err := ReadRaw(tagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tag, original[:len(original)-len(audio)]) {
+		t.Fatal("expected the tag to be every byte before the audio")
+	}
+	empty, untaggedAudio, err := ReadRaw(untagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty) != 0 {
+		t.Fatalf("expected no tag, got %d bytes", len(empty))
+	}
+	if *tagAudio != *untaggedAudio || tagAudio.Size != int64(len(audio)) {
+		t.Fatalf("expected the same audio, got %+v and %+v", tagAudio, untaggedAudio)
+	}
+
+	// the raw tag restores the original file exactly
+	if err := WriteRaw(tag, untagged, untagged, WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := os.ReadFile(untagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(restored, original) {
+		t.Fatal("expected the restored file to be the original file")
+	}
+}
+
+func TestReadRawSamplesLongAudio(t *testing.T) {
+	dir := t.TempDir()
+	audio := bytes.Repeat([]byte("long audio "), 3*audioSample/10)
+	describe := func(audio []byte) *Audio {
+		_, described, err := ReadRaw(writeTagged(t, dir, audio))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return described
+	}
+	original := describe(audio)
+	if original.Size != int64(len(audio)) {
+		t.Fatalf("expected the size of the audio, got %d", original.Size)
+	}
+	testcases := []struct {
+		name    string
+		at      int
+		changed bool
+	}{
+		{name: "start", at: 0, changed: true},
+		{name: "end", at: len(audio) - 1, changed: true},
+		// the middle of the audio is not read
+		{name: "middle", at: len(audio) / 2, changed: false},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := append([]byte{}, audio...)
+			changed[tt.at] ^= 0xff
+			if got := describe(changed); (*got != *original) != tt.changed {
+				t.Fatalf("expected a change to be found: %v, got %+v and %+v", tt.changed, original, got)
+			}
+		})
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 	"text/tabwriter"
@@ -21,6 +20,8 @@ const (
 type ID3v2 struct {
 	Header *Header
 	Frames *frames.Frames
+	// Warning is a problem that did not stop the tag from being read, e.g. a frames.IgnoredFramesError.
+	Warning error
 }
 
 func NewID3v2() *ID3v2 {
@@ -41,7 +42,12 @@ func NewID3v2FromFile(file string) (*ID3v2, error) {
 	defer f.Close()
 	tag := NewID3v2()
 	headerBytes := make([]byte, 10)
-	if _, err := f.Read(headerBytes); err != nil {
+	n, err := io.ReadFull(f, headerBytes)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// too short to hold a tag
+		return nil, errors.WithStack(NewNoID3v2IdentifierError(headerBytes[:n]))
+	}
+	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 	if err := tag.Header.UnmarshalBinary(headerBytes); err != nil {
@@ -49,10 +55,10 @@ func NewID3v2FromFile(file string) (*ID3v2, error) {
 	}
 	// TODO: there could be an extended header here
 	tagBytes := make([]byte, tag.Header.Size)
-	if _, err := f.Read(tagBytes); err != nil {
-		return nil, errors.WithStack(err)
+	if _, err := io.ReadFull(f, tagBytes); err != nil {
+		return nil, errors.Errorf("tag of %d bytes is cut off by the end of the file: %w", tag.Header.Size, err)
 	}
-	if err := tag.Frames.UnmarshalBinary(tagBytes); err != nil {
+	if err := tag.unmarshalFrames(tagBytes); err != nil {
 		return nil, err
 	}
 	return tag, nil
@@ -62,15 +68,51 @@ func (i *ID3v2) UnmarshalBinary(b []byte) error {
 	if err := i.Header.UnmarshalBinary(b[0:10]); err != nil {
 		return errors.WithStack(err)
 	}
-	if err := i.Frames.UnmarshalBinary(b[10:]); err != nil {
-		return errors.WithStack(err)
+	return i.unmarshalFrames(b[10:])
+}
+
+// unmarshalFrames reads the frames of the tag; frames that are ignored become the Warning.
+func (i *ID3v2) unmarshalFrames(b []byte) error {
+	err := i.Frames.UnmarshalBinary(b)
+	var ignored *frames.IgnoredFramesError
+	if errors.As(err, &ignored) {
+		i.Warning = err
+		return nil
+	}
+	return errors.WithStack(err)
+}
+
+// Clone returns a copy of the tag whose frames can be changed without changing the tag.
+func (i *ID3v2) Clone() (*ID3v2, error) {
+	b := []byte{}
+	for _, frame := range *i.Frames {
+		frameBytes, err := frame.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, frameBytes...)
 	}
-	return nil
+	header := *i.Header
+	clone := &ID3v2{Header: &header, Frames: &frames.Frames{}, Warning: i.Warning}
+	if err := clone.Frames.UnmarshalBinary(b); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return clone, nil
 }
 
-// MarshalBinaryv2 will only marshal the id3v2 tag to binary.
+// MarshalBinary marshals the tag with the DefaultPaddingPolicy.
+// The tag is expected to replace a tag of Header.Size; a tag without a size replaces nothing.
 func (i *ID3v2) MarshalBinary() ([]byte, error) {
-	originalHeaderSize := i.Header.Size
+	footprint := 0
+	if i.Header.Size > 0 {
+		footprint = i.Header.Size + 10
+	}
+	return i.MarshalWithPadding(footprint, DefaultPaddingPolicy)
+}
+
+// MarshalWithPadding marshals the tag to replace a tag that takes up footprint bytes, including its header.
+// The padding is decided by the policy. Header.Size is updated to the size of the result.
+func (i *ID3v2) MarshalWithPadding(footprint int, policy PaddingPolicy) ([]byte, error) {
 	// TODO: marshal the extra header?
 	frames := []byte{}
 	for _, frame := range *i.Frames {
@@ -80,126 +122,73 @@ func (i *ID3v2) MarshalBinary() ([]byte, error) {
 		}
 		frames = append(frames, frameBytes...)
 	}
-	i.Header.Size = len(frames)
-	// marshal header
+	out := make([]byte, policy.size(len(frames)+10, footprint))
+	copy(out[10:], frames)
+	// the size in the header counts the padding too
+	i.Header.Size = len(out) - 10
 	header, err := i.Header.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
-	tag := append(header, frames...)
+	copy(out, header)
+	return out, nil
+}
 
-	// the new tag + padding is too large for the original header
-	if len(tag)+MinimalPaddingSize > originalHeaderSize+10 {
-		out := make([]byte, len(tag)+MinimalPaddingSize)
-		copy(out, tag)
-		return out, nil
+// ApplyFrames sets the frames keyed by frame ID or frame key, e.g. "TIT2" or "TXXX:Narrator", in the order of their keys.
+func (i *ID3v2) ApplyFrames(fs map[string]frames.FrameBody) error {
+	keys := make([]string, 0, len(fs))
+	for key := range fs {
+		keys = append(keys, key)
 	}
-
-	// the leftover padding is not significantly large
-	if (originalHeaderSize+10)-len(tag) < 3*MinimalPaddingSize {
-		out := make([]byte, originalHeaderSize+10)
-		copy(out, tag)
-		return out, nil
+	sort.Strings(keys)
+	list := make([]*frames.Frame, 0, len(fs))
+	for _, key := range keys {
+		id, _ := frames.ParseKey(key)
+		list = append(list, frames.NewFrame(id, fs[key]))
 	}
-
-	// otherwise shrink the header
-	// the leftover padding is massive. Shrink the padding
-	out := make([]byte, len(tag)+MinimalPaddingSize)
-	copy(out, tag)
-	return out, nil
+	return i.SetFrames(list...)
 }
 
-func (i *ID3v2) ApplyFrames(fs map[string]frames.FrameBody) error {
-	for id, fb := range fs {
-		if err := i.Frames.ApplyFrame(frames.NewFrame(id, fb)); err != nil {
-			return err
-		}
+// SetFrames applies each frame in order following the id3v2.3 rules for how many of each frame may exist.
+func (i *ID3v2) SetFrames(fs ...*frames.Frame) error {
+	ops := make([]Operation, 0, len(fs))
+	for _, frame := range fs {
+		ops = append(ops, Operation{Kind: Set, Frame: frame})
 	}
-	// Just put APIC at the end; don't change anything else
-	sort.Sort(i.Frames)
-	return nil
+	return i.ApplyOperations(ops...)
 }
 
 func (i *ID3v2) String() string {
 	var s strings.Builder
-	w := tabwriter.NewWriter(&s, 0, 0, 1, '.', tabwriter.AlignRight|tabwriter.Debug)
-	fmt.Fprintf(w, "%s\t%s\n", "Header", i.Header.String())
+	fmt.Fprintf(&s, "Header: %s\n", i.Header.String())
+	w := tabwriter.NewWriter(&s, 0, 0, 1, ' ', 0)
 	for _, frame := range *i.Frames {
-		fmt.Fprintf(w, "%s:\t%v\n", frame.Header, frame.Body.String())
+		body := frame.Body.String()
+		if ti, ok := frame.Body.(*frames.TextInformation); ok && frame.Header.ID == "TCON" {
+			body = fmt.Sprintf("%s; genres: %q", body, frames.ParseContentType(string(ti.Information)).Names())
+		}
+		fmt.Fprintf(w, "%s:\t%v\n", frame.Header, body)
 	}
 	w.Flush()
 	return s.String()
 }
 
-// An mp3 with an ID3v2 tag contains a header and mp3 bytes.
-// Sometimes the entire file needs to be read, but sometimes it doesn't
-// what are the inputs and outputs?
-// A user says "apply this configuration to the id3v2 tag"
-// The resulting id3v2 tag could be larger or smaller than the original.
-// if the new header fits in the existing padding, simply rewrite the header with more padding
-// if the new header doesn't fit in the existing space, the entire file must be rewritten.
-
-// Write writes the tag to the dst file, using the src file as the original.
-// In cases where the src and dst are the same, there is an optimization that can happen: just rewrite the header.
-func (t *ID3v2) Write(src, dst string) error {
-	file := filepath.Base(dst)
-	// get the tag as bytes.
-	out, err := t.MarshalBinary()
-	if err != nil {
-		return errors.WithStack(err)
-	}
-
-	// Optimization case:
-	// TODO: Consider figuring out some interface to move this a layer up
-	// if the output file is the same as the input file AND the header fits, just re-write the header.
-	if src == dst && len(out) == t.Header.Size {
-		f, err := os.OpenFile(dst, os.O_RDWR, 0644)
-		if err != nil {
-			return errors.WithStack(err)
-		}
-		defer f.Close()
-		if _, err := f.Write(out); err != nil {
-			return errors.WithStack(err)
+// tagLength is the number of bytes the ID3v2 tag at the start of the file takes up, including its header.
+// A file without a tag has a tag length of 0.
+func tagLength(f io.Reader) (int64, error) {
+	headerBytes := make([]byte, 10)
+	if _, err := io.ReadFull(f, headerBytes); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return 0, nil
+	} else if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	header := &Header{}
+	if err := header.UnmarshalBinary(headerBytes); err != nil {
+		var e *NoID3v2IdentifierError
+		if errors.As(err, &e) {
+			return 0, nil
 		}
-		return nil
-	}
-
-	// normal case, write to a tmp file and then copy it back
-	tmpf, err := os.CreateTemp("", file)
-	if err != nil {
-		return errors.WithStack(err)
-	}
-	defer os.Remove(tmpf.Name())
-	// write the tag to the tmp file
-	if _, err := tmpf.Write(out); err != nil {
-		return errors.WithStack(err)
-	}
-	ogf, err := os.Open(src)
-	if err != nil {
-		return errors.WithStack(err)
-	}
-	if _, err := ogf.Seek(int64(t.Header.Size), 0); err != nil {
-		return errors.WithStack(err)
-	}
-	// copy the rest of the file
-	if _, err := io.Copy(tmpf, ogf); err != nil {
-		return errors.WithStack(err)
-	}
-	if err := ogf.Close(); err != nil {
-		return errors.WithStack(err)
-	}
-	// create the output file if it doesn't exist or open it if it does.
-	outfile, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE, 0644)
-	if err != nil {
-		return errors.WithStack(err)
-	}
-	defer outfile.Close()
-	if _, err := tmpf.Seek(0, 0); err != nil {
-		return errors.WithStack(err)
-	}
-	// copy the completed temp file to the output file
-	if _, err := io.Copy(outfile, tmpf); err != nil {
-		return errors.WithStack(err)
+		return 0, err
 	}
-	return nil
+	return int64(header.Size) + 10, nil
 }
@@ -1,10 +1,14 @@
 package tags
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/chuckha/tagger/id3v23/frames"
+
+	"gitlab.com/tozd/go/errors"
 )
 
 func TestID3v2_MarshalBinary(t *testing.T) {
@@ -126,3 +130,121 @@ func createTag(t *testing.T, fs ...*frames.Frame) *ID3v2 {
 	}
 	return tag
 }
+
+func TestID3v2_Genres(t *testing.T) {
+	tag := createTag(t)
+	if err := tag.SetGenres("Rock", "80"); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(tag.TextFrame("TCON").Information); got != "(17)(80)Folk" {
+		t.Fatalf("expected genres to be normalized, got %q", got)
+	}
+	genres := tag.Genres()
+	if len(genres) != 2 || genres[0] != "Rock" || genres[1] != "Folk" {
+		t.Fatalf("unexpected genres %q", genres)
+	}
+}
+
+func TestID3v2_GenresKeepSeveralRefinements(t *testing.T) {
+	tag := createTag(t)
+	if err := tag.SetGenres("Fantasy", "SciFi"); err != nil {
+		t.Fatal(err)
+	}
+	out, err := tag.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nt := NewID3v2()
+	if err := nt.UnmarshalBinary(out); err != nil {
+		t.Fatal(err)
+	}
+	genres := nt.Genres()
+	if len(genres) != 2 || genres[0] != "Fantasy" || genres[1] != "SciFi" {
+		t.Fatalf("expected both genres back, got %q", genres)
+	}
+}
+
+func TestID3v2_Clone(t *testing.T) {
+	tag := createTag(t)
+	// an unsupported frame as it would be read from a file
+	*tag.Frames = append(*tag.Frames, frames.NewFrame("RVAD", &frames.Unknown{Data: []byte{1, 2}}))
+	tag.Header.Size = 2048
+	clone, err := tag.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := Diff(tag, clone); len(changes) != 0 {
+		t.Fatalf("expected the same frames, got %v", changes)
+	}
+	if clone.Header.Size != 2048 {
+		t.Fatalf("expected the header to be copied, got %+v", clone.Header)
+	}
+	if err := clone.SetFrames(frames.NewFrame("TIT2", frames.NewTextInformation("changed"))); err != nil {
+		t.Fatal(err)
+	}
+	clone.Header.Size = 0
+	if string(tag.TextFrame("TIT2").Information) == "changed" || tag.Header.Size != 2048 {
+		t.Fatal("expected changing the clone to leave the tag alone")
+	}
+}
+
+func TestID3v2_UnmarshalBinaryWarnsAboutIgnoredFrames(t *testing.T) {
+	tag := createTag(t)
+	out, err := tag.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := 10
+	for _, frame := range *tag.Frames {
+		b, err := frame.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		end += len(b)
+	}
+	if len(out) < end+4 {
+		t.Fatal("expected the tag to have padding")
+	}
+	// a frame id with a null byte where the padding starts
+	copy(out[end:], "TI\x00\x00")
+	tag = NewID3v2()
+	if err := tag.UnmarshalBinary(out); err != nil {
+		t.Fatal(err)
+	}
+	var ignored *frames.IgnoredFramesError
+	if !errors.As(tag.Warning, &ignored) {
+		t.Fatalf("expected an IgnoredFramesError warning, got %v", tag.Warning)
+	}
+	if len(*tag.Frames) != 3 {
+		t.Fatalf("expected the 3 frames before the null byte, got %d", len(*tag.Frames))
+	}
+}
+
+func TestNewID3v2FromFileRejectsBadTextFrames(t *testing.T) {
+	testcases := []struct {
+		name string
+		body []byte
+	}{
+		{name: "empty text frame", body: []byte{}},
+		{name: "unknown text encoding", body: []byte{3, 'a'}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			frame := append([]byte("TIT2"), 0, 0, 0, byte(len(tt.body)), 0, 0)
+			frame = append(frame, tt.body...)
+			file := filepath.Join(t.TempDir(), "bad.mp3")
+			mp3 := append([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, byte(len(frame))}, frame...)
+			if err := os.WriteFile(file, append(mp3, 0xff, 0xfb, 0x90, 0x00), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := NewID3v2FromFile(file)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			var noTag *NoID3v2IdentifierError
+			if errors.As(err, &noTag) {
+				t.Fatalf("expected a parse error, not a missing tag: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package tags
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// BackupSuffix is appended to the name of the file a write with Backup replaces.
+const BackupSuffix = ".bak"
+
+// WriteOptions change how a tag is written.
+type WriteOptions struct {
+	// Backup keeps a copy of the file that is replaced as dst + BackupSuffix, replacing any earlier backup.
+	Backup bool
+	// Padding decides the padding of the tag; nil uses the DefaultPaddingPolicy.
+	Padding *PaddingPolicy
+	// Durable never overwrites a tag in place: the file is always replaced through a temporary file,
+	// so a crash can not leave it with half of a tag. It costs a copy of the audio for every write.
+	Durable bool
+
+	// interrupt, if set, is called between the steps of a write so tests can simulate a write that stops part way through.
+	interrupt func(step string) error
+}
+
+// interrupted reports if the write was interrupted before the step.
+func (o WriteOptions) interrupted(step string) error {
+	if o.interrupt == nil {
+		return nil
+	}
+	return o.interrupt(step)
+}
+
+// An mp3 with an ID3v2 tag contains a header and mp3 bytes.
+// The resulting id3v2 tag could be larger or smaller than the original.
+// If the new tag fits in the space the old tag took up, only the tag bytes are rewritten.
+// If it doesn't, the entire file must be rewritten.
+
+// Write writes the tag to the dst file, using the src file as the original.
+func (t *ID3v2) Write(src, dst string) error {
+	return t.WriteWithOptions(src, dst, WriteOptions{})
+}
+
+// WriteWithOptions writes the tag followed by the audio of src to dst.
+// When src and dst are the same file and the padding policy keeps the size of its tag, only the tag bytes are overwritten in place
+// unless the write is Durable.
+// Otherwise the tag and audio are written to a temporary file next to dst that is renamed over dst,
+// so dst is either left as it was or completely replaced, even if the write is interrupted.
+// Either way the file keeps the mode and modification time of the file it replaces, or of src if dst does not exist yet.
+func (t *ID3v2) WriteWithOptions(src, dst string, opts WriteOptions) error {
+	footprint, err := fileTagLength(src)
+	if err != nil {
+		return err
+	}
+	policy := DefaultPaddingPolicy
+	if opts.Padding != nil {
+		if err := opts.Padding.Validate(); err != nil {
+			return err
+		}
+		policy = *opts.Padding
+	}
+	out, err := t.MarshalWithPadding(int(footprint), policy)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !opts.Durable && filepath.Clean(src) == filepath.Clean(dst) && int64(len(out)) == footprint {
+		return writeInPlace(out, dst, opts)
+	}
+	return WriteRaw(out, src, dst, opts)
+}
+
+// writeInPlace overwrites the tag at the start of dst with a tag of exactly the same size and leaves the audio alone.
+// It is fast but not atomic: a crash while the tag is written can leave a mix of the old and the new tag, never damaged audio.
+func writeInPlace(tag []byte, dst string, opts WriteOptions) error {
+	info, err := os.Stat(dst)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if opts.Backup {
+		if err := copyFile(dst, dst+BackupSuffix); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(dst, os.O_WRONLY, 0)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	if err := opts.interrupted("tag"); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(tag, 0); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := f.Sync(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := f.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Chtimes(dst, info.ModTime(), info.ModTime()))
+}
+
+// fileTagLength is the tagLength of the file.
+func fileTagLength(file string) (int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer f.Close()
+	return tagLength(f)
+}
+
+// WriteRaw writes the tag bytes followed by the audio of src to dst the way WriteWithOptions does.
+// The tag bytes are written as they are, so they must be a complete tag including its header or empty.
+func WriteRaw(tag []byte, src, dst string, opts WriteOptions) error {
+	ogf, err := os.Open(src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer ogf.Close()
+	// the replaced file keeps its mode and modification time
+	info, err := os.Stat(dst)
+	exists := err == nil
+	if errors.Is(err, os.ErrNotExist) {
+		info, err = ogf.Stat()
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// skip the original tag, if there is one; the new tag replaces it
+	offset, err := tagLength(ogf)
+	if err != nil {
+		return err
+	}
+	if _, err := ogf.Seek(offset, io.SeekStart); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// the temporary file is in the same directory so it can be renamed over dst
+	tmpf, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tmp := tmpf.Name()
+	if err := writeTemp(tmpf, tag, ogf, info, opts); err != nil {
+		return errors.Join(err, errors.WithStack(os.Remove(tmp)))
+	}
+	if opts.Backup && exists {
+		if err := copyFile(dst, dst+BackupSuffix); err != nil {
+			return errors.Join(err, errors.WithStack(os.Remove(tmp)))
+		}
+	}
+	if err := opts.interrupted("rename"); err != nil {
+		return errors.Join(err, errors.WithStack(os.Remove(tmp)))
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return errors.Join(errors.WithStack(err), errors.WithStack(os.Remove(tmp)))
+	}
+	return syncDir(filepath.Dir(dst))
+}
+
+// writeTemp writes the tag and the audio to the temporary file, flushes it to disk and gives it the mode and modification time of info.
+func writeTemp(tmpf *os.File, tag []byte, audio io.Reader, info os.FileInfo, opts WriteOptions) error {
+	defer tmpf.Close()
+	if _, err := tmpf.Write(tag); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := opts.interrupted("audio"); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmpf, audio); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := opts.interrupted("sync"); err != nil {
+		return err
+	}
+	if err := tmpf.Chmod(info.Mode().Perm()); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := tmpf.Sync(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := tmpf.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Chtimes(tmpf.Name(), info.ModTime(), info.ModTime()))
+}
+
+// copyFile copies src to dst through a temporary file so an existing dst is never left half written.
+// dst gets the mode and modification time of src.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tmpf, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := writeTemp(tmpf, nil, in, info, WriteOptions{}); err != nil {
+		return errors.Join(err, errors.WithStack(os.Remove(tmpf.Name())))
+	}
+	if err := os.Rename(tmpf.Name(), dst); err != nil {
+		return errors.Join(errors.WithStack(err), errors.WithStack(os.Remove(tmpf.Name())))
+	}
+	return nil
+}
+
+// syncDir flushes a rename in the directory to disk.
+// Not every platform can sync a directory so only opening it is an error.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer d.Close()
+	_ = d.Sync()
+	return nil
+}
@@ -0,0 +1,268 @@
+package tags
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func TestID3v2_Write(t *testing.T) {
+	dir := t.TempDir()
+	audio := []byte("not really audio, but it has to survive")
+
+	t.Run("the header size counts the padding", func(t *testing.T) {
+		tag := createTag(t)
+		out, err := tag.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tag.Header.Size != len(out)-10 {
+			t.Fatalf("expected a header size of %d, got %d", len(out)-10, tag.Header.Size)
+		}
+	})
+
+	t.Run("a tag is added to a file without one", func(t *testing.T) {
+		src := filepath.Join(dir, "untagged.mp3")
+		if err := os.WriteFile(src, audio, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := createTag(t).Write(src, src); err != nil {
+			t.Fatal(err)
+		}
+		assertWritten(t, src, audio, 3)
+	})
+
+	t.Run("a smaller tag replaces a larger one in another file", func(t *testing.T) {
+		src := filepath.Join(dir, "big.mp3")
+		big := createTag(t, frames.NewFrame("TPE1", frames.NewTextInformation(strings.Repeat("a", 10000))))
+		tagBytes, err := big.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(src, append(tagBytes, audio...), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// the destination is longer than the result so it has to be truncated
+		dst := filepath.Join(dir, "small.mp3")
+		if err := os.WriteFile(dst, bytes.Repeat([]byte{1}, 20000), 0644); err != nil {
+			t.Fatal(err)
+		}
+		small := NewID3v2()
+		small.SetFrames(frames.NewFrame("TIT2", frames.NewTextInformation("small")))
+		if err := small.Write(src, dst); err != nil {
+			t.Fatal(err)
+		}
+		assertWritten(t, dst, audio, 1)
+	})
+}
+
+// assertWritten checks the file has a tag with the number of frames followed by the audio.
+func assertWritten(t *testing.T, file string, audio []byte, frameCount int) {
+	t.Helper()
+	tag, err := NewID3v2FromFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*tag.Frames) != frameCount {
+		t.Fatalf("expected %d frames, got %d", frameCount, len(*tag.Frames))
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest := b[tag.Header.Size+10:]; !bytes.Equal(rest, audio) {
+		t.Fatalf("expected the audio to follow the tag, got %q", rest)
+	}
+}
+
+func TestID3v2_WriteWithOptions(t *testing.T) {
+	audio := []byte("not really audio, but it has to survive")
+	mtime := time.Date(2005, 7, 16, 0, 0, 0, 0, time.UTC)
+
+	t.Run("the mode and modification time are kept", func(t *testing.T) {
+		src := writeTagged(t, t.TempDir(), audio)
+		if err := os.Chmod(src, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(src, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		if err := createTag(t).WriteWithOptions(src, src, WriteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		assertWritten(t, src, audio, 3)
+		info, err := os.Stat(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Fatalf("expected mode 0600, got %v", info.Mode().Perm())
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Fatalf("expected modification time %v, got %v", mtime, info.ModTime())
+		}
+	})
+
+	t.Run("the replaced file is backed up", func(t *testing.T) {
+		src := writeTagged(t, t.TempDir(), audio)
+		original, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := createTag(t).WriteWithOptions(src, src, WriteOptions{Backup: true}); err != nil {
+			t.Fatal(err)
+		}
+		assertWritten(t, src, audio, 3)
+		backup, err := os.ReadFile(src + BackupSuffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(backup, original) {
+			t.Fatal("expected the backup to be the original file")
+		}
+	})
+
+	t.Run("a new file is not backed up", func(t *testing.T) {
+		dir := t.TempDir()
+		src := writeTagged(t, dir, audio)
+		dst := filepath.Join(dir, "new.mp3")
+		if err := createTag(t).WriteWithOptions(src, dst, WriteOptions{Backup: true}); err != nil {
+			t.Fatal(err)
+		}
+		assertWritten(t, dst, audio, 3)
+		if _, err := os.Stat(dst + BackupSuffix); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected no backup, got %v", err)
+		}
+	})
+
+	t.Run("a tag that fits is rewritten in place", func(t *testing.T) {
+		src := writeTagged(t, t.TempDir(), audio)
+		before, err := os.Stat(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := createTag(t).WriteWithOptions(src, src, WriteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		assertWritten(t, src, audio, 3)
+		after, err := os.Stat(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !os.SameFile(before, after) || before.Size() != after.Size() {
+			t.Fatal("expected the file to be rewritten in place")
+		}
+	})
+
+	t.Run("a tag with too much padding is shrunk", func(t *testing.T) {
+		src := writeTagged(t, t.TempDir(), audio)
+		policy := &PaddingPolicy{Min: 10, Max: 100}
+		if err := createTag(t).WriteWithOptions(src, src, WriteOptions{Padding: policy}); err != nil {
+			t.Fatal(err)
+		}
+		assertWritten(t, src, audio, 3)
+		tag, err := NewID3v2FromFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		written := tag.Header.Size
+		unpadded, err := tag.MarshalWithPadding(0, PaddingPolicy{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if padding := written + 10 - len(unpadded); padding != 10 {
+			t.Fatalf("expected 10 bytes of padding, got %d", padding)
+		}
+	})
+
+	t.Run("a durable write of a tag that fits replaces the file", func(t *testing.T) {
+		src := writeTagged(t, t.TempDir(), audio)
+		before, err := os.Stat(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := createTag(t).WriteWithOptions(src, src, WriteOptions{Durable: true}); err != nil {
+			t.Fatal(err)
+		}
+		assertWritten(t, src, audio, 3)
+		after, err := os.Stat(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if os.SameFile(before, after) {
+			t.Fatal("expected the file to be replaced")
+		}
+	})
+
+	big := createTag(t, frames.NewFrame("TPE1", frames.NewTextInformation(strings.Repeat("a", 10000))))
+	interrupted := []struct {
+		name    string
+		step    string
+		tag     *ID3v2
+		durable bool
+	}{
+		// the tag does not fit in the old one so the whole file is rewritten
+		{name: "a write interrupted before audio", step: "audio", tag: big},
+		{name: "a write interrupted before sync", step: "sync", tag: big},
+		{name: "a write interrupted before rename", step: "rename", tag: big},
+		{name: "an in place write interrupted before the tag", step: "tag", tag: createTag(t)},
+		{name: "a durable write of a tag that fits interrupted before rename", step: "rename", tag: createTag(t), durable: true},
+	}
+	for _, tt := range interrupted {
+		t.Run(tt.name+" leaves the file as it was", func(t *testing.T) {
+			dir := t.TempDir()
+			src := writeTagged(t, dir, audio)
+			original, err := os.ReadFile(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			crash := errors.New("crash")
+			opts := WriteOptions{Durable: tt.durable, interrupt: func(s string) error {
+				if s == tt.step {
+					return crash
+				}
+				return nil
+			}}
+			if err := tt.tag.WriteWithOptions(src, src, opts); !errors.Is(err, crash) {
+				t.Fatalf("expected the write to be interrupted, got %v", err)
+			}
+			b, err := os.ReadFile(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, original) {
+				t.Fatal("expected the file to be unchanged")
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected the temporary file to be removed, got %d files", len(entries))
+			}
+		})
+	}
+}
+
+// writeTagged writes a file with a single frame tag followed by the audio.
+func writeTagged(t *testing.T, dir string, audio []byte) string {
+	t.Helper()
+	tag := NewID3v2()
+	tag.SetFrames(frames.NewFrame("TIT2", frames.NewTextInformation("original")))
+	b, err := tag.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "tagged.mp3")
+	if err := os.WriteFile(file, append(b, audio...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
@@ -0,0 +1,336 @@
+package tagger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/chuckha/tagger/id3v23/tags"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// A journal records the original tag of every file a template config writes so the run can be undone.
+// It is a JSON object per line, written and flushed to disk before the file it describes is changed:
+//
+//	{"Path": "/book/01.mp3", "OutFile": "/book/Chapter 1.mp3", "Created": true, "Tag": "SUQzAwAAAAAKKA...", "Audio": {"Size": 4096, "SHA256": "9f86d0..."}}
+//
+// A file that is written over another file also records the file it replaces in Replaced,
+// a copy kept in the directory next to the journal (journal.replaced for journal.jsonl).
+//
+// An interrupted run leaves a journal that ends in a cut off line; it is ignored.
+
+// JournalEntry is the original state of one file.
+type JournalEntry struct {
+	// Path is the file that was tagged and OutFile the file the result was written to; they are the same unless the file was renamed.
+	Path    string
+	OutFile string
+	// Created is set when OutFile did not exist before it was written.
+	Created bool
+	// Replaced is a copy of what OutFile held before it was written over; it is only recorded when OutFile is not Path and existed.
+	Replaced string
+	// Tag is the original tag including its padding; it is empty if the file did not have one.
+	Tag []byte
+	// Audio describes everything after the tag, which writing a tag never changes.
+	Audio *tags.Audio
+}
+
+// Journal is a journal file that is being written.
+// It is safe to record files from more than one goroutine.
+type Journal struct {
+	mu      sync.Mutex
+	f       *os.File
+	entries int
+	// replacedDir holds the copies of the files that are written over.
+	replacedDir string
+}
+
+// CreateJournal creates a new journal file. An existing file is never overwritten.
+func CreateJournal(file string) (*Journal, error) {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Journal{f: f, replacedDir: JournalReplacedDir(file)}, nil
+}
+
+// JournalReplacedDir is the directory the files a run writes over are copied to, e.g. journal.replaced for journal.jsonl.
+func JournalReplacedDir(journal string) string {
+	return strings.TrimSuffix(journal, filepath.Ext(journal)) + ".replaced"
+}
+
+// Record writes the current state of path to the journal before it is tagged and written to outFile.
+func (j *Journal) Record(path, outFile string) error {
+	entry := &JournalEntry{}
+	var err error
+	if entry.Path, err = filepath.Abs(path); err != nil {
+		return errors.WithStack(err)
+	}
+	if entry.OutFile, err = filepath.Abs(outFile); err != nil {
+		return errors.WithStack(err)
+	}
+	if entry.Tag, entry.Audio, err = tags.ReadRaw(path); err != nil {
+		return err
+	}
+	if _, err := os.Stat(outFile); errors.Is(err, os.ErrNotExist) {
+		entry.Created = true
+	} else if err != nil {
+		return errors.WithStack(err)
+	} else if entry.OutFile != entry.Path {
+		if entry.Replaced, err = j.keep(entry.OutFile); err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.f.Write(append(b, '\n')); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := j.f.Sync(); err != nil {
+		return errors.WithStack(err)
+	}
+	j.entries++
+	return nil
+}
+
+// keep copies the file to the replaced directory, flushes the copy to disk and returns its path.
+func (j *Journal) keep(file string) (string, error) {
+	if err := os.MkdirAll(j.replacedDir, 0755); err != nil {
+		return "", errors.WithStack(err)
+	}
+	src, err := os.Open(file)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer src.Close()
+	dst, err := os.CreateTemp(j.replacedDir, "*-"+filepath.Base(file))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer dst.Close()
+	kept, err := filepath.Abs(dst.Name())
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if err := dst.Sync(); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return kept, errors.WithStack(dst.Close())
+}
+
+// Entries is the number of files recorded so far.
+func (j *Journal) Entries() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.entries
+}
+
+// Name is the path of the journal file.
+func (j *Journal) Name() string {
+	return j.f.Name()
+}
+
+// Close closes the journal file; every entry is already on disk.
+func (j *Journal) Close() error {
+	return errors.WithStack(j.f.Close())
+}
+
+// LoadJournal reads every entry of a journal file in the order they were recorded.
+// The file is read a line at a time.
+func LoadJournal(file string) ([]*JournalEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+	entries := []*JournalEntry{}
+	r := bufio.NewReader(f)
+	for i := 1; ; i++ {
+		line, readErr := r.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, errors.WithStack(readErr)
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			entry := &JournalEntry{}
+			err := json.Unmarshal(line, entry)
+			// every complete line ends in a newline, so only a line cut off by an interrupted run is last
+			if err != nil && readErr != io.EOF {
+				return nil, errors.Errorf("%s: line %d: %w", file, i, err)
+			}
+			if err == nil {
+				entries = append(entries, entry)
+			}
+		}
+		if readErr == io.EOF {
+			return entries, nil
+		}
+	}
+}
+
+// Verify checks that the audio of the file the entry was written to is the audio that was recorded.
+func (e *JournalEntry) Verify() error {
+	_, audio, err := tags.ReadRaw(e.current())
+	if err != nil {
+		return err
+	}
+	if *audio != *e.Audio {
+		return errors.Errorf("the audio of %q changed since it was tagged", e.current())
+	}
+	return nil
+}
+
+// Undo restores Path to the original tag followed by the audio and puts OutFile back the way it was:
+// it is removed if the run created it and otherwise gets back the contents it had before.
+// Nothing is changed if the audio changed since it was tagged.
+func (e *JournalEntry) Undo() error {
+	if err := e.Verify(); err != nil {
+		return err
+	}
+	src := e.current()
+	if err := tags.WriteRaw(e.Tag, src, e.Path, tags.WriteOptions{}); err != nil {
+		return err
+	}
+	_, audio, err := tags.ReadRaw(e.Path)
+	if err != nil {
+		return err
+	}
+	if *audio != *e.Audio {
+		return errors.Errorf("the audio of %q changed while it was restored", e.Path)
+	}
+	if src == e.Path {
+		return nil
+	}
+	if e.Created {
+		return errors.WithStack(os.Remove(e.OutFile))
+	}
+	return restoreFile(e.OutFile, e.Replaced)
+}
+
+// current is the file that holds the audio now: OutFile unless it was never written.
+func (e *JournalEntry) current() string {
+	if e.OutFile == e.Path {
+		return e.Path
+	}
+	if _, err := os.Stat(e.OutFile); err != nil {
+		return e.Path
+	}
+	// a file that was going to be replaced but still holds what it held before was never written
+	if !e.Created {
+		if same, err := sameContents(e.OutFile, e.Replaced); err == nil && same {
+			return e.Path
+		}
+	}
+	return e.OutFile
+}
+
+// sameContents reports if both files hold the same bytes.
+func sameContents(a, b string) (bool, error) {
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer fb.Close()
+	ia, err := fa.Stat()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	ib, err := fb.Stat()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	if ia.Size() != ib.Size() {
+		return false, nil
+	}
+	bufa, bufb := make([]byte, 32<<10), make([]byte, 32<<10)
+	for {
+		n, err := io.ReadFull(fa, bufa)
+		if _, err := io.ReadFull(fb, bufb[:n]); err != nil {
+			return false, errors.WithStack(err)
+		}
+		if !bytes.Equal(bufa[:n], bufb[:n]) {
+			return false, nil
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+	}
+}
+
+// restoreFile copies the kept file back to the file through a temporary file so it is never left half written.
+// The file keeps its mode and modification time.
+func restoreFile(file, kept string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tmpf, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tmp := tmpf.Name()
+	if err := writeRestored(tmpf, kept, info); err != nil {
+		return errors.Join(err, errors.WithStack(os.Remove(tmp)))
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		return errors.Join(errors.WithStack(err), errors.WithStack(os.Remove(tmp)))
+	}
+	return nil
+}
+
+// writeRestored copies the kept file to the temporary file, flushes it to disk and gives it the mode and modification time of info.
+func writeRestored(tmpf *os.File, kept string, info os.FileInfo) error {
+	defer tmpf.Close()
+	src, err := os.Open(kept)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer src.Close()
+	if _, err := io.Copy(tmpf, src); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := tmpf.Chmod(info.Mode().Perm()); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := tmpf.Sync(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := tmpf.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Chtimes(tmpf.Name(), info.ModTime(), info.ModTime()))
+}
+
+// UndoJournal undoes every entry of the journal, the most recent first, and returns the files that were restored.
+// An entry that cannot be undone is reported and the other entries are still undone.
+func UndoJournal(entries []*JournalEntry) ([]string, error) {
+	restored := []string{}
+	errs := []error{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := entries[i].Undo(); err != nil {
+			errs = append(errs, errors.WithMessagef(err, "%s", entries[i].Path))
+			continue
+		}
+		restored = append(restored, entries[i].Path)
+	}
+	return restored, errors.Join(errs...)
+}
@@ -0,0 +1,201 @@
+package tagger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+	"github.com/chuckha/tagger/id3v23/tags"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+	inPlace := filepath.Join(dir, "a.mp3")
+	renamed := filepath.Join(dir, "b.mp3")
+	writeMP3(t, inPlace)
+	writeMP3(t, renamed)
+	tag := tags.NewID3v2()
+	if err := tag.SetFrames(frames.NewFrame("TIT2", frames.NewTextInformation("original"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := tag.Write(inPlace, inPlace); err != nil {
+		t.Fatal(err)
+	}
+	originals := map[string][]byte{}
+	for _, file := range []string{inPlace, renamed} {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		originals[file] = b
+	}
+
+	framesTemplate := writeFile(t, t.TempDir(), "config.json.tmpl", `{"Frames": {"TIT2": {"Information": "{{.name}} {{.special.count}}"}}}`)
+	tc := newTemplateConfig(t, `{
+    "Rules": [
+        {"FilePattern": "a.mp3", "FramesTemplate": "`+framesTemplate+`"},
+        {"FilePattern": "%name%.mp3", "OutputFilePattern": "`+dir+`/renamed {{.name}}.mp3", "FramesTemplate": "`+framesTemplate+`"}
+    ],
+    "Behavior": {"missing-id3v2-tag": "add"}
+}`)
+	journalFile := filepath.Join(t.TempDir(), "journal.jsonl")
+	journal, err := CreateJournal(journalFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc.SetJournal(journal)
+	if err := tc.ProcessDir(dir); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	renamedTo := filepath.Join(dir, "renamed b.mp3")
+	if _, err := os.Stat(renamedTo); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("a journal is never overwritten", func(t *testing.T) {
+		if _, err := CreateJournal(journalFile); !errors.Is(err, os.ErrExist) {
+			t.Fatalf("expected the journal to exist, got %v", err)
+		}
+	})
+
+	t.Run("a cut off last line is ignored", func(t *testing.T) {
+		b, err := os.ReadFile(journalFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cutOff := writeFile(t, t.TempDir(), "cut.jsonl", string(b)+`{"Path": "/som`)
+		entries, err := LoadJournal(cutOff)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("expected 2 entries, got %d", len(entries))
+		}
+	})
+
+	entries, err := LoadJournal(journalFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("changed audio is not restored", func(t *testing.T) {
+		b, err := os.ReadFile(renamedTo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(renamedTo, append(b, 0), 0644); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if err := os.WriteFile(renamedTo, b, 0644); err != nil {
+				t.Fatal(err)
+			}
+		})
+		for _, entry := range entries {
+			if entry.OutFile == renamedTo {
+				if err := entry.Undo(); err == nil {
+					t.Fatal("expected the undo to fail")
+				}
+			}
+		}
+		if _, err := os.Stat(renamedTo); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	restored, err := UndoJournal(entries)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(restored) != 2 {
+		t.Fatalf("expected 2 restored files, got %v", restored)
+	}
+	for file, original := range originals {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, original) {
+			t.Fatalf("expected %q to be restored exactly", file)
+		}
+	}
+	if _, err := os.Stat(renamedTo); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the renamed file to be removed, got %v", err)
+	}
+}
+
+func TestJournalRestoresReplacedOutFile(t *testing.T) {
+	for _, written := range []bool{true, false} {
+		name := "written"
+		if !written {
+			name = "never written"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "a.mp3")
+			writeMP3(t, path)
+			original, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outFile := writeFile(t, dir, "b.mp3", "an existing file")
+			journalFile := filepath.Join(t.TempDir(), "journal.jsonl")
+			journal, err := CreateJournal(journalFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := journal.Record(path, outFile); err != nil {
+				t.Fatal(err)
+			}
+			if err := journal.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if written {
+				tag := tags.NewID3v2()
+				if err := tag.SetFrames(frames.NewFrame("TIT2", frames.NewTextInformation("renamed"))); err != nil {
+					t.Fatal(err)
+				}
+				if err := tag.Write(path, outFile); err != nil {
+					t.Fatal(err)
+				}
+			}
+			entries, err := LoadJournal(journalFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the replaced file is copied next to the journal instead of into it
+			if filepath.Dir(entries[0].Replaced) != JournalReplacedDir(journalFile) {
+				t.Fatalf("expected a copy in %q, got %q", JournalReplacedDir(journalFile), entries[0].Replaced)
+			}
+			if kept, err := os.ReadFile(entries[0].Replaced); err != nil || string(kept) != "an existing file" {
+				t.Fatalf("expected a copy of the replaced file, got %q: %v", kept, err)
+			}
+			if _, err := UndoJournal(entries); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			for file, expected := range map[string]string{path: string(original), outFile: "an existing file"} {
+				b, err := os.ReadFile(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != expected {
+					t.Fatalf("expected %q to be restored exactly", file)
+				}
+			}
+			files, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 2 {
+				t.Fatalf("expected no temporary files to be left, got %d files", len(files))
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package tagger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// The FramesTemplate renders a config but the values put into it are titles, names and other free text.
+// Escaping saves template authors from escaping quotes and backslashes themselves:
+// every action that prints something is escaped as the contents of a double quoted string of the format the template renders.
+// The output of an action whose last command is raw or one of the escape functions is left alone.
+// Escaping is on by default; a rule that prints JSON or quotes values itself sets Escape to false.
+
+const (
+	jsonEscapeFunc = "jsonEscape"
+	yamlEscapeFunc = "yamlEscape"
+	tomlEscapeFunc = "tomlEscape"
+	rawFunc        = "raw"
+)
+
+// escapeFuncs are available in every FramesTemplate so values can also be escaped by hand.
+var escapeFuncs = template.FuncMap{
+	jsonEscapeFunc: jsonEscape,
+	yamlEscapeFunc: yamlEscape,
+	tomlEscapeFunc: tomlEscape,
+	rawFunc:        raw,
+}
+
+// escapeFuncFor is the escape function for the contents of a double quoted string in the format.
+func escapeFuncFor(format Format) string {
+	switch format {
+	case YAML:
+		return yamlEscapeFunc
+	case TOML:
+		return tomlEscapeFunc
+	default:
+		return jsonEscapeFunc
+	}
+}
+
+// parseFramesTemplate parses a template that renders a config in the format.
+// With escape every action that prints a value is escaped for the format.
+func parseFramesTemplate(name, text string, format Format, escape bool, funcs template.FuncMap) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Funcs(escapeFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if !escape {
+		return tmpl, nil
+	}
+	// a tree may be known by more than one name but must only be escaped once
+	escaped := map[*parse.Tree]bool{}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && !escaped[t.Tree] {
+			escaped[t.Tree] = true
+			escapeActions(t.Tree, t.Tree.Root, escapeFuncFor(format))
+		}
+	}
+	return tmpl, nil
+}
+
+// escapeActions appends the escape function fn to the pipeline of every action in the node.
+func escapeActions(tree *parse.Tree, node parse.Node, fn string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeActions(tree, child, fn)
+		}
+	case *parse.ActionNode:
+		// actions that declare or assign a variable do not print anything
+		if len(n.Pipe.Decl) > 0 || endsWith(n.Pipe, rawFunc) || endsWithEscape(n.Pipe) {
+			return
+		}
+		ident := parse.NewIdentifier(fn).SetTree(tree).SetPos(n.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{ident},
+		})
+	case *parse.IfNode:
+		escapeActions(tree, n.List, fn)
+		escapeActions(tree, n.ElseList, fn)
+	case *parse.RangeNode:
+		escapeActions(tree, n.List, fn)
+		escapeActions(tree, n.ElseList, fn)
+	case *parse.WithNode:
+		escapeActions(tree, n.List, fn)
+		escapeActions(tree, n.ElseList, fn)
+	case *parse.TemplateNode:
+		// the called template prints its own output and is escaped on its own,
+		// so its output is not escaped again here
+	}
+}
+
+// endsWithEscape reports if the last command of the pipeline already escapes its value.
+func endsWithEscape(pipe *parse.PipeNode) bool {
+	return endsWith(pipe, jsonEscapeFunc) || endsWith(pipe, yamlEscapeFunc) || endsWith(pipe, tomlEscapeFunc)
+}
+
+// endsWith reports if the last command of the pipeline is a call to the function name.
+func endsWith(pipe *parse.PipeNode, name string) bool {
+	if len(pipe.Cmds) == 0 {
+		return false
+	}
+	last := pipe.Cmds[len(pipe.Cmds)-1]
+	if len(last.Args) == 0 {
+		return false
+	}
+	ident, ok := last.Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == name
+}
+
+// jsonEscape formats the value and escapes it so it can be placed between the quotes of a JSON string.
+func jsonEscape(in any) (string, error) {
+	if in == nil {
+		return "", nil
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(fmt.Sprint(in)); err != nil {
+		return "", errors.WithStack(err)
+	}
+	// drop the quotes and the newline the encoder adds
+	out := bytes.TrimSpace(b.Bytes())
+	return string(out[1 : len(out)-1]), nil
+}
+
+// yamlEscape escapes the value so it can be placed between the double quotes of a YAML string.
+// YAML understands the escapes of JSON but also needs DEL and the C1 control characters other than NEL escaped.
+func yamlEscape(in any) (string, error) {
+	return escapeWith(in, func(r rune) bool {
+		return r == 0x7f || (0x80 <= r && r <= 0x9f && r != 0x85)
+	})
+}
+
+// tomlEscape escapes the value so it can be placed between the quotes of a TOML basic string.
+// TOML understands the escapes of JSON but also needs DEL escaped.
+func tomlEscape(in any) (string, error) {
+	return escapeWith(in, func(r rune) bool {
+		return r == 0x7f
+	})
+}
+
+// escapeWith escapes the value like jsonEscape and also writes every rune for which extra is true as a \u escape.
+func escapeWith(in any, extra func(rune) bool) (string, error) {
+	out, err := jsonEscape(in)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, r := range out {
+		if extra(r) {
+			fmt.Fprintf(&b, `\u%04x`, r)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), nil
+}
+
+// raw marks the output of an action as text that must not be escaped.
+func raw(in any) any {
+	return in
+}
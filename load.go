@@ -0,0 +1,42 @@
+package tagger
+
+import (
+	"os"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// LoadConfig reads a config file in the format of its extension.
+func LoadConfig(file string) (*Config, error) {
+	b, err := readConfigFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cfg := NewConfig()
+	if err := cfg.UnmarshalJSON(b); err != nil {
+		return nil, errors.WithMessagef(err, "%s", file)
+	}
+	return cfg, nil
+}
+
+// LoadTemplateConfig reads a template config file in the format of its extension.
+func LoadTemplateConfig(file string) (*TemplateConfig, error) {
+	b, err := readConfigFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cfg := NewTemplateConfig()
+	if err := cfg.UnmarshalJSON(b); err != nil {
+		return nil, errors.WithMessagef(err, "%s", file)
+	}
+	return cfg, nil
+}
+
+// readConfigFile reads a config file as JSON.
+func readConfigFile(file string) ([]byte, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ToJSON(file, FormatOf(file), b)
+}
@@ -0,0 +1,82 @@
+package tagger
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chuckha/tagger/id3v23/tags"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	testcases := map[string]string{
+		"config.json": `{"Frames": {"TIT2": {"Information": "Spinner's End"}, "TPE1": {"Information": ["Stephen Fry", "Jim Dale"]}}}`,
+		"config.yaml": `Frames:
+  TIT2: {Information: Spinner's End}
+  TPE1: {Information: [Stephen Fry, Jim Dale]}
+`,
+		"config.toml": `[Frames.TIT2]
+Information = "Spinner's End"
+
+[Frames.TPE1]
+Information = ["Stephen Fry", "Jim Dale"]
+`,
+	}
+	for name, contents := range testcases {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := LoadConfig(writeFile(t, dir, name, contents))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			tag := tags.NewID3v2()
+			if err := cfg.Apply(tag); err != nil {
+				t.Fatal(err)
+			}
+			expectText(t, tag, "TIT2", "Spinner's End")
+			expectText(t, tag, "TPE1", "Stephen Fry/Jim Dale")
+		})
+	}
+}
+
+func TestLoadConfigErrorsNameTheFile(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "config.yaml", "Frames:\n  TRCK: {Information: one}\n")
+	_, err := LoadConfig(file)
+	if err == nil || !strings.Contains(err.Error(), file) || !strings.Contains(err.Error(), "TRCK") {
+		t.Fatalf("expected an error naming the file and frame, got %v", err)
+	}
+}
+
+func TestLoadTemplateConfigWithYAMLFramesTemplate(t *testing.T) {
+	dir := t.TempDir()
+	writeMP3(t, filepath.Join(dir, "Track 06.mp3"))
+	framesTemplate := writeFile(t, dir, "config.yaml.tmpl", `Frames:
+  TRCK: {Information: "{{.track | int}}"}
+  TIT2: {Information: "{{.userData.title}}"}
+`)
+	cfg := writeFile(t, dir, "template-config.toml", `FilePattern = "Track $track$.mp3"
+FramesTemplate = "`+framesTemplate+`"
+
+[UserData]
+title = "Draco's \"Detour\""
+
+[Behavior]
+missing-id3v2-tag = "add"
+`)
+	tc, err := LoadTemplateConfig(cfg)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	jobs, _, err := tc.plan(dir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("expected one file, got %d", len(jobs))
+	}
+	if err := jobs[0].config.Apply(jobs[0].tag); err != nil {
+		t.Fatal(err)
+	}
+	expectText(t, jobs[0].tag, "TRCK", "6")
+	expectText(t, jobs[0].tag, "TIT2", `Draco's "Detour"`)
+}
@@ -0,0 +1,78 @@
+package tagger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// RawPatternPrefix marks a FilePattern that is a regular expression and is used as is.
+const RawPatternPrefix = "re:"
+
+// patternToken finds the captures of a FilePattern:
+// $name$ and $name:2$ capture digits, %name% and %name?% capture words and {year} captures a four digit year.
+// $$ and %% are a literal $ and %.
+var patternToken = regexp.MustCompile(`\$\$|%%|\$(\w+)(?::(\d+))?\$|%(\w+)(\?)?%|\{year\}`)
+
+// CompilePattern turns the easier to read FilePattern into a regular expression.
+// Everything that is not a capture is matched literally.
+// The pattern matches whole path components from the end of a path, so "Disc 1/$track$.mp3" matches "Box Set/Disc 1/01.mp3" but not "Disc 11/01.mp3".
+// Words never span directories and are matched as short as possible.
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	if raw, ok := strings.CutPrefix(pattern, RawPatternPrefix); ok {
+		re, err := regexp.Compile(raw)
+		if err != nil {
+			return nil, errors.Errorf("pattern %q: %w", pattern, err)
+		}
+		return re, nil
+	}
+	var b strings.Builder
+	b.WriteString(`(?:^|/)`)
+	seen := map[string]bool{}
+	capture := func(name, expr string) error {
+		if seen[name] && name != "ignore" {
+			return errors.Errorf("pattern %q: the variable %q must be unique", pattern, name)
+		}
+		seen[name] = true
+		fmt.Fprintf(&b, `(?P<%s>%s)`, name, expr)
+		return nil
+	}
+	last := 0
+	for _, m := range patternToken.FindAllStringSubmatchIndex(pattern, -1) {
+		b.WriteString(regexp.QuoteMeta(pattern[last:m[0]]))
+		last = m[1]
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return pattern[m[2*i]:m[2*i+1]]
+		}
+		var err error
+		switch token := group(0); {
+		case token == "$$", token == "%%":
+			b.WriteString(regexp.QuoteMeta(token[:1]))
+		case token == "{year}":
+			err = capture("year", `\d{4}`)
+		case group(1) != "" && group(2) != "":
+			err = capture(group(1), fmt.Sprintf(`\d{%s}`, group(2)))
+		case group(1) != "":
+			err = capture(group(1), `\d+`)
+		case group(4) != "":
+			err = capture(group(3), `[^/]*?`)
+		default:
+			err = capture(group(3), `[^/]+?`)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	b.WriteString(regexp.QuoteMeta(pattern[last:]))
+	b.WriteString(`$`)
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, errors.Errorf("pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
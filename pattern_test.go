@@ -0,0 +1,84 @@
+package tagger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompilePattern(t *testing.T) {
+	testcases := []struct {
+		name     string
+		pattern  string
+		path     string
+		expected map[string]any
+	}{
+		{name: "digits", pattern: "Track $track$.mp3", path: "/music/Track 10.mp3", expected: map[string]any{"track": "10"}},
+		{name: "literal dots", pattern: "Vol. $vol$.mp3", path: "Vol. 1.mp3", expected: map[string]any{"vol": "1"}},
+		{name: "literal dots do not match anything", pattern: "Vol. $vol$.mp3", path: "Vol_ 1_mp3"},
+		{name: "brackets and plus", pattern: "[Disc+$disc$] (%title%).mp3", path: "[Disc+2] (The Cave).mp3", expected: map[string]any{"disc": "2", "title": "The Cave"}},
+		{name: "anchored at the end", pattern: "$track$.mp3", path: "01.mp3.bak"},
+		{name: "anchored at a directory", pattern: "Disc 1/$track$.mp3", path: "Box Set/Disc 11/01.mp3"},
+		{name: "directories", pattern: "Disc $disc$/$track$.mp3", path: "Box Set/Disc 1/01.mp3", expected: map[string]any{"disc": "1", "track": "01"}},
+		{name: "words are not greedy", pattern: "%author% - %title%.mp3", path: "Aesop - The Fox - The Grapes.mp3", expected: map[string]any{"author": "Aesop", "title": "The Fox - The Grapes"}},
+		{name: "words do not span directories", pattern: "%title%.mp3", path: "Aesop/The Fox.mp3", expected: map[string]any{"title": "The Fox"}},
+		{name: "fixed width digits", pattern: "$disc:1$$track:2$.mp3", path: "112.mp3", expected: map[string]any{"disc": "1", "track": "12"}},
+		{name: "fixed width digits do not match more", pattern: "$track:2$.mp3", path: "123.mp3"},
+		{name: "optional word", pattern: "$track$%extra?%.mp3", path: "01.mp3", expected: map[string]any{"track": "01", "extra": ""}},
+		{name: "optional word present", pattern: "$track$%extra?%.mp3", path: "01b.mp3", expected: map[string]any{"track": "01", "extra": "b"}},
+		{name: "year", pattern: "%album% ({year}).mp3", path: "Fables (1912).mp3", expected: map[string]any{"album": "Fables", "year": "1912"}},
+		{name: "year is four digits", pattern: "({year}).mp3", path: "(12).mp3"},
+		{name: "escaped", pattern: "$$5 %% off $n$.mp3", path: "$5 % off 1.mp3", expected: map[string]any{"n": "1"}},
+		{name: "ignored", pattern: "%ignore% - $track$.mp3", path: "x - 1.mp3", expected: map[string]any{"track": "1"}},
+		{name: "raw", pattern: `re:(?P<track>\d+)\.mp3$`, path: "a/b/c01.mp3", expected: map[string]any{"track": "01"}},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := CompilePattern(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rule := &Rule{FilePattern: re}
+			got, ok := rule.Match(tt.path)
+			if tt.expected == nil {
+				if ok {
+					t.Fatalf("expected %q not to match %s, got %v", tt.path, re, got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("expected %q to match %s", tt.path, re)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for k, v := range tt.expected {
+				if got[k] != v {
+					t.Fatalf("expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCompilePatternErrors(t *testing.T) {
+	testcases := []struct {
+		name     string
+		pattern  string
+		expected string
+	}{
+		{name: "duplicate", pattern: "$track$ - $track$.mp3", expected: `the variable "track" must be unique`},
+		{name: "duplicate year", pattern: "{year} - $year$.mp3", expected: `the variable "year" must be unique`},
+		{name: "bad regex", pattern: "re:(", expected: "missing closing )"},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompilePattern(tt.pattern)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q in %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package tagger
+
+import (
+	"sync"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// parallel calls fn for every index below n, running at most jobs calls at the same time.
+// The errors are joined in index order so they never depend on which call finished first.
+func parallel(n, jobs int, fn func(i int) error) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+	errs := make([]error, n)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,115 @@
+package tagger
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"text/template"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// MatchMode decides what happens when a file matches more than one rule.
+type MatchMode string
+
+const (
+	// FirstMatch applies only the first rule that matches a file.
+	FirstMatch MatchMode = "first"
+	// AllMatch applies every rule that matches a file in order.
+	AllMatch MatchMode = "all"
+)
+
+// Rule generates the config of every file that matches its FilePattern.
+type Rule struct {
+	// Name identifies the rule in errors and reports.
+	Name string
+	// Pattern is the FilePattern as it was written.
+	Pattern            string
+	FilePattern        *regexp.Regexp
+	Overrides          map[string]any
+	OutputFileTemplate *template.Template
+	// FramesTemplate is a pointer to a frames template json file.
+	// This is so the user does not have to do weird escaping within a string.
+	FramesTemplate *template.Template
+	// FramesFormat is the format the FramesTemplate renders, picked by the extension of the template file.
+	FramesFormat Format
+	// Escape escapes every value the FramesTemplate prints for a double quoted string of the FramesFormat.
+	// It is on unless the rule sets it to false.
+	Escape bool
+}
+
+func (r *Rule) UnmarshalJSON(b []byte) error {
+	var rule struct {
+		Name              string
+		FilePattern       string
+		Overrides         map[string]any
+		OutputFilePattern string
+		FramesTemplate    string
+		Escape            *bool
+	}
+	if err := json.Unmarshal(b, &rule); err != nil {
+		return errors.WithStack(err)
+	}
+	if rule.FilePattern == "" {
+		return errors.New("a rule needs a FilePattern")
+	}
+	if rule.FramesTemplate == "" {
+		return errors.New("a rule needs a FramesTemplate")
+	}
+	r.Name = rule.Name
+	r.Pattern = rule.FilePattern
+	r.Overrides = rule.Overrides
+
+	// regexp
+	re, err := CompilePattern(rule.FilePattern)
+	if err != nil {
+		return err
+	}
+	r.FilePattern = re
+
+	// templates
+	outFileTmpl, err := template.New("output").Funcs(tmplFuncs()).Parse(rule.OutputFilePattern)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.OutputFileTemplate = outFileTmpl
+
+	framesb, err := os.ReadFile(rule.FramesTemplate)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.FramesFormat = FormatOf(rule.FramesTemplate)
+	r.Escape = rule.Escape == nil || *rule.Escape
+	tmpl, err := parseFramesTemplate("frames", string(framesb), r.FramesFormat, r.Escape, tmplFuncs())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.FramesTemplate = tmpl
+	return nil
+}
+
+// String is the name of the rule or its pattern when it has no name.
+func (r *Rule) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("%q", r.Pattern)
+}
+
+// Match returns the variables the FilePattern captures from the path and whether the path matches at all.
+func (r *Rule) Match(path string) (map[string]any, bool) {
+	matches := r.FilePattern.FindStringSubmatch(filepath.ToSlash(path))
+	if matches == nil {
+		return nil, false
+	}
+	captured := map[string]any{}
+	for i, name := range r.FilePattern.SubexpNames() {
+		if name == "" || name == "ignore" {
+			continue
+		}
+		captured[name] = matches[i]
+	}
+	return captured, true
+}
@@ -0,0 +1,339 @@
+package tagger
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+	"github.com/chuckha/tagger/id3v23/tags"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+//go:generate go run ./cmd/tagger config-schema -o schema/config.schema.json
+//go:generate go run ./cmd/tagger config-schema -template -o schema/template-config.schema.json
+
+// SchemaDraft is the version of JSON Schema the config schemas are written in.
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the part of JSON Schema the config schemas use.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	PatternProperties    map[string]*Schema `json:"patternProperties,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+var no = false
+
+// closed is an object that only allows the given properties.
+func closed(description string, properties map[string]*Schema) *Schema {
+	return &Schema{Type: "object", Description: description, Properties: properties, AdditionalProperties: &no}
+}
+
+func str(description string) *Schema {
+	return &Schema{Type: "string", Description: description}
+}
+
+func list(description string, items *Schema) *Schema {
+	return &Schema{Type: "array", Description: description, Items: items}
+}
+
+// kindDef is the name of the definition of the body of a kind of frame, e.g. "user defined url" is "userDefinedURL".
+func kindDef(kind string) string {
+	var b strings.Builder
+	for i, word := range strings.FieldsFunc(kind, func(r rune) bool { return r == ' ' || r == '-' }) {
+		switch {
+		case word == "url" || word == "cd":
+			word = strings.ToUpper(word)
+		case i > 0:
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+// frameDefs are the definitions of every kind of frame body, each a single body or a list of bodies.
+func frameDefs() map[string]*Schema {
+	defs := map[string]*Schema{}
+	for _, id := range frames.SupportedIDs() {
+		name := kindDef(frames.IDToFrameKind[id])
+		if _, ok := defs[name]; ok {
+			continue
+		}
+		properties := map[string]*Schema{}
+		for _, field := range frames.Fields(id) {
+			s := str(field.Description)
+			if field.Data {
+				s = &Schema{OneOf: []*Schema{
+					s,
+					closed("read the value from a file", map[string]*Schema{"File": str("path to the file")}),
+				}}
+			}
+			if field.Enum != nil {
+				s.Enum = field.Enum
+			}
+			if field.Values {
+				// the description is the one of the frame so it is left to the frame property
+				s = &Schema{OneOf: []*Schema{
+					{Type: "string"},
+					{Type: "number"},
+					list("every value of a frame that may have several", &Schema{Type: "string"}),
+				}}
+			}
+			properties[field.Name] = s
+		}
+		body := closed("", properties)
+		defs[name] = &Schema{OneOf: []*Schema{body, list("several frames with the same key", body)}}
+	}
+	return defs
+}
+
+// framesSchema is the Frames object: a property for every supported frame ID and a pattern for the qualified keys.
+func framesSchema() *Schema {
+	s := &Schema{
+		Type:                 "object",
+		Description:          "frames to set keyed by frame ID or frame key, e.g. TIT2 or TXXX:Narrator",
+		Properties:           map[string]*Schema{},
+		PatternProperties:    map[string]*Schema{},
+		AdditionalProperties: &no,
+	}
+	for _, id := range frames.SupportedIDs() {
+		kind := frames.IDToFrameKind[id]
+		ref := &Schema{Ref: "#/$defs/" + kindDef(kind), Description: frames.Descriptions[id]}
+		s.Properties[id] = ref
+		if kind != frames.TextInformationKind && kind != frames.NonStandardTextInformationKind && kind != frames.MusicCDIdentifierKind {
+			s.PatternProperties[fmt.Sprintf("^%s:.+$", regexp.QuoteMeta(id))] = ref
+		}
+	}
+	return s
+}
+
+func operationsSchema() *Schema {
+	return list("operations run in order after Frames", closed("", map[string]*Schema{
+		"Op": {Type: "string", Enum: []string{
+			string(tags.Set), string(tags.Remove), string(tags.RemoveMatching), string(tags.SetIfMissing), string(tags.AppendValue),
+		}},
+		"Frame":   str("frame key, e.g. TIT2 or TXXX:Narrator"),
+		"Value":   {Description: "a frame body or a list of frame bodies"},
+		"Pattern": str("regular expression matched against the text of the frames to remove"),
+	}))
+}
+
+// ConfigSchema describes a config that sets and removes frames.
+func ConfigSchema() *Schema {
+	s := closed("", map[string]*Schema{
+		"Frames":     framesSchema(),
+		"Operations": operationsSchema(),
+	})
+	s.Schema = SchemaDraft
+	s.ID = "https://github.com/chuckha/tagger/schema/config.schema.json"
+	s.Title = "tagger config"
+	s.Defs = frameDefs()
+	return s
+}
+
+// ruleProperties are the properties of a rule, which may also be written at the top level of a template config.
+func ruleProperties() map[string]*Schema {
+	return map[string]*Schema{
+		"Name":              str("identifies the rule in errors and reports"),
+		"FilePattern":       str("pattern the path of a file must match, e.g. Disc $disc$/%title%.mp3, or re: followed by a regular expression"),
+		"Overrides":         {Type: "object", Description: "values that replace or add to the captured variables"},
+		"OutputFilePattern": str("template of the path the file is written to"),
+		"FramesTemplate":    str("path to the template that renders the config of every file"),
+		"Escape":            {Type: "boolean", Description: "escape every value the FramesTemplate prints for a double quoted string of its format, true unless set to false"},
+	}
+}
+
+// TemplateConfigSchema describes a template config.
+func TemplateConfigSchema() *Schema {
+	rule := closed("", ruleProperties())
+	rule.Required = []string{"FilePattern", "FramesTemplate"}
+	properties := ruleProperties()
+	properties["Rules"] = list("rules applied to the matching files in order", rule)
+	properties["Match"] = &Schema{Type: "string", Enum: []string{string(FirstMatch), string(AllMatch)}}
+	properties["UserData"] = &Schema{Description: "any data available to the templates as .userData"}
+	properties["Behavior"] = closed("", map[string]*Schema{
+		string(MissingTag): {Type: "string", Enum: []string{string(Add), string(Skip)}},
+		string(Logging):    {Type: "string", Enum: []string{string(Noisy)}},
+		string(WriteFile):  {Type: "string", Enum: []string{string(Skip)}},
+		string(Backup):     {Type: "string", Enum: []string{string(Add)}},
+		string(Durable):    {Type: "string", Enum: []string{string(Add)}},
+	})
+	properties["Padding"] = closed("padding of every tag that is written", map[string]*Schema{
+		"Min":   {Type: "integer", Description: "padding of a tag that has to grow"},
+		"Max":   {Type: "integer", Description: "most padding a tag keeps before it is shrunk; 0 never shrinks a tag"},
+		"Block": {Type: "integer", Description: "the size of a grown or shrunk tag is rounded up to a multiple of this"},
+	})
+	properties["SortBy"] = list("keys the files are sorted by before they are counted", str("path, filename, a captured variable like $part$ or a tag value like tag.TPOS"))
+	properties["DataSources"] = list("tables joined to every file", &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"Name":     str("name of the rows in templates"),
+			"Path":     str("CSV, TSV, JSON or cue sheet file"),
+			"Format":   {Type: "string", Enum: []string{"csv", "tsv", "json", "cue"}},
+			"Key":      str("column that identifies a row"),
+			"On":       str("path, filename, a captured variable like $part$ or a tag value like tag.TPOS"),
+			"Optional": {Type: "boolean"},
+		},
+		Required:             []string{"Path", "On"},
+		AdditionalProperties: &no,
+	})
+	s := closed("", properties)
+	s.Schema = SchemaDraft
+	s.ID = "https://github.com/chuckha/tagger/schema/template-config.schema.json"
+	s.Title = "tagger template config"
+	return s
+}
+
+// validator checks a document against a schema and remembers every problem.
+type validator struct {
+	file string
+	root *Schema
+	errs []error
+}
+
+func (v *validator) fail(n *node, path, format string, args ...any) {
+	v.errs = append(v.errs, &PositionError{File: v.file, Line: n.line, Column: n.column, Path: path, Err: errors.Errorf(format, args...)})
+}
+
+// failKey reports a problem with the key of a field rather than its value.
+func (v *validator) failKey(n *node, path, format string, args ...any) {
+	v.errs = append(v.errs, &PositionError{File: v.file, Line: n.keyLine, Column: n.keyColumn, Path: path, Err: errors.Errorf(format, args...)})
+}
+
+// check reports whether the node matches the schema and, when report is set, records why it does not.
+func (v *validator) check(s *Schema, n *node, path string, report bool) bool {
+	if s.Ref != "" {
+		def, ok := v.root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+		if !ok {
+			panic(fmt.Sprintf("unknown schema reference %q", s.Ref))
+		}
+		s = def
+	}
+	if len(s.OneOf) > 0 {
+		for _, option := range s.OneOf {
+			if v.check(option, n, path, false) {
+				return true
+			}
+		}
+		if report {
+			// report the problems of the option of the same type, e.g. a field of an object, which is the most useful
+			for _, option := range s.OneOf {
+				if option.Type == n.kind() {
+					return v.check(option, n, path, true)
+				}
+			}
+			v.fail(n, path, "%s is not %s", n.kind(), v.types(s.OneOf))
+		}
+		return false
+	}
+	if s.Type != "" && s.Type != n.kind() && !(s.Type == "integer" && n.kind() == "number") {
+		if report {
+			v.fail(n, path, "expected %s, got %s", s.Type, n.kind())
+		}
+		return false
+	}
+	ok := true
+	if s.Enum != nil {
+		value, _ := n.value.(string)
+		found := false
+		for _, e := range s.Enum {
+			found = found || e == value
+		}
+		if !found {
+			if report {
+				v.fail(n, path, "%q must be one of %q", value, s.Enum)
+			}
+			ok = false
+		}
+	}
+	if n.object {
+		for _, key := range s.Required {
+			if _, found := n.fields[key]; !found {
+				if report {
+					v.fail(n, path, "%s is required", key)
+				}
+				ok = false
+			}
+		}
+		for _, key := range n.keys {
+			child := n.fields[key]
+			childPath := joinPath(path, key)
+			if property, found := s.Properties[key]; found {
+				ok = v.check(property, child, childPath, report) && ok
+				continue
+			}
+			matched := false
+			for pattern, property := range s.PatternProperties {
+				if regexp.MustCompile(pattern).MatchString(key) {
+					matched = true
+					ok = v.check(property, child, childPath, report) && ok
+					break
+				}
+			}
+			if !matched && s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				if report {
+					v.failKey(child, childPath, "unknown field %q", key)
+				}
+				ok = false
+			}
+		}
+	}
+	if n.list && s.Items != nil {
+		for i, item := range n.items {
+			ok = v.check(s.Items, item, fmt.Sprintf("%s[%d]", path, i), report) && ok
+		}
+	}
+	return ok
+}
+
+func (v *validator) types(options []*Schema) string {
+	types := []string{}
+	for _, option := range options {
+		if option.Ref != "" {
+			types = append(types, "a frame")
+			continue
+		}
+		types = append(types, option.Type)
+	}
+	return strings.Join(types, " or ")
+}
+
+// ValidateFile checks a config file in any format against the schema.
+// Every problem is a PositionError naming the line and column it is on.
+func ValidateFile(file string, schema *Schema) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return ValidateDocument(file, FormatOf(file), b, schema)
+}
+
+// ValidateDocument checks a config document against the schema.
+func ValidateDocument(file string, format Format, b []byte, schema *Schema) error {
+	n, err := parseDocument(file, format, b)
+	if err != nil {
+		return err
+	}
+	v := &validator{file: file, root: schema}
+	v.check(schema, n, "", true)
+	if len(v.errs) > 0 {
+		return errors.Join(v.errs...)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package tagger
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSchemaFilesAreGenerated(t *testing.T) {
+	for file, schema := range map[string]*Schema{
+		"schema/config.schema.json":          ConfigSchema(),
+		"schema/template-config.schema.json": TemplateConfigSchema(),
+	} {
+		b, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		onDisk, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(onDisk) != string(b)+"\n" {
+			t.Errorf("%s is out of date; run go generate", file)
+		}
+	}
+}
+
+func TestValidateDocument(t *testing.T) {
+	testcases := []struct {
+		name     string
+		format   Format
+		doc      string
+		schema   *Schema
+		expected []string
+	}{
+		{
+			name:   "valid json",
+			format: JSON,
+			doc:    `{"Frames": {"TIT2": {"Information": "a"}, "TPE1": {"Information": ["a", "b"]}, "TXXX:Narrator": {"Value": "b"}, "COMM": [{"Language": "eng"}]}}`,
+			schema: ConfigSchema(),
+		},
+		{
+			name:   "json",
+			format: JSON,
+			doc: `{
+  "Frames": {
+    "TIT2": {"Info": "a"},
+    "TXXX:Narrator": {"Value": 1},
+    "APIC": {"PictureType": "Cover"},
+    "TZZZ": {"Information": "a"}
+  },
+  "Operations": [{"Op": "delete", "Frame": "TIT2"}]
+}`,
+			schema: ConfigSchema(),
+			expected: []string{
+				`config:3:14: Frames.TIT2.Info: unknown field "Info"`,
+				`config:4:32: Frames.TXXX:Narrator.Value: expected string, got number`,
+				`config:5:29: Frames.APIC.PictureType: "Cover" must be one of`,
+				`config:6:5: Frames.TZZZ: unknown field "TZZZ"`,
+				`config:8:25: Operations[0].Op: "delete" must be one of`,
+			},
+		},
+		{
+			name:   "yaml",
+			format: YAML,
+			doc: `Frames:
+  TIT2:
+    Information: true
+Rules: []
+`,
+			schema: ConfigSchema(),
+			expected: []string{
+				`config:3:18: Frames.TIT2.Information: boolean is not string or number or array`,
+				`config:4:1: Rules: unknown field "Rules"`,
+			},
+		},
+		{
+			name:   "toml",
+			format: TOML,
+			doc: `FilePattern = "$track$.mp3"
+FramesTemplate = "config.json.tmpl"
+Match = "some"
+
+[[Rules]]
+Name = "no pattern"
+`,
+			schema: TemplateConfigSchema(),
+			expected: []string{
+				`config:3:1: Match: "some" must be one of`,
+				`config:5:1: Rules[0]: FilePattern is required`,
+				`config:5:1: Rules[0]: FramesTemplate is required`,
+			},
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDocument("config", tt.format, []byte(tt.doc), tt.schema)
+			if len(tt.expected) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, e := range tt.expected {
+				if !strings.Contains(err.Error(), e) {
+					t.Errorf("expected %q in\n%s", e, err)
+				}
+			}
+		})
+	}
+}
+
+func TestREADMEConfigurationMatchesSchema(t *testing.T) {
+	if err := ValidateFile("examples/template-config.json", TemplateConfigSchema()); err != nil {
+		t.Fatal(err)
+	}
+}
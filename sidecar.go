@@ -0,0 +1,211 @@
+package tagger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+	"github.com/chuckha/tagger/id3v23/tags"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// A sidecar file holds the tags of many files outside of them.
+// It is an object keyed by the path of each file whose values are configs that set every frame of the tag in order:
+//
+//	{"book/ch01.mp3": {"Frames": {"TIT2": {"Information": "Chapter 1"}, "APIC": {"Data": {"File": "tags.data/0001-APIC-2.jpg"}}}}}
+//
+// Binary data such as pictures is written to files in the data directory next to the sidecar file.
+
+// SidecarEntry is the config of one file in a sidecar file.
+type SidecarEntry struct {
+	File   string
+	Config *Config
+}
+
+// Apply replaces the frames of the tag with the frames of the entry.
+// Frames tagger does not support cannot be exported so they are kept where they were.
+// Text frames are set exactly as they are written in the sidecar file; genres and separators are not normalized.
+func (s *SidecarEntry) Apply(tag *tags.ID3v2) error {
+	return tag.ReplaceFrames(s.Config.operations()...)
+}
+
+// SidecarDataDir is the directory the binary data of the sidecar file is written to, e.g. tags.data for tags.json.
+func SidecarDataDir(sidecar string) string {
+	return strings.TrimSuffix(sidecar, filepath.Ext(sidecar)) + ".data"
+}
+
+// ExportDir writes the tag of every file in dir to the sidecar file.
+// Files without a tag are left out. The returned warnings name everything that could not be exported.
+func ExportDir(dir, sidecar string) ([]string, error) {
+	dataDir := SidecarDataDir(sidecar)
+	warnings := []string{}
+	errs := []error{}
+	var out bytes.Buffer
+	out.WriteString("{")
+	count := 0
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if sameFile(path, dataDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == DirConfigFile || sameFile(path, sidecar) {
+			return nil
+		}
+		tag, err := tags.NewID3v2FromFile(path)
+		var noTag *tags.NoID3v2IdentifierError
+		if errors.As(err, &noTag) {
+			warnings = append(warnings, fmt.Sprintf("%s: no tag to export", path))
+			return nil
+		}
+		if err != nil {
+			errs = append(errs, errors.WithMessagef(err, "%s", path))
+			return nil
+		}
+		count++
+		entry, skipped, err := exportTag(tag, func(id string, i int, field string, data []byte, body frames.FrameBody) (string, error) {
+			name := filepath.Join(dataDir, fmt.Sprintf("%04d-%s-%d%s", count, id, i+1, dataExtension(field, body)))
+			if err := os.MkdirAll(dataDir, 0755); err != nil {
+				return "", errors.WithStack(err)
+			}
+			return name, errors.WithStack(os.WriteFile(name, data, 0644))
+		})
+		if err != nil {
+			errs = append(errs, errors.WithMessagef(err, "%s", path))
+			return nil
+		}
+		for _, id := range skipped {
+			warnings = append(warnings, fmt.Sprintf("%s: %s (%s) is not supported and was not exported", path, id, frames.Descriptions[id]))
+		}
+		if count > 1 {
+			out.WriteString(",")
+		}
+		if err := writeJSON(&out, path); err != nil {
+			return err
+		}
+		out.WriteString(":")
+		out.Write(entry)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	out.WriteString("}")
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, out.Bytes(), "", "  "); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	indented.WriteString("\n")
+	return warnings, errors.WithStack(os.WriteFile(sidecar, indented.Bytes(), 0644))
+}
+
+// exportTag writes the config that sets every frame of the tag in order.
+// Frames that share a key are written as a list; unsupported frames are skipped and their IDs returned.
+func exportTag(tag *tags.ID3v2, write func(id string, i int, field string, data []byte, body frames.FrameBody) (string, error)) ([]byte, []string, error) {
+	keys := []string{}
+	bodies := map[string][]map[string]any{}
+	skipped := []string{}
+	for i, frame := range *tag.Frames {
+		if _, ok := frame.Body.(*frames.Unknown); ok {
+			skipped = append(skipped, frame.Header.ID)
+			continue
+		}
+		body, err := frames.Export(frame.Body, func(field string, data []byte) (string, error) {
+			return write(frame.Header.ID, i, field, data, frame.Body)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		key := frame.Key()
+		if _, ok := bodies[key]; !ok {
+			keys = append(keys, key)
+		}
+		bodies[key] = append(bodies[key], body)
+	}
+	var out bytes.Buffer
+	out.WriteString(`{"Frames":{`)
+	for i, key := range keys {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		if err := writeJSON(&out, key); err != nil {
+			return nil, nil, err
+		}
+		out.WriteString(":")
+		var value any = bodies[key]
+		if len(bodies[key]) == 1 {
+			value = bodies[key][0]
+		}
+		if err := writeJSON(&out, value); err != nil {
+			return nil, nil, err
+		}
+	}
+	out.WriteString("}}")
+	return out.Bytes(), skipped, nil
+}
+
+// writeJSON writes the value without escaping HTML so file names and text stay readable.
+func writeJSON(out *bytes.Buffer, value any) error {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return errors.WithStack(err)
+	}
+	// Encode ends every value with a newline
+	out.Truncate(out.Len() - 1)
+	return nil
+}
+
+// dataExtension picks the extension of a data file so it can be opened by other programs.
+func dataExtension(field string, body frames.FrameBody) string {
+	switch b := body.(type) {
+	case *frames.AttachedPicture:
+		return frames.Extension(b.MIMEType, b.PictureData)
+	case *frames.GeneralEncapsulationObject:
+		if ext := filepath.Ext(string(b.Filename)); ext != "" {
+			return ext
+		}
+		return frames.Extension(b.MIMEType, b.EncapsulatedObject)
+	case *frames.UnsynchronizedLyrics:
+		return ".txt"
+	}
+	return ".bin"
+}
+
+func sameFile(a, b string) bool {
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// LoadSidecar reads a sidecar file in the format of its extension.
+func LoadSidecar(file string) ([]*SidecarEntry, error) {
+	b, err := readConfigFile(file)
+	if err != nil {
+		return nil, err
+	}
+	keys, values, err := orderedObject(b)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "%s", file)
+	}
+	entries := make([]*SidecarEntry, 0, len(keys))
+	for i, key := range keys {
+		cfg := NewConfig()
+		cfg.verbatim = true
+		if err := cfg.UnmarshalJSON(values[i]); err != nil {
+			return nil, errors.WithMessagef(err, "%s: %s", file, key)
+		}
+		entries = append(entries, &SidecarEntry{File: key, Config: cfg})
+	}
+	return entries, nil
+}
@@ -0,0 +1,170 @@
+package tagger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+	"github.com/chuckha/tagger/id3v23/tags"
+)
+
+func TestSidecarRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	book := filepath.Join(dir, "book")
+	tagged := filepath.Join(book, "ch01.mp3")
+	untagged := filepath.Join(book, "ch02.mp3")
+	writeMP3(t, tagged)
+	writeMP3(t, untagged)
+	tag := tags.NewID3v2()
+	err := tag.SetFrames(
+		frames.NewFrame("TIT2", &frames.TextInformation{TextEncoding: 1, Information: []rune("Chapter 1")}),
+		// neither normalized like a genre of a config nor validated like a track
+		frames.NewFrame("TCON", frames.NewTextInformation("(17)")),
+		frames.NewFrame("TRCK", frames.NewTextInformation("01/")),
+		frames.NewFrame("TPE1", frames.NewTextInformationValues("TPE1", "Stephen Fry", "日本語")),
+		frames.NewFrame("TXXX", &frames.UserDefinedTextInformation{Description: []rune("Narrator"), Value: []rune("Stephen Fry")}),
+		frames.NewFrame("PRIV", &frames.PrivateData{OwnerIdentifier: "shop", Data: []byte{0, 1, 2}}),
+		frames.NewFrame("PRIV", &frames.PrivateData{OwnerIdentifier: "shop", Data: []byte{3}}),
+		frames.NewFrame("APIC", &frames.AttachedPicture{MIMEType: "image/png", PictureType: 3, PictureData: []byte("\x89PNG\x0D\x0A\x1A\x0A")}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tag.Write(tagged, tagged); err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile(tagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sidecar := filepath.Join(dir, "tags.json")
+	warnings, err := ExportDir(book, sidecar)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], untagged) {
+		t.Fatalf("expected a warning about the file without a tag, got %q", warnings)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tags.data", "0001-APIC-8.png")); err != nil {
+		t.Fatalf("expected the picture to be written to the data directory: %v", err)
+	}
+
+	// change the file so importing has something to undo
+	changed, err := tags.NewID3v2FromFile(tagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed.ResetFrames()
+	changed.SetFrames(frames.NewFrame("TIT2", frames.NewTextInformation("changed")))
+	if err := changed.Write(tagged, tagged); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := LoadSidecar(sidecar)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(entries) != 1 || entries[0].File != tagged {
+		t.Fatalf("expected a single entry for %s, got %v", tagged, entries)
+	}
+	imported, err := tags.NewID3v2FromFile(tagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entries[0].Apply(imported); err != nil {
+		t.Fatal(err)
+	}
+	if err := imported.Write(tagged, tagged); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(tagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the padding may differ, everything else may not
+	if !bytes.Equal(withoutPadding(t, got), withoutPadding(t, original)) {
+		t.Fatalf("expected the round trip to restore the file\nexpected: %q\n     got: %q", withoutPadding(t, original), withoutPadding(t, got))
+	}
+}
+
+func TestSidecarImportKeepsUnsupportedFramesInPlace(t *testing.T) {
+	dir := t.TempDir()
+	book := filepath.Join(dir, "book")
+	file := filepath.Join(book, "ch01.mp3")
+	writeMP3(t, file)
+	tag := tags.NewID3v2()
+	// unsupported frames as they would be read from a file written by another tagger
+	*tag.Frames = frames.Frames{
+		frames.NewFrame("TIT2", frames.NewTextInformation("Chapter 1")),
+		frames.NewFrame("RVAD", &frames.Unknown{Data: []byte{1, 2}}),
+		frames.NewFrame("TPE1", frames.NewTextInformation("Stephen Fry")),
+		frames.NewFrame("EQUA", &frames.Unknown{Data: []byte{3}}),
+		frames.NewFrame("APIC", &frames.AttachedPicture{MIMEType: "image/png", PictureType: 3, PictureData: []byte("\x89PNG\x0D\x0A\x1A\x0A")}),
+	}
+	if err := tag.Write(file, file); err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sidecar := filepath.Join(dir, "tags.json")
+	warnings, err := ExportDir(book, sidecar)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("expected a warning for every unsupported frame, got %q", warnings)
+	}
+	entries, err := LoadSidecar(sidecar)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	imported, err := tags.NewID3v2FromFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entries[0].Apply(imported); err != nil {
+		t.Fatal(err)
+	}
+	if err := imported.Write(file, file); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(withoutPadding(t, got), withoutPadding(t, original)) {
+		t.Fatalf("expected the round trip to keep the frames in order\nexpected: %q\n     got: %q", withoutPadding(t, original), withoutPadding(t, got))
+	}
+}
+
+// withoutPadding returns the frames of the tag followed by the audio.
+func withoutPadding(t *testing.T, file []byte) []byte {
+	t.Helper()
+	header := &tags.Header{}
+	if err := header.UnmarshalBinary(file[:10]); err != nil {
+		t.Fatal(err)
+	}
+	tag := tags.NewID3v2()
+	if err := tag.UnmarshalBinary(file[:header.Size+10]); err != nil {
+		t.Fatal(err)
+	}
+	if len(*tag.Frames) == 0 {
+		t.Fatal("expected frames")
+	}
+	out := []byte{}
+	for _, frame := range *tag.Frames {
+		b, err := frame.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, b...)
+	}
+	return append(out, file[tag.Header.Size+10:]...)
+}
@@ -0,0 +1,98 @@
+package tagger
+
+import (
+	"sort"
+	"strings"
+)
+
+// sortMatches orders the files by each key in turn and then by path so the order never depends on the file system.
+// The values of the keys are worked out once per file rather than on every comparison.
+func sortMatches(keys []FileKey, found []*match) {
+	type sorted struct {
+		m      *match
+		values []string
+	}
+	all := make([]sorted, len(found))
+	for i, m := range found {
+		values := make([]string, 0, len(keys)+1)
+		for _, key := range keys {
+			values = append(values, key.value(m))
+		}
+		all[i] = sorted{m: m, values: append(values, PathKey.value(m))}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return lessValues(all[i].values, all[j].values)
+	})
+	for i, s := range all {
+		found[i] = s.m
+	}
+}
+
+// lessValues compares the values of the keys of two files in turn.
+func lessValues(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return naturalLess(a[i], b[i])
+		}
+	}
+	return false
+}
+
+// naturalLess compares strings the way people do so "Track 2" comes before "Track 10".
+// Runs of digits are compared by their value and everything else by its runes.
+func naturalLess(a, b string) bool {
+	ar, br := []rune(a), []rune(b)
+	for len(ar) > 0 && len(br) > 0 {
+		if isDigit(ar[0]) && isDigit(br[0]) {
+			an, arest := digits(ar)
+			bn, brest := digits(br)
+			if c := compareDigits(an, bn); c != 0 {
+				return c < 0
+			}
+			ar, br = arest, brest
+			continue
+		}
+		if ar[0] != br[0] {
+			return ar[0] < br[0]
+		}
+		ar, br = ar[1:], br[1:]
+	}
+	if len(ar) != len(br) {
+		return len(ar) < len(br)
+	}
+	// the values only differ in leading zeros
+	return a < b
+}
+
+// digits splits a leading run of digits from the rest of the runes.
+func digits(r []rune) ([]rune, []rune) {
+	i := 0
+	for i < len(r) && isDigit(r[i]) {
+		i++
+	}
+	return r[:i], r[i:]
+}
+
+// compareDigits compares two runs of digits by value without parsing them so long runs never overflow.
+func compareDigits(a, b []rune) int {
+	a = trimZeros(a)
+	b = trimZeros(b)
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(string(a), string(b))
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+func trimZeros(r []rune) []rune {
+	for len(r) > 1 && r[0] == '0' {
+		r = r[1:]
+	}
+	return r
+}
@@ -0,0 +1,52 @@
+package tagger
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	paths := []string{
+		"Track 10.mp3",
+		"Track 2.mp3",
+		"Disk 2/Track 1.mp3",
+		"Track 02.mp3",
+		"Disk 10/Track 1.mp3",
+		"Track 1.mp3",
+		"Disk 2/Track 1b.mp3",
+		"Track 99999999999999999999999.mp3",
+	}
+	sort.Slice(paths, func(i, j int) bool { return naturalLess(paths[i], paths[j]) })
+	expected := []string{
+		"Disk 2/Track 1.mp3",
+		"Disk 2/Track 1b.mp3",
+		"Disk 10/Track 1.mp3",
+		"Track 1.mp3",
+		"Track 02.mp3",
+		"Track 2.mp3",
+		"Track 10.mp3",
+		"Track 99999999999999999999999.mp3",
+	}
+	if strings.Join(paths, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(paths, "\n"))
+	}
+}
+
+func TestSortMatches(t *testing.T) {
+	found := []*match{
+		{path: "b/Track 2.mp3", captures: map[string]any{"part": "1"}},
+		{path: "a/Track 10.mp3", captures: map[string]any{"part": "2"}},
+		{path: "a/Track 1.mp3", captures: map[string]any{"part": "2"}},
+		{path: "c/Track 1.mp3", captures: map[string]any{"part": "10"}},
+	}
+	sortMatches([]FileKey{"$part$"}, found)
+	paths := []string{}
+	for _, m := range found {
+		paths = append(paths, m.path)
+	}
+	expected := []string{"b/Track 2.mp3", "a/Track 1.mp3", "a/Track 10.mp3", "c/Track 1.mp3"}
+	if strings.Join(paths, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(paths, "\n"))
+	}
+}
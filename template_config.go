@@ -4,12 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"html/template"
+	"io"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strconv"
-	"strings"
 
 	"github.com/chuckha/tagger/id3v23/tags"
 	"gitlab.com/tozd/go/errors"
@@ -26,174 +23,513 @@ const (
 	MissingTag Situation = "missing-id3v2-tag"
 	Logging    Situation = "logging"
 	WriteFile  Situation = "write-file"
+	// Backup set to add keeps a copy of every file that is replaced.
+	Backup Situation = "backup"
+	// Durable set to add never overwrites a tag in place; see tags.WriteOptions.
+	Durable Situation = "durable"
 )
 
 // TemplateConfig is a user defined template config.
-// It references a FramesTemplate that is used to generate a Config for file.
+// It is an ordered list of rules, each with a FramesTemplate that is used to generate a Config for every file the rule matches.
 // The config, once rendered, will define the tags that should exist on the file.
 type TemplateConfig struct {
-	FilePattern        *regexp.Regexp
-	Overrides          map[string]any
-	OutputFileTemplate *template.Template
-	// FramesTemplate is a pointer to a frames template json file.
-	// This is so the user does not have to do weird escaping within a string.
-	FramesTemplate *template.Template
-	UserData       any
-	Behavior       map[Situation]Behavior
+	Rules []*Rule
+	// Match decides whether only the first or every rule that matches a file is applied.
+	Match    MatchMode
+	UserData any
+	Behavior map[Situation]Behavior
+	// SortBy orders the matching files before they are counted.
+	// Files are always ordered by path last.
+	SortBy []FileKey
+	// DataSources are tables joined to every file. The row of the first is {{.row}} and every row is in {{.rows}}.
+	DataSources []*DataSource
+	// Padding decides the padding of every tag that is written; nil uses tags.DefaultPaddingPolicy.
+	Padding *tags.PaddingPolicy
 
 	// special is an internal variable that holds aggregate values across all files.
 	// special is available in all templates.
 	special map[string]any
+	// journal records the original tag of every file before it is written.
+	journal *Journal
+	// jobs is the number of files read, planned and written at the same time.
+	jobs int
+	// out is where progress is printed; nil prints to stdout.
+	out io.Writer
 }
 
 func NewTemplateConfig() *TemplateConfig {
 	return &TemplateConfig{
-		Overrides: make(map[string]any),
-		Behavior:  make(map[Situation]Behavior),
-		special:   make(map[string]any),
+		Match:    FirstMatch,
+		Behavior: make(map[Situation]Behavior),
+		special:  make(map[string]any),
 	}
 }
 
+// UnmarshalJSON reads a template config.
+// A FilePattern at the top level is a rule of its own that comes before the Rules.
 func (t *TemplateConfig) UnmarshalJSON(b []byte) error {
 	var cfg struct {
-		FilePattern       string
-		Overrides         map[string]any
-		OutputFilePattern string
-		FramesTemplate    string
-		UserData          any
-		Behavior          map[Situation]Behavior
+		FilePattern string
+		Rules       []json.RawMessage
+		Match       MatchMode
+		UserData    any
+		Behavior    map[Situation]Behavior
+		SortBy      []FileKey
+		DataSources []*DataSource
+		Padding     *tags.PaddingPolicy
 	}
 	if err := json.Unmarshal(b, &cfg); err != nil {
 		return errors.WithStack(err)
 	}
 	// straight forward conversions
-	t.Overrides = cfg.Overrides
 	t.UserData = cfg.UserData
 	t.Behavior = cfg.Behavior
+	if t.Behavior == nil {
+		t.Behavior = make(map[Situation]Behavior)
+	}
+	t.SortBy = cfg.SortBy
+	if cfg.Padding != nil {
+		if err := cfg.Padding.Validate(); err != nil {
+			return err
+		}
+	}
+	t.Padding = cfg.Padding
+	switch cfg.Match {
+	case "":
+		t.Match = FirstMatch
+	case FirstMatch, AllMatch:
+		t.Match = cfg.Match
+	default:
+		return errors.Errorf("match must be %q or %q, got %q", FirstMatch, AllMatch, cfg.Match)
+	}
 
-	// regexp
-	t.FilePattern = regexp.MustCompile(subRegex(cfg.FilePattern))
-
-	// templates
-	outFileTmpl, err := template.New("output").Funcs(tmplFuncs()).Parse(cfg.OutputFilePattern)
-	if err != nil {
-		return errors.WithStack(err)
+	// rules
+	raw := cfg.Rules
+	if cfg.FilePattern != "" {
+		raw = append([]json.RawMessage{b}, raw...)
+	}
+	if len(raw) == 0 {
+		return errors.New("a template config needs a FilePattern or Rules")
+	}
+	t.Rules = make([]*Rule, 0, len(raw))
+	for i, r := range raw {
+		rule := &Rule{}
+		if err := rule.UnmarshalJSON(r); err != nil {
+			return errors.WithMessagef(err, "rule %d", i+1)
+		}
+		t.Rules = append(t.Rules, rule)
 	}
-	t.OutputFileTemplate = outFileTmpl
 
-	framesb, err := os.ReadFile(cfg.FramesTemplate)
-	if err != nil {
-		return errors.WithStack(err)
+	captures := []string{}
+	for _, rule := range t.Rules {
+		captures = append(captures, rule.FilePattern.SubexpNames()...)
 	}
-	tmpl, err := template.New("frames").Funcs(tmplFuncs()).Parse(string(framesb))
-	if err != nil {
-		return errors.WithStack(err)
+	for _, key := range t.SortBy {
+		if err := key.Validate(captures); err != nil {
+			return errors.WithMessage(err, "sort by")
+		}
+	}
+
+	// data sources
+	names := map[string]bool{}
+	for _, ds := range cfg.DataSources {
+		if err := ds.Load(); err != nil {
+			return err
+		}
+		if names[ds.Name] {
+			return errors.Errorf("data source %q: the name must be unique", ds.Name)
+		}
+		names[ds.Name] = true
+		if err := ds.On.Validate(captures); err != nil {
+			return errors.WithMessagef(err, "data source %q", ds.Name)
+		}
 	}
-	t.FramesTemplate = tmpl
+	t.DataSources = cfg.DataSources
 	return nil
 }
 
+// SetJournal makes ProcessDir record every file in the journal before it is written so the run can be undone.
+func (t *TemplateConfig) SetJournal(journal *Journal) {
+	t.journal = journal
+}
+
+// SetJobs sets the number of files ProcessDir reads, plans and writes at the same time.
+// The result is the same for any number of jobs.
+func (t *TemplateConfig) SetJobs(jobs int) {
+	t.jobs = jobs
+}
+
+// SetOutput sets where ProcessDir prints its progress.
+// Progress is printed in the order of the files, never in the order the jobs finish in.
+func (t *TemplateConfig) SetOutput(out io.Writer) {
+	t.out = out
+}
+
+func (t *TemplateConfig) printf(format string, args ...any) {
+	out := t.out
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintf(out, format, args...)
+}
+
 func (t *TemplateConfig) UpdateBehavior(situation Situation, behavior Behavior) {
 	t.Behavior[situation] = behavior
 }
 
-func (t *TemplateConfig) SetupSpecial(dir string) error {
-	total := 0
+// job is the work planned for a single file.
+type job struct {
+	path    string
+	tag     *tags.ID3v2
+	config  *Config
+	outFile string
+}
+
+// Report describes the files ProcessDirReport found.
+type Report struct {
+	// Rules maps every planned file to the rules that matched it.
+	Rules map[string][]*Rule
+	// Unmatched are the files no rule matched.
+	Unmatched []string
+}
+
+// ProcessDir renders and validates the config of every matching file before any file is written.
+// A mistake in the template or a malformed frame value therefore never leaves the directory half tagged.
+// Files are read, planned and written by SetJobs workers at a time; every count is decided before that so the result does not depend on the order they finish in.
+func (t *TemplateConfig) ProcessDir(dir string) error {
+	_, err := t.ProcessDirReport(dir)
+	return err
+}
+
+// ProcessDirReport is ProcessDir that also reports which rules matched every file and which files no rule matched.
+func (t *TemplateConfig) ProcessDirReport(dir string) (*Report, error) {
+	jobs, report, err := t.plan(dir)
+	if err != nil {
+		return nil, err
+	}
+	// every file is written even if another one fails
+	applied := make([]bool, len(jobs))
+	err = parallel(len(jobs), t.jobs, func(i int) error {
+		if err := t.apply(jobs[i]); err != nil {
+			return errors.WithMessagef(err, "%s", jobs[i].path)
+		}
+		applied[i] = true
+		return nil
+	})
+	// printed once every job is done so the order does not depend on which one finished first
+	if t.DryRun() {
+		for i, j := range jobs {
+			if applied[i] {
+				t.printf("[dry run] would have written %q\n", j.outFile)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// match is a file that matches at least one rule along with what is known about it before it is planned.
+type match struct {
+	path  string
+	tag   *tags.ID3v2
+	rules []*Rule
+	// captures are the variables captured by every matching rule; the first rule to capture a name wins.
+	captures map[string]any
+	// dir is the merged DirConfigFile of the directory the file is in.
+	dir *DirConfig
+	// count is the position of the file among all matches and dirCount its position within its directory.
+	count    int
+	dirCount int
+	dirTotal int
+	// data is the tag as template data, built the first time it is needed.
+	data map[string]any
+}
+
+// tagData returns the tag as template data.
+// A match is only ever worked on by one goroutine at a time so the data is built without locking.
+func (m *match) tagData() map[string]any {
+	if m.data == nil {
+		m.data = m.tag.TemplateData()
+	}
+	return m.data
+}
+
+// rulesFor returns the rules to apply to the path.
+func (t *TemplateConfig) rulesFor(path string) []*Rule {
+	rules := []*Rule{}
+	for _, rule := range t.Rules {
+		if _, ok := rule.Match(path); !ok {
+			continue
+		}
+		rules = append(rules, rule)
+		if t.Match != AllMatch {
+			break
+		}
+	}
+	return rules
+}
+
+// collect finds every file in dir, reads the tags of the files that match a rule and orders them by SortBy.
+// The counts are assigned after sorting so they never depend on the order the file system returns files in.
+// Every rule shares the same counts.
+func (t *TemplateConfig) collect(dir string) ([]*match, *Report, error) {
+	report := &Report{Rules: map[string][]*Rule{}, Unmatched: []string{}}
+	candidates := []*match{}
+	// directories are walked before the files in them so the config of the parent is always known
+	dirConfigs := map[string]*DirConfig{}
 	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 		if d.IsDir() {
+			parent, ok := dirConfigs[filepath.Dir(path)]
+			if !ok {
+				parent = NewDirConfig()
+			}
+			dc, err := parent.Merge(filepath.Join(path, DirConfigFile))
+			if err != nil {
+				return err
+			}
+			dirConfigs[path] = dc
+			return nil
+		}
+		if d.Name() == DirConfigFile {
 			return nil
 		}
-		matches := t.FilePattern.FindStringSubmatch(path)
-		if len(matches) == 0 {
+		rules := t.rulesFor(path)
+		if len(rules) == 0 {
+			report.Unmatched = append(report.Unmatched, path)
 			return nil
 		}
-		total++
+		captures := map[string]any{}
+		for i := len(rules) - 1; i >= 0; i-- {
+			captured, _ := rules[i].Match(path)
+			for name, value := range captured {
+				captures[name] = value
+			}
+		}
+		candidates = append(candidates, &match{path: path, rules: rules, captures: captures, dir: dirConfigs[filepath.Dir(path)]})
 		return nil
 	})
 	if err != nil {
-		return err
+		return nil, nil, errors.WithStack(err)
 	}
-	t.special["total"] = total
+	err = parallel(len(candidates), t.jobs, func(i int) error {
+		tag, err := t.readTag(candidates[i].path)
+		candidates[i].tag = tag
+		return errors.WithMessagef(err, "%s", candidates[i].path)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// files without a tag are skipped unless missing tags are added
+	found := []*match{}
+	for _, m := range candidates {
+		if m.tag == nil {
+			if t.Noisy() {
+				t.printf("skipping %q; no id3 file identifier\n", m.path)
+			}
+			continue
+		}
+		report.Rules[m.path] = m.rules
+		found = append(found, m)
+	}
+	sortMatches(t.SortBy, found)
+	dirTotals := map[string]int{}
+	for _, m := range found {
+		dirTotals[filepath.Dir(m.path)]++
+	}
+	dirCounts := map[string]int{}
+	for i, m := range found {
+		dir := filepath.Dir(m.path)
+		dirCounts[dir]++
+		m.count = i + 1
+		m.dirCount = dirCounts[dir]
+		m.dirTotal = dirTotals[dir]
+	}
+	return found, report, nil
+}
 
+// SetupSpecial sets the total in special to the number of files in dir that ProcessDir would write.
+//
+// Deprecated: ProcessDir sets up special itself and gives every file its own count.
+func (t *TemplateConfig) SetupSpecial(dir string) error {
+	found, _, err := t.collect(dir)
+	if err != nil {
+		return err
+	}
+	t.special["total"] = len(found)
 	t.special["count"] = 1
 	return nil
 }
 
-func (t *TemplateConfig) ProcessDir(dir string) error {
-	if err := t.SetupSpecial(dir); err != nil {
-		return err
+// plan renders the frames and output templates for every matching file.
+func (t *TemplateConfig) plan(dir string) ([]*job, *Report, error) {
+	found, report, err := t.collect(dir)
+	if err != nil {
+		return nil, nil, err
 	}
-	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return err
+	t.special["total"] = len(found)
+	// printed before the jobs start so the order does not depend on which one starts first
+	if t.Noisy() {
+		for _, m := range found {
+			t.printf("working on %s\n", m.path)
 		}
-		if d.IsDir() {
-			return nil
+	}
+	jobs := make([]*job, len(found))
+	err = parallel(len(found), t.jobs, func(i int) error {
+		j, err := t.planFile(found[i])
+		jobs[i] = j
+		return errors.WithMessagef(err, "%s", found[i].path)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkOutFiles(jobs); err != nil {
+		return nil, nil, err
+	}
+	return jobs, report, nil
+}
+
+// checkOutFiles makes sure no two files are written to the same file and no file is written over another file that is tagged,
+// which would make the result depend on the order the files are written in.
+func checkOutFiles(jobs []*job) error {
+	sources := map[string]bool{}
+	for _, j := range jobs {
+		sources[samePath(j.path)] = true
+	}
+	written := map[string]string{}
+	errs := []error{}
+	for _, j := range jobs {
+		out := samePath(j.outFile)
+		if other, ok := written[out]; ok {
+			errs = append(errs, errors.Errorf("%s and %s are both written to %s", other, j.path, j.outFile))
+			continue
 		}
-		matches := t.FilePattern.FindStringSubmatch(path)
-		if len(matches) == 0 {
-			return nil
+		written[out] = j.path
+		if sources[out] && out != samePath(j.path) {
+			errs = append(errs, errors.Errorf("%s would be written over %s, which is tagged too", j.path, j.outFile))
 		}
-		// TODO: move this into a logging object
-		if t.Behavior[Logging] == Noisy {
-			fmt.Println("working on", path)
+	}
+	return errors.Join(errs...)
+}
+
+// samePath is the absolute path of the file so different ways of naming it compare equal.
+func samePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// planFile renders the config and output file of a single match.
+// The Frames of the DirConfigFile of the directory are applied after every rule.
+// With AllMatch the operations of every matching rule are applied in rule order and the last rule with an output file decides where the file is written.
+// A file without an output file is written in place.
+func (t *TemplateConfig) planFile(m *match) (*job, error) {
+	j := &job{path: m.path, tag: m.tag, config: NewConfig(), outFile: m.path}
+	// every file gets its own copy of special so the counts are the same in every template
+	special := map[string]any{}
+	for k, v := range t.special {
+		special[k] = v
+	}
+	special["count"] = m.count
+	special["dirCount"] = m.dirCount
+	special["dirTotal"] = m.dirTotal
+	rows := map[string]any{}
+	var row any
+	for i, ds := range t.DataSources {
+		r, err := ds.row(m)
+		if err != nil {
+			return nil, err
 		}
-		// set up the config template context
-		extracted := map[string]any{}
-		for i, name := range t.FilePattern.SubexpNames() {
-			if name == "" || name == "ignore" {
-				continue
-			}
-			extracted[name] = matches[i]
+		rows[ds.Name] = r
+		if i == 0 {
+			row = r
 		}
+	}
+	// the directory config may be inherited from far above the file so the rules, which are written for the file, are applied after it
+	dirFrames, err := m.dir.Frames()
+	if err != nil {
+		return nil, errors.WithMessage(err, DirConfigFile)
+	}
+	j.config.Operations = append(j.config.Operations, dirFrames.operations()...)
+	for _, rule := range m.rules {
+		// set up the config template context
+		extracted, _ := rule.Match(m.path)
 		// apply the overrides (anything not captured in the file path but used in the template is required)
 		// continue setting up the config template context
-		for name, override := range t.Overrides {
+		for name, override := range rule.Overrides {
 			extracted[name] = override
 		}
 		extracted["userData"] = t.UserData
-		extracted["special"] = t.special
+		extracted["special"] = special
+		// turning the tag into template data decodes every frame so it is only done for templates that use it
+		if usesField("tag", rule.FramesTemplate, rule.OutputFileTemplate) {
+			extracted["tag"] = m.tagData()
+		}
+		extracted["rows"] = rows
+		extracted["row"] = row
+		extracted["dir"] = m.dir.Values
 		// get the config for the file
 		var b bytes.Buffer
-		if err := t.FramesTemplate.Execute(&b, extracted); err != nil {
-			return errors.WithStack(err)
-		}
-		nc := NewConfig()
-		if err := nc.UnmarshalJSON(b.Bytes()); err != nil {
-			return err
+		if err := rule.FramesTemplate.Execute(&b, extracted); err != nil {
+			return nil, errors.WithMessagef(err, "rule %s", rule)
 		}
-		tag, err := tags.NewID3v2FromFile(path)
+		rendered, err := ToJSON("rendered FramesTemplate", rule.FramesFormat, b.Bytes())
 		if err != nil {
-			var e *tags.NoID3v2IdentifierError
-			if !errors.As(err, &e) {
-				return err
-			}
-			if !t.AddMissingTag() {
-				if t.Noisy() {
-					fmt.Printf("skipping %q; no id3 file identifier\n", path)
-				}
-				return nil
-			}
-			tag = tags.NewID3v2()
+			return nil, errors.WithMessagef(err, "rule %s", rule)
 		}
-		if err := tag.ApplyFrames(nc.Frames); err != nil {
-			panic(fmt.Sprintf("%+v", err))
+		nc := NewConfig()
+		if err := nc.UnmarshalJSON(rendered); err != nil {
+			return nil, errors.WithMessagef(err, "rule %s", rule)
 		}
-		t.special["count"] = t.special["count"].(int) + 1
+		j.config.Operations = append(j.config.Operations, nc.operations()...)
 		// generate the outfile name from the outfile pattern
 		var outFile bytes.Buffer
-		if err := t.OutputFileTemplate.Execute(&outFile, extracted); err != nil {
-			panic(fmt.Sprintf("%+v", err))
+		if err := rule.OutputFileTemplate.Execute(&outFile, extracted); err != nil {
+			return nil, errors.WithMessagef(err, "rule %s", rule)
 		}
-		if !t.DryRun() {
-			return tag.Write(path, outFile.String())
+		if outFile.Len() > 0 {
+			j.outFile = outFile.String()
 		}
-		fmt.Printf("[dry run] would have written %q\n", outFile.String())
-		return nil
-	})
+	}
+	return j, nil
+}
+
+// readTag reads the existing tag of the file.
+// A file without a tag gets an empty one or, if missing tags are not added, a nil tag so it is skipped.
+func (t *TemplateConfig) readTag(path string) (*tags.ID3v2, error) {
+	tag, err := tags.NewID3v2FromFile(path)
+	if err == nil {
+		return tag, nil
+	}
+	var e *tags.NoID3v2IdentifierError
+	if !errors.As(err, &e) {
+		return nil, err
+	}
+	if !t.AddMissingTag() {
+		return nil, nil
+	}
+	return tags.NewID3v2(), nil
+}
+
+// apply applies the planned config to the tag and, unless it is a dry run, writes the result.
+func (t *TemplateConfig) apply(j *job) error {
+	if err := j.config.Apply(j.tag); err != nil {
+		return err
+	}
+	if !t.DryRun() {
+		if t.journal != nil {
+			if err := t.journal.Record(j.path, j.outFile); err != nil {
+				return errors.WithMessage(err, "journal")
+			}
+		}
+		return j.tag.WriteWithOptions(j.path, j.outFile, tags.WriteOptions{Backup: t.Backup(), Durable: t.Durable(), Padding: t.Padding})
+	}
+	return nil
 }
 
 func (t *TemplateConfig) DryRun() bool {
@@ -204,42 +540,14 @@ func (t *TemplateConfig) Noisy() bool {
 	return t.Behavior[Logging] == Noisy
 }
 
-func (t *TemplateConfig) AddMissingTag() bool {
-	return t.Behavior[MissingTag] == Add
-}
-
-// subRegex substitutes the easier to read input pattern with the regex pattern
-func subRegex(inputPattern string) string {
-	inputPattern = strings.ReplaceAll(inputPattern, "(", `\(`)
-	inputPattern = strings.ReplaceAll(inputPattern, ")", `\)`)
-	// find any \$(\w+)\$ and replace them with `(?P<$1>\d+))`
-	// find any %(\w+)% and replace them with `(?P<$1>.+)
-	// input is a user pattern like     "FilePattern": "fables_$volume$_$fable$_aesop_64kb.mp3",
-	// output is a regular expression
-
-	digitGrabber := regexp.MustCompile(`\$(\w+)\$`)
-	for _, match := range digitGrabber.FindAllStringSubmatch(inputPattern, -1) {
-		inputPattern = strings.ReplaceAll(inputPattern, match[0], fmt.Sprintf(`(?P<%s>\d+)`, match[1]))
-	}
-	wordGrabber := regexp.MustCompile(`%(\w+)%`)
-	for _, match := range wordGrabber.FindAllStringSubmatch(inputPattern, -1) {
-		inputPattern = strings.ReplaceAll(inputPattern, match[0], fmt.Sprintf(`(?P<%s>.+)`, match[1]))
-	}
-	return inputPattern
+func (t *TemplateConfig) Backup() bool {
+	return t.Behavior[Backup] == Add
 }
 
-func tmplFuncs() template.FuncMap {
-	return template.FuncMap{
-		"get": get,
-	}
+func (t *TemplateConfig) Durable() bool {
+	return t.Behavior[Durable] == Add
 }
 
-func get(in any, key string) any {
-	switch x := in.(type) {
-	case []any:
-		d, _ := strconv.Atoi(key)
-		return x[d-1]
-	default:
-		panic(fmt.Sprintf("unknown type %T", x))
-	}
+func (t *TemplateConfig) AddMissingTag() bool {
+	return t.Behavior[MissingTag] == Add
 }
@@ -0,0 +1,822 @@
+package tagger
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+	"github.com/chuckha/tagger/id3v23/tags"
+)
+
+// These tests run the examples from the README end to end.
+
+var chapters = []string{
+	"Chapter 01 - The Other Minister",
+	"Chapter 02 - Spinner's End",
+	"Chapter 03 - Will and Won't",
+	"Chapter 04 - Horace Slughorn",
+	"Chapter 05 - An Excess of Phlegm",
+	"Chapter 06 - Draco's Detour",
+}
+
+func TestREADMEConfiguration(t *testing.T) {
+	cfg := NewConfig()
+	err := cfg.UnmarshalJSON([]byte(`{
+    "Frames": {
+        "TRCK": {"Information": "1/17"},
+        "TEXT": {"Information": "J.K. Rowling"},
+        "TPE1": {"Information": "Stephen Fry"},
+        "TIT2": {"Information": "Chapter 01 - The Other Minister"},
+        "TALB": {"Information": "Harry Potter and the Half-Blood Prince"}
+    }
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := tags.NewID3v2()
+	if err := cfg.Apply(tag); err != nil {
+		t.Fatal(err)
+	}
+	expectText(t, tag, "TRCK", "1/17")
+	expectText(t, tag, "TALB", "Harry Potter and the Half-Blood Prince")
+}
+
+func TestREADMETemplatedConfiguration(t *testing.T) {
+	dir := t.TempDir()
+	for disk, parts := range map[string][]string{"01": {"01", "02", "03"}, "02": {"04", "05", "06"}} {
+		for _, part := range parts {
+			writeMP3(t, filepath.Join(dir, "Harry Potter and the Half Blood Prince Disk "+disk, "Stephen Fry - Track "+part+".mp3"))
+		}
+	}
+	framesTemplate := writeFile(t, dir, "config.json.tmpl", `{
+    "Frames": {
+        "TPOS": {"Information": "{{.disk}}/{{.totalDiscs}}"},
+        "TRCK": {"Information": "{{.special.count}}/{{.special.total}}"},
+        "TCOM": {"Information": "{{.reader}}"},
+        "TCON": {"Information": "Audiobook"},
+        "TPE1": {"Information": "J. K. Rowling, Stephen Fry"},
+        "TIT1": {"Information": "Harry Potter"},
+        "TIT2": {"Information": "{{get .userData.chapters .part}}"},
+        "TALB": {"Information": "Harry Potter and the Half-Blood Prince"},
+        "COMM": {"ActualText": "{{.userData.comment}}"}
+    }
+}`)
+	out := filepath.Join(dir, "hbp")
+	if err := os.Mkdir(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	tc := newTemplateConfig(t, `{
+    "FilePattern": "Harry Potter and the Half Blood Prince Disk $disk$/%reader% - Track $part$.mp3",
+    "Overrides": {
+        "reader": "Stephen Fry",
+        "totalDiscs": 17
+    },
+    "OutputFilePattern": "`+out+`/{{.disk}}-{{.part}}.mp3",
+    "FramesTemplate": "`+framesTemplate+`",
+    "UserData": {
+        "chapters": `+jsonList(chapters)+`,
+        "comment": "Rock & Roll <\"quoted\"> \\ back\\slash"
+    },
+    "Behavior": {"missing-id3v2-tag": "add"}
+}`)
+	if err := tc.ProcessDir(dir); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for i, chapter := range chapters {
+		part := chapters[i][len("Chapter "):len("Chapter 01")]
+		disk := "01"
+		if i >= 3 {
+			disk = "02"
+		}
+		tag, err := tags.NewID3v2FromFile(filepath.Join(out, disk+"-"+part+".mp3"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectText(t, tag, "TIT2", chapter)
+		expectText(t, tag, "TPOS", disk+"/17")
+		expectText(t, tag, "TCON", "(183)Audiobook")
+		expectText(t, tag, "TCOM", "Stephen Fry")
+		if got := tag.TemplateData()["COMM"].(map[string]any)["eng"]; got != `Rock & Roll <"quoted"> \ back\slash` {
+			t.Fatalf("expected the comment to survive verbatim, got %q", got)
+		}
+	}
+}
+
+func TestREADMESpecialTemplateCharacters(t *testing.T) {
+	dir := t.TempDir()
+	writeMP3(t, filepath.Join(dir, "shirokuma_01.mp3"))
+	picture := writeFile(t, dir, "shirokuma.png", "\x89PNG\r\n\x1a\nnot really a png")
+	framesTemplate := writeFile(t, dir, "config.json.tmpl", `{
+    "Frames": {
+        "TPOS": {"Information": "1"},
+        "TRCK": {"Information": "{{.episode}}/{{.special.total}}"},
+        "TCON": {"Information": "Other"},
+        "TALB": {"Information": "しろくまカフェ"},
+        "TPE1": {"Information": "ヒガアロハ"},
+        "APIC": {
+            "MIMEType": "image/png",
+            "PictureType": "Cover (front)",
+            "Description": "Shirokuma-san and Panda-kun",
+            "Data": "@`+picture+`"
+        }
+    }
+}`)
+	tc := newTemplateConfig(t, `{
+    "FilePattern": "shirokuma_$episode$.mp3",
+    "OutputFilePattern": "`+dir+`/out.mp3",
+    "FramesTemplate": "`+framesTemplate+`",
+    "Behavior": {"missing-id3v2-tag": "add"}
+}`)
+	if err := tc.ProcessDir(dir); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	tag, err := tags.NewID3v2FromFile(filepath.Join(dir, "out.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectText(t, tag, "TALB", "しろくまカフェ")
+	expectText(t, tag, "TPE1", "ヒガアロハ")
+	expectText(t, tag, "TRCK", "01/1")
+	last := (*tag.Frames)[len(*tag.Frames)-1]
+	ap, ok := last.Body.(*frames.AttachedPicture)
+	if !ok {
+		t.Fatalf("expected APIC to be the last frame, got %s", last.Header.ID)
+	}
+	if string(ap.Description) != "Shirokuma-san and Panda-kun" || ap.PictureType != 0x03 {
+		t.Fatalf("unexpected picture %v", ap)
+	}
+}
+
+func TestREADMETagComments(t *testing.T) {
+	tag := tags.NewID3v2()
+	err := tag.SetFrames(
+		frames.NewFrame("COMM", &frames.Comment{Language: "eng", ActualText: []rune("plain")}),
+		frames.NewFrame("COMM", &frames.Comment{Language: "eng", ShortContentDescription: []rune("Note"), ActualText: []rune("described")}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := template.New("test").Parse(`{{.tag.COMM.eng}} {{index .tag.COMM "eng:Note"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, map[string]any{"tag": tag.TemplateData()}); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "plain described" {
+		t.Fatalf("unexpected comments %q", b.String())
+	}
+}
+
+func TestExampleTemplateConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeMP3(t, filepath.Join(dir, "fables_01_01_aesop_64kb.mp3"))
+	writeMP3(t, filepath.Join(dir, "fables_01_02_aesop_64kb.mp3"))
+	b, err := os.ReadFile("examples/template-config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := NewTemplateConfig()
+	if err := tc.UnmarshalJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	tc.UpdateBehavior(WriteFile, Skip)
+	jobs, _, err := tc.plan(dir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("expected two files to match, got %d", len(jobs))
+	}
+	j := jobs[1]
+	if err := j.config.Apply(j.tag); err != nil {
+		t.Fatal(err)
+	}
+	expectText(t, j.tag, "TIT1", "Aesop's Fables")
+	expectText(t, j.tag, "TALB", "Aesop's Fables, Volume 1")
+	expectText(t, j.tag, "TRCK", "02/2")
+	if j.outFile != "examples/output/01_02.mp3" {
+		t.Fatalf("unexpected output file %q", j.outFile)
+	}
+}
+
+func TestSortByPath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Disk 1/Track 10.mp3", "Disk 1/Track 2.mp3", "Disk 1/Track 1.mp3", "Disk 2/Track 1.mp3", "Disk 2/Track 9.mp3"} {
+		writeMP3(t, filepath.Join(dir, name))
+	}
+	framesTemplate := writeFile(t, dir, "config.json.tmpl", `{
+    "Frames": {
+        "TRCK": {"Information": "{{.special.count}}/{{.special.total}}"},
+        "TIT2": {"Information": "{{.special.dirCount}}/{{.special.dirTotal}}"}
+    }
+}`)
+	tc := newTemplateConfig(t, `{
+    "FilePattern": "Disk $disk$/Track $track$.mp3",
+    "OutputFilePattern": "{{.disk}}-{{.track}}.mp3",
+    "FramesTemplate": "`+framesTemplate+`",
+    "Behavior": {"missing-id3v2-tag": "add"}
+}`)
+	jobs, _, err := tc.plan(dir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	expected := []struct{ outFile, trck, tit2 string }{
+		{"1-1.mp3", "1/5", "1/3"},
+		{"1-2.mp3", "2/5", "2/3"},
+		{"1-10.mp3", "3/5", "3/3"},
+		{"2-1.mp3", "4/5", "1/2"},
+		{"2-9.mp3", "5/5", "2/2"},
+	}
+	if len(jobs) != len(expected) {
+		t.Fatalf("expected %d files, got %d", len(expected), len(jobs))
+	}
+	for i, j := range jobs {
+		if j.outFile != expected[i].outFile {
+			t.Fatalf("expected file %d to be %q, got %q", i+1, expected[i].outFile, j.outFile)
+		}
+		if err := j.config.Apply(j.tag); err != nil {
+			t.Fatal(err)
+		}
+		expectText(t, j.tag, "TRCK", expected[i].trck)
+		expectText(t, j.tag, "TIT2", expected[i].tit2)
+	}
+}
+
+func TestSortByCaptures(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Part 1 Disk 2.mp3", "Part 2 Disk 1.mp3", "Part 1 Disk 1.mp3", "Part 10 Disk 1.mp3"} {
+		writeMP3(t, filepath.Join(dir, name))
+	}
+	framesTemplate := writeFile(t, dir, "config.json.tmpl", `{"Frames": {"TRCK": {"Information": "{{.special.count}}"}}}`)
+	tc := newTemplateConfig(t, `{
+    "FilePattern": "Part $part$ Disk $disk$.mp3",
+    "OutputFilePattern": "{{.disk}}-{{.part}}.mp3",
+    "FramesTemplate": "`+framesTemplate+`",
+    "SortBy": ["$disk$", "$part$"],
+    "Behavior": {"missing-id3v2-tag": "add"}
+}`)
+	jobs, _, err := tc.plan(dir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	got := []string{}
+	for _, j := range jobs {
+		got = append(got, j.outFile)
+	}
+	if strings.Join(got, " ") != "1-1.mp3 1-2.mp3 1-10.mp3 2-1.mp3" {
+		t.Fatalf("unexpected order %v", got)
+	}
+}
+
+func TestSortByTag(t *testing.T) {
+	dir := t.TempDir()
+	for name, title := range map[string]string{"a.mp3": "Chapter 3", "b.mp3": "Chapter 1", "c.mp3": "Chapter 2"} {
+		path := filepath.Join(dir, name)
+		writeMP3(t, path)
+		tag := tags.NewID3v2()
+		if err := tag.SetFrames(frames.NewFrame("TIT2", frames.NewTextInformation(title))); err != nil {
+			t.Fatal(err)
+		}
+		if err := tag.Write(path, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	framesTemplate := writeFile(t, dir, "config.json.tmpl", `{"Frames": {"TRCK": {"Information": "{{.special.count}}"}}}`)
+	tc := newTemplateConfig(t, `{
+    "FilePattern": "%name%.mp3",
+    "OutputFilePattern": "{{.tag.TIT2}}",
+    "FramesTemplate": "`+framesTemplate+`",
+    "SortBy": ["tag.TIT2"]
+}`)
+	jobs, _, err := tc.plan(dir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	got := []string{}
+	for _, j := range jobs {
+		got = append(got, j.outFile)
+	}
+	if strings.Join(got, ", ") != "Chapter 1, Chapter 2, Chapter 3" {
+		t.Fatalf("unexpected order %v", got)
+	}
+}
+
+func TestSkippedFilesAreNotCounted(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Track 1.mp3", "Track 2.mp3", "Track 3.mp3"} {
+		writeMP3(t, filepath.Join(dir, name))
+	}
+	// Track 2 has no tag so it is skipped
+	for _, name := range []string{"Track 1.mp3", "Track 3.mp3"} {
+		path := filepath.Join(dir, name)
+		if err := tags.NewID3v2().Write(path, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	framesTemplate := writeFile(t, dir, "config.json.tmpl", `{"Frames": {"TRCK": {"Information": "{{.special.count}}/{{.special.total}}"}}}`)
+	tc := newTemplateConfig(t, `{
+    "FilePattern": "Track $track$.mp3",
+    "FramesTemplate": "`+framesTemplate+`"
+}`)
+	jobs, _, err := tc.plan(dir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	expected := []string{"1/2", "2/2"}
+	if len(jobs) != len(expected) {
+		t.Fatalf("expected %d files, got %d", len(expected), len(jobs))
+	}
+	for i, j := range jobs {
+		if err := j.config.Apply(j.tag); err != nil {
+			t.Fatal(err)
+		}
+		expectText(t, j.tag, "TRCK", expected[i])
+	}
+	if err := tc.SetupSpecial(dir); err != nil {
+		t.Fatal(err)
+	}
+	if tc.special["total"] != 2 {
+		t.Fatalf("expected SetupSpecial to count 2 files, got %v", tc.special["total"])
+	}
+}
+
+func TestRules(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Disc One/01.mp3", "Disc One/02.mp3", "CD2/Track 1.mp3", "CD2/Track 2.mp3", "CD2/cover.jpg"} {
+		writeMP3(t, filepath.Join(dir, name))
+	}
+	discOne := writeFile(t, dir, "one.json.tmpl", `{"Frames": {"TPOS": {"Information": "1/2"}, "TRCK": {"Information": "{{.special.count}}/{{.special.total}}"}}}`)
+	discTwo := writeFile(t, dir, "two.json.tmpl", `{"Frames": {"TPOS": {"Information": "2/2"}, "TRCK": {"Information": "{{.special.count}}/{{.special.total}}"}}}`)
+	everything := writeFile(t, dir, "all.json.tmpl", `{"Frames": {"TALB": {"Information": "Box Set"}, "TPOS": {"Information": "1/1"}}}`)
+	rules := `
+        {"Name": "disc one", "FilePattern": "Disc One/$track$.mp3", "FramesTemplate": "` + discOne + `"},
+        {"Name": "disc two", "FilePattern": "CD2/Track $track$.mp3", "FramesTemplate": "` + discTwo + `"}`
+
+	t.Run("first match", func(t *testing.T) {
+		tc := newTemplateConfig(t, `{
+    "Rules": [`+rules+`,
+        {"Name": "everything", "FilePattern": "%name%.mp3", "FramesTemplate": "`+everything+`"}
+    ],
+    "Behavior": {"missing-id3v2-tag": "add"}
+}`)
+		jobs, report, err := tc.plan(dir)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		expected := []struct{ tpos, trck string }{{"1/2", "1/4"}, {"1/2", "2/4"}, {"2/2", "3/4"}, {"2/2", "4/4"}}
+		if len(jobs) != len(expected) {
+			t.Fatalf("expected %d files, got %d", len(expected), len(jobs))
+		}
+		// CD2 sorts before Disc One
+		jobs = append(jobs[2:], jobs[:2]...)
+		for i, j := range jobs {
+			if err := j.config.Apply(j.tag); err != nil {
+				t.Fatal(err)
+			}
+			if j.outFile != j.path {
+				t.Fatalf("expected %q to be written in place, got %q", j.path, j.outFile)
+			}
+			expectText(t, j.tag, "TPOS", expected[i].tpos)
+			if j.tag.TextFrame("TALB") != nil {
+				t.Fatalf("expected only the first matching rule to apply to %q", j.path)
+			}
+		}
+		if rules := report.Rules[filepath.Join(dir, "CD2/Track 1.mp3")]; len(rules) != 1 || rules[0].Name != "disc two" {
+			t.Fatalf("unexpected rules %v", rules)
+		}
+		unmatched := strings.Join(report.Unmatched, " ")
+		if !strings.Contains(unmatched, "cover.jpg") || strings.Contains(unmatched, ".mp3") {
+			t.Fatalf("unexpected unmatched files %v", report.Unmatched)
+		}
+	})
+
+	t.Run("all match", func(t *testing.T) {
+		tc := newTemplateConfig(t, `{
+    "Match": "all",
+    "Rules": [
+        {"Name": "everything", "FilePattern": "%name%.mp3", "FramesTemplate": "`+everything+`"},`+rules+`
+    ],
+    "Behavior": {"missing-id3v2-tag": "add"}
+}`)
+		jobs, report, err := tc.plan(dir)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if len(jobs) != 4 {
+			t.Fatalf("expected 4 files, got %d", len(jobs))
+		}
+		for _, j := range jobs {
+			if err := j.config.Apply(j.tag); err != nil {
+				t.Fatal(err)
+			}
+			expectText(t, j.tag, "TALB", "Box Set")
+			if string(j.tag.TextFrame("TPOS").Information) == "1/1" {
+				t.Fatalf("expected the later rule to set TPOS of %q", j.path)
+			}
+			if len(report.Rules[j.path]) != 2 {
+				t.Fatalf("expected two rules to match %q", j.path)
+			}
+		}
+	})
+}
+
+func TestProcessDirReport(t *testing.T) {
+	dir := t.TempDir()
+	writeMP3(t, filepath.Join(dir, "01.mp3"))
+	writeFile(t, dir, "notes.txt", "not audio")
+	framesTemplate := writeFile(t, t.TempDir(), "config.json.tmpl", `{"Frames": {"TIT2": {"Information": "{{.track}}"}}}`)
+	tc := newTemplateConfig(t, `{
+    "Name": "tracks",
+    "FilePattern": "$track$.mp3",
+    "FramesTemplate": "`+framesTemplate+`",
+    "Behavior": {"missing-id3v2-tag": "add"}
+}`)
+	report, err := tc.ProcessDirReport(dir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if rules := report.Rules[filepath.Join(dir, "01.mp3")]; len(rules) != 1 || rules[0].Name != "tracks" {
+		t.Fatalf("unexpected rules %v", report.Rules)
+	}
+	if len(report.Unmatched) != 1 || report.Unmatched[0] != filepath.Join(dir, "notes.txt") {
+		t.Fatalf("unexpected unmatched files %v", report.Unmatched)
+	}
+	tag, err := tags.NewID3v2FromFile(filepath.Join(dir, "01.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectText(t, tag, "TIT2", "01")
+}
+
+func TestRulesErrors(t *testing.T) {
+	dir := t.TempDir()
+	framesTemplate := writeFile(t, dir, "config.json.tmpl", `{"Frames": {}}`)
+	testcases := []struct {
+		name     string
+		cfg      string
+		expected string
+	}{
+		{name: "no rules", cfg: `{}`, expected: "needs a FilePattern or Rules"},
+		{name: "no frames template", cfg: `{"Rules": [{"FilePattern": "%name%.mp3"}]}`, expected: "rule 1: a rule needs a FramesTemplate"},
+		{name: "no file pattern", cfg: `{"Rules": [{"FramesTemplate": "` + framesTemplate + `"}]}`, expected: "rule 1: a rule needs a FilePattern"},
+		{name: "match", cfg: `{"Match": "some", "FilePattern": "%name%.mp3", "FramesTemplate": "` + framesTemplate + `"}`, expected: `match must be "first" or "all"`},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewTemplateConfig().UnmarshalJSON([]byte(tt.cfg))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q in %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestRuleEscaping(t *testing.T) {
+	dir := t.TempDir()
+	writeMP3(t, filepath.Join(dir, "01.mp3"))
+	escaped := writeFile(t, dir, "escaped.json.tmpl", `{"Frames": {"TIT2": {"Information": "{{.userData.title}}"}}}`)
+	unescaped := writeFile(t, dir, "unescaped.json.tmpl", `{"Frames": {"TIT2": {"Information": {{printf "%q" .userData.title}}}}}`)
+	testcases := []struct {
+		name     string
+		rule     string
+		title    string
+		expected string
+	}{
+		{name: "escaped by default", rule: `"FramesTemplate": "` + escaped + `"`, title: `"Say \"hi\" \\ Rock & Roll"`, expected: `Say "hi" \ Rock & Roll`},
+		{name: "escape set to false", rule: `"FramesTemplate": "` + unescaped + `", "Escape": false`, title: `"Say \"hi\""`, expected: `Say "hi"`},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTemplateConfig(t, `{
+    "FilePattern": "$track$.mp3",
+    `+tt.rule+`,
+    "UserData": {"title": `+tt.title+`},
+    "Behavior": {"missing-id3v2-tag": "add"}
+}`)
+			jobs, _, err := tc.plan(dir)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if len(jobs) != 1 {
+				t.Fatalf("expected 1 file, got %d", len(jobs))
+			}
+			if err := jobs[0].config.Apply(jobs[0].tag); err != nil {
+				t.Fatal(err)
+			}
+			expectText(t, jobs[0].tag, "TIT2", tt.expected)
+		})
+	}
+}
+
+func TestDataSources(t *testing.T) {
+	dir := t.TempDir()
+	for _, part := range []string{"01", "02"} {
+		writeMP3(t, filepath.Join(dir, "Track "+part+".mp3"))
+	}
+	chapters := writeFile(t, dir, "chapters.csv", "Chapter,Title\n1,The Other Minister\n2,Spinner's End\n")
+	readers := writeFile(t, dir, "readers.json", `[{"File": "Track 01.mp3", "Reader": "Stephen Fry"}]`)
+	framesTemplate := writeFile(t, dir, "config.json.tmpl", `{
+    "Frames": {
+        "TIT2": {"Information": "{{.row.Title}}"},
+        "TPE1": {"Information": "{{.rows.readers.Reader | default "Jim Dale"}}"}
+    }
+}`)
+	tc := newTemplateConfig(t, `{
+    "FilePattern": "Track $part$.mp3",
+    "FramesTemplate": "`+framesTemplate+`",
+    "DataSources": [
+        {"Path": "`+chapters+`", "Key": "Chapter", "On": "$part$"},
+        {"Path": "`+readers+`", "Key": "File", "On": "filename", "Optional": true}
+    ],
+    "Behavior": {"missing-id3v2-tag": "add"}
+}`)
+	jobs, _, err := tc.plan(dir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	expected := []struct{ tit2, tpe1 string }{{"The Other Minister", "Stephen Fry"}, {"Spinner's End", "Jim Dale"}}
+	for i, j := range jobs {
+		if err := j.config.Apply(j.tag); err != nil {
+			t.Fatal(err)
+		}
+		expectText(t, j.tag, "TIT2", expected[i].tit2)
+		expectText(t, j.tag, "TPE1", expected[i].tpe1)
+	}
+
+	writeMP3(t, filepath.Join(dir, "Track 03.mp3"))
+	_, _, err = tc.plan(dir)
+	if err == nil || !strings.Contains(err.Error(), `data source "chapters" has no row where Chapter is "03"`) {
+		t.Fatalf("expected a missing row error, got %v", err)
+	}
+}
+
+func TestDirConfig(t *testing.T) {
+	dir := writeDirConfigs(t, map[string]string{
+		".":     `{"series": "Harry Potter", "Frames": {"TIT1": {"Information": "Harry Potter"}, "TCON": {"Information": "Audiobook"}}}`,
+		"book6": `{"book": "Half-Blood Prince", "Frames": {"TCON": {"Information": "Fantasy"}}}`,
+	})
+	writeMP3(t, filepath.Join(dir, "book6", "01.mp3"))
+	writeMP3(t, filepath.Join(dir, "book7", "01.mp3"))
+	framesTemplate := writeFile(t, t.TempDir(), "config.json.tmpl", `{
+    "Frames": {
+        "TALB": {"Information": "{{.dir.series}}{{with .dir.book}}: {{.}}{{end}}"},
+        "TIT1": {"Information": "Wizarding World"}
+    }
+}`)
+	tc := newTemplateConfig(t, `{
+    "FilePattern": "$track$.mp3",
+    "FramesTemplate": "`+framesTemplate+`",
+    "Behavior": {"missing-id3v2-tag": "add"}
+}`)
+	jobs, report, err := tc.plan(dir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(report.Unmatched) != 0 {
+		t.Fatalf("expected the directory configs not to be reported, got %v", report.Unmatched)
+	}
+	expected := []struct{ talb, tcon string }{{"Harry Potter: Half-Blood Prince", "Fantasy"}, {"Harry Potter", "(183)Audiobook"}}
+	for i, j := range jobs {
+		if err := j.config.Apply(j.tag); err != nil {
+			t.Fatal(err)
+		}
+		// the FramesTemplate wins over the directory configs
+		expectText(t, j.tag, "TIT1", "Wizarding World")
+		expectText(t, j.tag, "TALB", expected[i].talb)
+		expectText(t, j.tag, "TCON", expected[i].tcon)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	framesTemplate := writeFile(t, t.TempDir(), "config.json.tmpl", `{
+    "Frames": {
+        "TRCK": {"Information": "{{.special.count}}/{{.special.total}}"},
+        "TPOS": {"Information": "{{.special.dirCount}}/{{.special.dirTotal}}"},
+        "TIT2": {"Information": "{{.disk}} {{.track}}"}
+    }
+}`)
+	cfg := `{
+    "FilePattern": "Disk $disk$/Track $track$.mp3",
+    "OutputFilePattern": "DIR/{{.disk}}-{{.track}}.mp3",
+    "FramesTemplate": "` + framesTemplate + `",
+    "SortBy": ["$disk$", "$track$"],
+    "Behavior": {"missing-id3v2-tag": "add"}
+}`
+	// process the same files with one and with many jobs
+	results := []map[string]string{}
+	for _, jobs := range []int{1, 8} {
+		dir := t.TempDir()
+		for disk := 1; disk <= 3; disk++ {
+			for track := 1; track <= 12; track++ {
+				writeMP3(t, filepath.Join(dir, fmt.Sprintf("Disk %d/Track %d.mp3", disk, track)))
+			}
+		}
+		tc := newTemplateConfig(t, strings.ReplaceAll(cfg, "DIR", dir))
+		tc.SetJobs(jobs)
+		if err := tc.ProcessDir(dir); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		files := map[string]string{}
+		err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			b, err := os.ReadFile(path)
+			files[strings.TrimPrefix(path, dir)] = string(b)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, files)
+	}
+	if len(results[0]) != 3*12*2 {
+		t.Fatalf("expected every file to be written, got %d files", len(results[0]))
+	}
+	for name, b := range results[0] {
+		if results[1][name] != b {
+			t.Fatalf("expected %q to be the same with one and many jobs", name)
+		}
+	}
+}
+
+func TestNoisyOutputIsInOrder(t *testing.T) {
+	dir := t.TempDir()
+	var expected strings.Builder
+	working, dryRun := []string{}, []string{}
+	for track := 1; track <= 20; track++ {
+		path := filepath.Join(dir, fmt.Sprintf("Track %02d.mp3", track))
+		writeMP3(t, path)
+		// files without a tag are skipped
+		if track%2 == 1 {
+			fmt.Fprintf(&expected, "skipping %q; no id3 file identifier\n", path)
+			continue
+		}
+		tag := tags.NewID3v2()
+		if err := tag.SetFrames(frames.NewFrame("TIT2", frames.NewTextInformation("original"))); err != nil {
+			t.Fatal(err)
+		}
+		if err := tag.Write(path, path); err != nil {
+			t.Fatal(err)
+		}
+		working = append(working, fmt.Sprintf("working on %s\n", path))
+		dryRun = append(dryRun, fmt.Sprintf("[dry run] would have written %q\n", path))
+	}
+	expected.WriteString(strings.Join(working, "") + strings.Join(dryRun, ""))
+	framesTemplate := writeFile(t, t.TempDir(), "config.json.tmpl", `{"Frames": {"TIT2": {"Information": "{{.track}}"}}}`)
+	tc := newTemplateConfig(t, `{
+    "FilePattern": "Track $track$.mp3",
+    "FramesTemplate": "`+framesTemplate+`",
+    "Behavior": {"logging": "noisy", "write-file": "skip"}
+}`)
+	tc.SetJobs(8)
+	var out bytes.Buffer
+	tc.SetOutput(&out)
+	if err := tc.ProcessDir(dir); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if out.String() != expected.String() {
+		t.Fatalf("expected the output in the order of the files\nexpected:\n%s\ngot:\n%s", expected.String(), out.String())
+	}
+}
+
+func TestOutFileCollisions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a 1.mp3", "b 1.mp3", "c 2.mp3", "d 3.mp3"} {
+		writeMP3(t, filepath.Join(dir, name))
+	}
+	framesTemplate := writeFile(t, t.TempDir(), "config.json.tmpl", `{"Frames": {"TRCK": {"Information": "{{.track}}"}}}`)
+	tc := newTemplateConfig(t, `{
+    "Rules": [
+        {"FilePattern": "d $track$.mp3", "OutputFilePattern": "`+dir+`/a 1.mp3", "FramesTemplate": "`+framesTemplate+`"},
+        {"FilePattern": "%name% $track$.mp3", "OutputFilePattern": "`+dir+`/{{.track}}.mp3", "FramesTemplate": "`+framesTemplate+`"}
+    ],
+    "Behavior": {"missing-id3v2-tag": "add"}
+}`)
+	tc.SetJobs(4)
+	err := tc.ProcessDir(dir)
+	if err == nil {
+		t.Fatal("expected the output files to collide")
+	}
+	for _, expected := range []string{"b 1.mp3 are both written to", "d 3.mp3 would be written over"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q in %q", expected, err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected no file to be written, got %d files", len(entries))
+	}
+}
+
+func TestParseFramesTemplate(t *testing.T) {
+	testcases := []struct {
+		name     string
+		tmpl     string
+		format   Format
+		escape   bool
+		data     any
+		expected string
+	}{
+		{name: "quotes", tmpl: `{{.}}`, escape: true, data: `say "hi"`, expected: `say \"hi\"`},
+		{name: "html is not escaped", tmpl: `{{.}}`, escape: true, data: `Rock & Roll <3`, expected: `Rock & Roll <3`},
+		{name: "apostrophes", tmpl: `{{.}}`, escape: true, data: `Draco's Detour`, expected: `Draco's Detour`},
+		{name: "numbers", tmpl: `{{.}}`, escape: true, data: 17, expected: `17`},
+		{name: "inside control structures", tmpl: `{{range .}}{{if .}}{{.}}{{end}}{{end}}`, escape: true, data: []string{`a"`, ``, `b\`}, expected: `a\"b\\`},
+		{name: "variables do not print", tmpl: `{{$x := .}}{{$x}}`, escape: true, data: `"`, expected: `\"`},
+		{name: "raw is not escaped", tmpl: `{{raw .}}`, escape: true, data: `["a", "b"]`, expected: `["a", "b"]`},
+		{name: "null separator", tmpl: `{{joinValues "TCON" .}}`, escape: true, data: []string{"Folk", "Rock"}, expected: `Folk\u0000Rock`},
+		{name: "called templates are escaped once", tmpl: `{{define "t"}}{{.}}{{end}}{{template "t" .}}`, escape: true, data: `a"`, expected: `a\"`},
+		{name: "blocks are escaped once", tmpl: `{{block "t" .}}{{.}}{{end}}`, escape: true, data: `a"`, expected: `a\"`},
+		{name: "escaped by hand", tmpl: `{{. | jsonEscape}}`, escape: true, data: `a"`, expected: `a\"`},
+		{name: "yaml", tmpl: `{{.}}`, format: YAML, escape: true, data: "a\"\x7f\u0085\u0086", expected: `a\"\u007f` + "\u0085" + `\u0086`},
+		{name: "toml", tmpl: `{{.}}`, format: TOML, escape: true, data: "a\"\x7f\u0085", expected: `a\"\u007f` + "\u0085"},
+		// rules that set Escape to false print their values as they are
+		{name: "not escaped", tmpl: `{{.}}`, data: `say "hi"`, expected: `say "hi"`},
+		{name: "quoted by hand", tmpl: `{{printf "%q" .}}`, data: `say "hi"`, expected: `"say \"hi\""`},
+		{name: "printed JSON", tmpl: `{{.}}`, data: `{"Information": "a"}`, expected: `{"Information": "a"}`},
+		{name: "escape functions without escaping", tmpl: `{{. | yamlEscape}}`, data: `a"`, expected: `a\"`},
+		{name: "null separator escaped by hand", tmpl: `{{joinValues "TCON" . | jsonEscape}}`, data: []string{"Folk", "Rock"}, expected: `Folk\u0000Rock`},
+		{name: "null separator escaped by hand once", tmpl: `{{joinValues "TCON" . | jsonEscape}}`, escape: true, data: []string{"Folk", "Rock"}, expected: `Folk\u0000Rock`},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			format := tt.format
+			if format == "" {
+				format = JSON
+			}
+			tmpl, err := parseFramesTemplate("test", tt.tmpl, format, tt.escape, tmplFuncs())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			if err := tmpl.Execute(&b, tt.data); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, b.String())
+			}
+		})
+	}
+}
+
+func newTemplateConfig(t *testing.T, cfg string) *TemplateConfig {
+	t.Helper()
+	tc := NewTemplateConfig()
+	if err := tc.UnmarshalJSON([]byte(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	return tc
+}
+
+// writeMP3 writes a file without a tag that stands in for audio.
+func writeMP3(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Repeat("\xff\xfb\x90\x00", 256)), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeFile(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func jsonList(vals []string) string {
+	quoted := make([]string, 0, len(vals))
+	for _, val := range vals {
+		quoted = append(quoted, `"`+val+`"`)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func expectText(t *testing.T, tag *tags.ID3v2, id, expected string) {
+	t.Helper()
+	ti := tag.TextFrame(id)
+	if ti == nil {
+		t.Fatalf("expected a %s frame", id)
+	}
+	if got := string(ti.Information); got != expected {
+		t.Fatalf("expected %s to be %q, got %q", id, expected, got)
+	}
+}
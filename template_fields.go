@@ -0,0 +1,80 @@
+package tagger
+
+import (
+	"text/template"
+	"text/template/parse"
+)
+
+// usesField reports if any of the templates may read the field name of the data they are executed with, e.g. .tag or $.tag.
+// It errs on the side of true: printing or passing on the whole data, such as {{.}} or {{template "x" .}}, counts as reading every field.
+func usesField(name string, tmpls ...*template.Template) bool {
+	for _, tmpl := range tmpls {
+		if tmpl == nil {
+			continue
+		}
+		for _, t := range tmpl.Templates() {
+			if t.Tree != nil && nodeUsesField(name, t.Tree.Root, true) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nodeUsesField walks the node; root is true while dot is still the data the template was executed with.
+func nodeUsesField(name string, node parse.Node, root bool) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, child := range n.Nodes {
+			if nodeUsesField(name, child, root) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return nodeUsesField(name, n.Pipe, root)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, cmd := range n.Cmds {
+			if nodeUsesField(name, cmd, root) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if nodeUsesField(name, arg, root) {
+				return true
+			}
+		}
+	case *parse.IfNode:
+		return nodeUsesField(name, n.Pipe, root) || nodeUsesField(name, n.List, root) || nodeUsesField(name, n.ElseList, root)
+	case *parse.RangeNode:
+		// dot is an item of the range inside of it
+		return nodeUsesField(name, n.Pipe, root) || nodeUsesField(name, n.List, false) || nodeUsesField(name, n.ElseList, root)
+	case *parse.WithNode:
+		return nodeUsesField(name, n.Pipe, root) || nodeUsesField(name, n.List, false) || nodeUsesField(name, n.ElseList, root)
+	case *parse.TemplateNode:
+		// the called template is walked on its own as if it had the data
+		return nodeUsesField(name, n.Pipe, root)
+	case *parse.FieldNode:
+		return root && len(n.Ident) > 0 && n.Ident[0] == name
+	case *parse.VariableNode:
+		// $ is always the data; a variable declared as the data was already caught by the dot it was declared with
+		if n.Ident[0] != "$" {
+			return false
+		}
+		return len(n.Ident) == 1 || n.Ident[1] == name
+	case *parse.ChainNode:
+		return nodeUsesField(name, n.Node, root)
+	case *parse.DotNode:
+		return root
+	case *parse.StringNode:
+		// e.g. index . "tag"
+		return n.Text == name
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package tagger
+
+import (
+	"testing"
+	"text/template"
+)
+
+func TestUsesField(t *testing.T) {
+	testcases := []struct {
+		tmpl     string
+		expected bool
+	}{
+		{tmpl: `{{.title}}`, expected: false},
+		{tmpl: `{{.tag.TIT2}}`, expected: true},
+		{tmpl: `{{.tag.TIT2 | upper}}`, expected: true},
+		{tmpl: `{{if .tag}}x{{end}}`, expected: true},
+		{tmpl: `{{range .userData.list}}{{.tag}}{{end}}`, expected: false},
+		{tmpl: `{{range .userData.list}}{{$.tag.TIT2}}{{end}}`, expected: true},
+		{tmpl: `{{range $i, $v := .userData.list}}{{$v}}{{end}}`, expected: false},
+		{tmpl: `{{with .row}}{{.title}}{{else}}{{.tag.TIT2}}{{end}}`, expected: true},
+		{tmpl: `{{index . "tag"}}`, expected: true},
+		{tmpl: `{{.}}`, expected: true},
+		{tmpl: `{{define "t"}}{{.TIT2}}{{end}}{{template "t" .tag}}`, expected: true},
+		{tmpl: `{{define "t"}}{{.tag.TIT2}}{{end}}{{template "t" .}}`, expected: true},
+		{tmpl: `{{define "t"}}{{.title}}{{end}}{{template "t" .userData}}`, expected: false},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			tmpl, err := template.New("test").Funcs(tmplFuncs()).Parse(tt.tmpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := usesField("tag", tmpl); got != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
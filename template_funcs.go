@@ -0,0 +1,251 @@
+package tagger
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/chuckha/tagger/id3v23/frames"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// tmplFuncs are the functions available in every template.
+// Functions that take the value being worked on take it last so they read well in a pipeline, e.g. {{.track | pad 2}}.
+// Functions return an error instead of panicking so a bad template names the problem.
+func tmplFuncs() template.FuncMap {
+	return template.FuncMap{
+		// lists and maps
+		"get":    get,
+		"lookup": lookup,
+		// text frames
+		"joinValues":  joinValues,
+		"splitValues": splitValues,
+		// numbers
+		"int": toInt,
+		"pad": pad,
+		"add": add,
+		"sub": sub,
+		"mul": mul,
+		"div": div,
+		"mod": mod,
+		// strings
+		"title":   title,
+		"upper":   strings.ToUpper,
+		"lower":   strings.ToLower,
+		"trim":    strings.TrimSpace,
+		"replace": replace,
+		"slugify": slugify,
+		"default": defaultValue,
+		// dates
+		"date": date,
+	}
+}
+
+// get returns the nth (1-indexed) item of a list so captured track numbers can be used as is.
+func get(in any, index any) (any, error) {
+	n, err := toInt(index)
+	if err != nil {
+		return nil, errors.Errorf("get: %w", err)
+	}
+	v := reflect.ValueOf(in)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, errors.Errorf("get: expected a list, got %T", in)
+	}
+	if n < 1 || n > v.Len() {
+		return nil, errors.Errorf("get: %d is outside of the list of %d items", n, v.Len())
+	}
+	return v.Index(n - 1).Interface(), nil
+}
+
+// lookup returns the value of the key in a map and errors when the key is missing.
+func lookup(in any, key any) (any, error) {
+	v := reflect.ValueOf(in)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, errors.Errorf("lookup: expected a map with string keys, got %T", in)
+	}
+	val := v.MapIndex(reflect.ValueOf(fmt.Sprint(key)).Convert(v.Type().Key()))
+	if !val.IsValid() {
+		return nil, errors.Errorf("lookup: %q is not in the map", fmt.Sprint(key))
+	}
+	return val.Interface(), nil
+}
+
+// joinValues joins a list of values with the separator the text frame id expects.
+func joinValues(id string, in any) (string, error) {
+	var vals []string
+	switch x := in.(type) {
+	case []string:
+		vals = x
+	case []any:
+		for _, v := range x {
+			vals = append(vals, fmt.Sprint(v))
+		}
+	case string:
+		vals = []string{x}
+	default:
+		return "", errors.Errorf("joinValues: cannot join %T", in)
+	}
+	return strings.Join(vals, frames.ValueSeparator(id)), nil
+}
+
+// splitValues splits a string into values using the same rules as reading the text frame id.
+func splitValues(id string, in string) []string {
+	return frames.NewTextInformation(in).Values(id)
+}
+
+// toInt converts captured digits like "06", JSON numbers and Go integers to an int.
+func toInt(in any) (int, error) {
+	switch x := in.(type) {
+	case int:
+		return x, nil
+	case float64:
+		if x != math.Trunc(x) {
+			return 0, errors.Errorf("%v is not a whole number", x)
+		}
+		return int(x), nil
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(x))
+		if err != nil {
+			return 0, errors.Errorf("%q is not a number", x)
+		}
+		return n, nil
+	}
+	v := reflect.ValueOf(in)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(v.Uint()), nil
+	}
+	return 0, errors.Errorf("%v (%T) is not a number", in, in)
+}
+
+// pad zero pads a number to the width, e.g. pad 2 "6" is "06".
+func pad(width int, in any) (string, error) {
+	n, err := toInt(in)
+	if err != nil {
+		return "", errors.Errorf("pad: %w", err)
+	}
+	return fmt.Sprintf("%0*d", width, n), nil
+}
+
+// arithmetic takes the value being worked on last, so {{.part | add 1}} and {{add .part 1}} are the same.
+func arithmetic(name string, op func(a, b int) (int, error)) func(b, a any) (int, error) {
+	return func(b, a any) (int, error) {
+		x, err := toInt(a)
+		if err != nil {
+			return 0, errors.Errorf("%s: %w", name, err)
+		}
+		y, err := toInt(b)
+		if err != nil {
+			return 0, errors.Errorf("%s: %w", name, err)
+		}
+		return op(x, y)
+	}
+}
+
+var (
+	add = arithmetic("add", func(a, b int) (int, error) { return a + b, nil })
+	sub = arithmetic("sub", func(a, b int) (int, error) { return a - b, nil })
+	mul = arithmetic("mul", func(a, b int) (int, error) { return a * b, nil })
+	div = arithmetic("div", func(a, b int) (int, error) {
+		if b == 0 {
+			return 0, errors.New("div: division by zero")
+		}
+		return a / b, nil
+	})
+	mod = arithmetic("mod", func(a, b int) (int, error) {
+		if b == 0 {
+			return 0, errors.New("mod: division by zero")
+		}
+		return a % b, nil
+	})
+)
+
+// title upper cases the first letter of every word.
+func title(in string) string {
+	out := []rune(in)
+	start := true
+	for i, r := range out {
+		if start && unicode.IsLetter(r) {
+			out[i] = unicode.ToUpper(r)
+		}
+		start = unicode.IsSpace(r) || r == '-' || r == '(' || r == '"'
+	}
+	return string(out)
+}
+
+// replace replaces every match of the regular expression in the value, e.g. replace "_" " " .title.
+func replace(pattern, replacement string, in string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", errors.Errorf("replace: %w", err)
+	}
+	return re.ReplaceAllString(in, replacement), nil
+}
+
+var nonSlug = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// slugify turns a value into something safe to use as a file name, e.g. "Draco's Detour" is "dracos-detour".
+// Letters outside of ascii are kept so japanese titles still make sense.
+func slugify(in string) string {
+	in = strings.ToLower(strings.NewReplacer("'", "", "’", "").Replace(in))
+	return strings.Trim(nonSlug.ReplaceAllString(in, "-"), "-")
+}
+
+// defaultValue returns def when the value is missing, empty or zero, e.g. {{.tag.TCON | default "Audiobook"}}.
+func defaultValue(def any, in any) any {
+	if in == nil {
+		return def
+	}
+	v := reflect.ValueOf(in)
+	if v.IsZero() {
+		return def
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		if v.Len() == 0 {
+			return def
+		}
+	}
+	return in
+}
+
+// dateLayouts are the formats date understands when it is given a string.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+	"02/01/2006",
+}
+
+// date formats a date with a Go layout, e.g. date "0201" "2005-07-16" is the TDAT value "1607".
+// The value can be a time, a string in one of dateLayouts or "now".
+func date(layout string, in any) (string, error) {
+	switch x := in.(type) {
+	case time.Time:
+		return x.Format(layout), nil
+	case string:
+		if x == "now" {
+			return time.Now().Format(layout), nil
+		}
+		for _, l := range dateLayouts {
+			if t, err := time.Parse(l, strings.TrimSpace(x)); err == nil {
+				return t.Format(layout), nil
+			}
+		}
+		return "", errors.Errorf("date: %q is not a date", x)
+	default:
+		return "", errors.Errorf("date: expected a date, got %T", in)
+	}
+}
@@ -0,0 +1,127 @@
+package tagger
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chuckha/tagger/id3v23/tags"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	testcases := []struct {
+		name     string
+		tmpl     string
+		data     any
+		expected string
+	}{
+		{name: "get", tmpl: `{{get . "2"}}`, data: []any{"a", "b"}, expected: "b"},
+		{name: "get strings", tmpl: `{{get . 1}}`, data: []string{"a", "b"}, expected: "a"},
+		{name: "lookup", tmpl: `{{lookup . "Fry"}}`, data: map[string]any{"Fry": "Stephen Fry"}, expected: "Stephen Fry"},
+		{name: "int", tmpl: `{{int .}}`, data: "06", expected: "6"},
+		{name: "pad", tmpl: `{{. | pad 2}}`, data: "6", expected: "06"},
+		{name: "pad json number", tmpl: `{{pad 3 .}}`, data: float64(7), expected: "007"},
+		{name: "pad wider", tmpl: `{{pad 1 .}}`, data: "123", expected: "123"},
+		{name: "add", tmpl: `{{. | add 1}}`, data: "06", expected: "7"},
+		{name: "sub", tmpl: `{{sub 1 .}}`, data: 6, expected: "5"},
+		{name: "mul", tmpl: `{{. | mul 2}}`, data: "3", expected: "6"},
+		{name: "div", tmpl: `{{. | div 2}}`, data: "7", expected: "3"},
+		{name: "mod", tmpl: `{{. | mod 2}}`, data: "7", expected: "1"},
+		{name: "chained", tmpl: `{{. | sub 1 | pad 2}}`, data: "10", expected: "09"},
+		{name: "title", tmpl: `{{title .}}`, data: "the half-blood prince", expected: "The Half-Blood Prince"},
+		{name: "upper", tmpl: `{{upper .}}`, data: "eng", expected: "ENG"},
+		{name: "lower", tmpl: `{{lower .}}`, data: "ENG", expected: "eng"},
+		{name: "trim", tmpl: `{{trim .}}`, data: "  Spinner's End ", expected: "Spinner's End"},
+		{name: "replace", tmpl: `{{. | replace "_+" " "}}`, data: "Horace__Slughorn", expected: "Horace Slughorn"},
+		{name: "replace groups", tmpl: `{{. | replace "(\\w+), (\\w+)" "$2 $1"}}`, data: "Fry, Stephen", expected: "Stephen Fry"},
+		{name: "slugify", tmpl: `{{slugify .}}`, data: "Chapter 06 - Draco's Detour!", expected: "chapter-06-dracos-detour"},
+		{name: "slugify unicode", tmpl: `{{slugify .}}`, data: "しろくま カフェ", expected: "しろくま-カフェ"},
+		{name: "default missing", tmpl: `{{.TCON | default "Audiobook"}}`, data: map[string]any{}, expected: "Audiobook"},
+		{name: "default empty", tmpl: `{{.TCON | default "Audiobook"}}`, data: map[string]any{"TCON": ""}, expected: "Audiobook"},
+		{name: "default set", tmpl: `{{.TCON | default "Audiobook"}}`, data: map[string]any{"TCON": "Rock"}, expected: "Rock"},
+		{name: "date", tmpl: `{{date "0201" .}}`, data: "2005-07-16", expected: "1607"},
+		{name: "date year", tmpl: `{{date "2006" .}}`, data: "2005-07-16T10:00:00Z", expected: "2005"},
+		{name: "date time", tmpl: `{{date "2006" .}}`, data: time.Date(2005, 7, 16, 0, 0, 0, 0, time.UTC), expected: "2005"},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := executeTemplate(tt.tmpl, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTemplateFuncErrors(t *testing.T) {
+	testcases := []struct {
+		name     string
+		tmpl     string
+		data     any
+		expected string
+	}{
+		{name: "get out of range", tmpl: `{{get . 3}}`, data: []any{"a", "b"}, expected: "3 is outside of the list of 2 items"},
+		{name: "get not a list", tmpl: `{{get . 1}}`, data: "a", expected: "expected a list"},
+		{name: "get bad index", tmpl: `{{get . "one"}}`, data: []any{"a"}, expected: `"one" is not a number`},
+		{name: "lookup missing", tmpl: `{{lookup . "Dale"}}`, data: map[string]any{"Fry": "Stephen Fry"}, expected: `"Dale" is not in the map`},
+		{name: "int", tmpl: `{{int .}}`, data: "six", expected: `"six" is not a number`},
+		{name: "pad", tmpl: `{{pad 2 .}}`, data: "six", expected: `pad: "six" is not a number`},
+		{name: "pad fraction", tmpl: `{{pad 2 .}}`, data: 1.5, expected: "1.5 is not a whole number"},
+		{name: "add", tmpl: `{{add 1 .}}`, data: nil, expected: "add:"},
+		{name: "div by zero", tmpl: `{{div 0 .}}`, data: "6", expected: "division by zero"},
+		{name: "mod by zero", tmpl: `{{mod 0 .}}`, data: "6", expected: "division by zero"},
+		{name: "replace bad pattern", tmpl: `{{replace "(" "" .}}`, data: "a", expected: "replace:"},
+		{name: "date", tmpl: `{{date "2006" .}}`, data: "sometime", expected: `"sometime" is not a date`},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := executeTemplate(tt.tmpl, tt.data)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected %q in %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func executeTemplate(tmpl string, data any) (string, error) {
+	parsed, err := parseFramesTemplate("test", tmpl, JSON, true, tmplFuncs())
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := parsed.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func TestJoinValuesInConfig(t *testing.T) {
+	tmpl, err := parseFramesTemplate("test", `{"Frames": {"TPE2": {"Information": "{{joinValues "TPE2" . | jsonEscape}}"}}}`, JSON, false, tmplFuncs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, []string{"Rebecca", "Stephen"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewConfig()
+	if err := json.Unmarshal([]byte(b.String()), cfg); err != nil {
+		t.Fatal(err)
+	}
+	tag := tags.NewID3v2()
+	if err := cfg.Apply(tag); err != nil {
+		t.Fatal(err)
+	}
+	got := tag.TextFrame("TPE2").Values("TPE2")
+	if !reflect.DeepEqual(got, []string{"Rebecca", "Stephen"}) {
+		t.Fatalf("expected [Rebecca Stephen], got %q", got)
+	}
+}